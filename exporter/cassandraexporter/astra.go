@@ -0,0 +1,249 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"time"
+
+	"github.com/gocql/gocql"
+)
+
+// astraTokenUser is the user name Astra expects when authenticating with an
+// application token.
+const astraTokenUser = "token"
+
+var errAstraNoContactPoints = errors.New("astra: metadata service returned no contact points")
+
+// astraBundle is the content of a secure connect bundle that is needed to
+// reach the metadata service of the database.
+type astraBundle struct {
+	host      string
+	port      int
+	tlsConfig *tls.Config
+}
+
+// astraContactInfo is the answer of the metadata service: every node is
+// reached through the SNI proxy, the node is picked by the TLS server name.
+type astraContactInfo struct {
+	SNIProxyAddress string   `json:"sni_proxy_address"`
+	ContactPoints   []string `json:"contact_points"`
+	LocalDC         string   `json:"local_dc"`
+}
+
+// loadAstraBundle reads the config.json, CA and client certificate of the
+// secure connect bundle zip at path.
+func loadAstraBundle(path string) (*astraBundle, error) {
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, fmt.Errorf("astra: open secure connect bundle: %w", err)
+	}
+	defer r.Close()
+
+	files := make(map[string][]byte, len(r.File))
+	for _, f := range r.File {
+		switch f.Name {
+		case "config.json", "ca.crt", "cert", "key":
+		default:
+			continue
+		}
+		content, err := readZipFile(f)
+		if err != nil {
+			return nil, fmt.Errorf("astra: read %s from secure connect bundle: %w", f.Name, err)
+		}
+		files[f.Name] = content
+	}
+	for _, name := range []string{"config.json", "ca.crt", "cert", "key"} {
+		if _, ok := files[name]; !ok {
+			return nil, fmt.Errorf("astra: secure connect bundle has no %s", name)
+		}
+	}
+
+	var config struct {
+		Host string `json:"host"`
+		Port int    `json:"port"`
+	}
+	if err := json.Unmarshal(files["config.json"], &config); err != nil {
+		return nil, fmt.Errorf("astra: parse config.json of secure connect bundle: %w", err)
+	}
+	rootCAs := x509.NewCertPool()
+	if !rootCAs.AppendCertsFromPEM(files["ca.crt"]) {
+		return nil, errors.New("astra: no CA certificate in ca.crt of secure connect bundle")
+	}
+	cert, err := tls.X509KeyPair(files["cert"], files["key"])
+	if err != nil {
+		return nil, fmt.Errorf("astra: load client certificate of secure connect bundle: %w", err)
+	}
+	return &astraBundle{
+		host: config.Host,
+		port: config.Port,
+		tlsConfig: &tls.Config{
+			RootCAs:      rootCAs,
+			Certificates: []tls.Certificate{cert},
+			MinVersion:   tls.VersionTLS12,
+		},
+	}, nil
+}
+
+func readZipFile(f *zip.File) ([]byte, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(rc)
+}
+
+// fetchAstraContactInfo asks the metadata service of the database for the SNI
+// proxy and the ids of the nodes behind it.
+func fetchAstraContactInfo(ctx context.Context, bundle *astraBundle) (*astraContactInfo, error) {
+	url := "https://" + net.JoinHostPort(bundle.host, strconv.Itoa(bundle.port)) + "/metadata"
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("astra: metadata request: %w", err)
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: bundle.tlsConfig}}
+	defer client.CloseIdleConnections()
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("astra: metadata request: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("astra: metadata request: unexpected status %s", resp.Status)
+	}
+
+	var metadata struct {
+		ContactInfo astraContactInfo `json:"contact_info"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&metadata); err != nil {
+		return nil, fmt.Errorf("astra: decode metadata: %w", err)
+	}
+	if len(metadata.ContactInfo.ContactPoints) == 0 || metadata.ContactInfo.SNIProxyAddress == "" {
+		return nil, errAstraNoContactPoints
+	}
+	return &metadata.ContactInfo, nil
+}
+
+// newAstraCluster returns the cluster configuration reaching the database of
+// the secure connect bundle, authenticated with the application token.
+func newAstraCluster(ctx context.Context, astra *Astra) (*gocql.ClusterConfig, error) {
+	bundle, err := loadAstraBundle(astra.SecureConnectBundle)
+	if err != nil {
+		return nil, err
+	}
+	info, err := fetchAstraContactInfo(ctx, bundle)
+	if err != nil {
+		return nil, err
+	}
+	host, portStr, err := net.SplitHostPort(info.SNIProxyAddress)
+	if err != nil {
+		return nil, fmt.Errorf("astra: invalid sni proxy address %q: %w", info.SNIProxyAddress, err)
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return nil, fmt.Errorf("astra: invalid sni proxy address %q: %w", info.SNIProxyAddress, err)
+	}
+
+	cluster := gocql.NewCluster(host)
+	cluster.Port = port
+	cluster.Authenticator = gocql.PasswordAuthenticator{
+		Username: astraTokenUser,
+		Password: string(astra.Token),
+	}
+	cluster.HostDialer = &astraHostDialer{
+		sniProxyAddress: info.SNIProxyAddress,
+		contactPoints:   info.ContactPoints,
+		tlsConfig:       bundle.tlsConfig,
+	}
+	if info.LocalDC != "" {
+		cluster.PoolConfig.HostSelectionPolicy = gocql.TokenAwareHostPolicy(gocql.DCAwareRoundRobinPolicy(info.LocalDC))
+	}
+	return cluster, nil
+}
+
+// astraHostDialer connects every host through the SNI proxy, selecting the
+// node with the TLS server name set to its host id. Hosts whose id is not
+// known yet, such as the initial contact point, go to one of the contact
+// points returned by the metadata service.
+type astraHostDialer struct {
+	sniProxyAddress string
+	contactPoints   []string
+	tlsConfig       *tls.Config
+	dialer          net.Dialer
+	// connectTimeout bounds the dial and the TLS handshake. gocql dials
+	// with the context of the session, which has no deadline.
+	connectTimeout time.Duration
+	next           atomic.Uint32
+}
+
+func (d *astraHostDialer) DialHost(ctx context.Context, host *gocql.HostInfo) (*gocql.DialedHost, error) {
+	if d.connectTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.connectTimeout)
+		defer cancel()
+	}
+	conn, err := d.dialer.DialContext(ctx, "tcp", d.sniProxyAddress)
+	if err != nil {
+		return nil, err
+	}
+	tlsConn := tls.Client(conn, d.nodeTLSConfig(d.serverName(host)))
+	if err := tlsConn.HandshakeContext(ctx); err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return &gocql.DialedHost{Conn: tlsConn, DisableCoalesce: true}, nil
+}
+
+// nodeTLSConfig returns the TLS configuration of a connection to the node with
+// the given id. The server name only routes the connection, the certificate of
+// the proxy does not carry it, so the chain is verified without a host name.
+func (d *astraHostDialer) nodeTLSConfig(serverName string) *tls.Config {
+	tlsConfig := d.tlsConfig.Clone()
+	tlsConfig.ServerName = serverName
+	tlsConfig.InsecureSkipVerify = true
+	tlsConfig.VerifyPeerCertificate = func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		return verifyCertificateChain(d.tlsConfig.RootCAs, rawCerts)
+	}
+	return tlsConfig
+}
+
+func verifyCertificateChain(roots *x509.CertPool, rawCerts [][]byte) error {
+	if len(rawCerts) == 0 {
+		return errors.New("astra: no server certificate")
+	}
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, raw := range rawCerts {
+		cert, err := x509.ParseCertificate(raw)
+		if err != nil {
+			return fmt.Errorf("astra: parse server certificate: %w", err)
+		}
+		certs = append(certs, cert)
+	}
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+	_, err := certs[0].Verify(x509.VerifyOptions{Roots: roots, Intermediates: intermediates})
+	return err
+}
+
+func (d *astraHostDialer) serverName(host *gocql.HostInfo) string {
+	if id := host.HostID(); id != "" {
+		return id
+	}
+	i := d.next.Add(1) - 1
+	return d.contactPoints[int(i)%len(d.contactPoints)]
+}
@@ -0,0 +1,315 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"archive/zip"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// testCertificates is a CA with a server certificate for 127.0.0.1 and a
+// client certificate signed by it.
+type testCertificates struct {
+	caPEM     []byte
+	server    tls.Certificate
+	clientPEM []byte
+	keyPEM    []byte
+}
+
+func newTestCertificates(t *testing.T) testCertificates {
+	caKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	require.NoError(t, err)
+	ca, err := x509.ParseCertificate(caDER)
+	require.NoError(t, err)
+
+	issue := func(serial int64, ips []net.IP, usage x509.ExtKeyUsage) ([]byte, []byte) {
+		key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		require.NoError(t, err)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(serial),
+			Subject:      pkix.Name{CommonName: "test-" + strconv.FormatInt(serial, 10)},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			IPAddresses:  ips,
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+		require.NoError(t, err)
+		keyDER, err := x509.MarshalECPrivateKey(key)
+		require.NoError(t, err)
+		return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+			pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+	}
+
+	serverPEM, serverKeyPEM := issue(2, []net.IP{net.ParseIP("127.0.0.1")}, x509.ExtKeyUsageServerAuth)
+	server, err := tls.X509KeyPair(serverPEM, serverKeyPEM)
+	require.NoError(t, err)
+	clientPEM, clientKeyPEM := issue(3, nil, x509.ExtKeyUsageClientAuth)
+	return testCertificates{
+		caPEM:     pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: caDER}),
+		server:    server,
+		clientPEM: clientPEM,
+		keyPEM:    clientKeyPEM,
+	}
+}
+
+// writeTestBundle writes a secure connect bundle with the given files and
+// returns its path.
+func writeTestBundle(t *testing.T, files map[string][]byte) string {
+	path := filepath.Join(t.TempDir(), "secure-connect.zip")
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	w := zip.NewWriter(f)
+	for name, content := range files {
+		fw, err := w.Create(name)
+		require.NoError(t, err)
+		_, err = fw.Write(content)
+		require.NoError(t, err)
+	}
+	require.NoError(t, w.Close())
+	require.NoError(t, f.Close())
+	return path
+}
+
+// newTestMetadataService serves the metadata of an Astra database and returns
+// a bundle pointing at it.
+func newTestMetadataService(t *testing.T, certs testCertificates, contactInfo astraContactInfo) string {
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/metadata" {
+			http.NotFound(w, r)
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"version": 1, "contact_info": contactInfo})
+	}))
+	server.TLS = &tls.Config{Certificates: []tls.Certificate{certs.server}, MinVersion: tls.VersionTLS12}
+	server.StartTLS()
+	t.Cleanup(server.Close)
+
+	host, port, err := net.SplitHostPort(server.Listener.Addr().String())
+	require.NoError(t, err)
+	config, err := json.Marshal(map[string]any{"host": host, "port": json.Number(port)})
+	require.NoError(t, err)
+	return writeTestBundle(t, map[string][]byte{
+		"config.json": config,
+		"ca.crt":      certs.caPEM,
+		"cert":        certs.clientPEM,
+		"key":         certs.keyPEM,
+	})
+}
+
+func TestNewClusterAstra(t *testing.T) {
+	certs := newTestCertificates(t)
+	bundle := newTestMetadataService(t, certs, astraContactInfo{
+		SNIProxyAddress: "127.0.0.1:29042",
+		ContactPoints:   []string{"host-1", "host-2"},
+		LocalDC:         "dc-1",
+	})
+
+	c, err := newCluster(context.Background(), withDefaultConfig(func(config *Config) {
+		config.Endpoints = []string{"ignored"}
+		config.Astra = &Astra{SecureConnectBundle: bundle, Token: "AstraCS:secret"}
+		config.SocketKeepalive = 30 * time.Second
+		config.ConnectTimeout = 5 * time.Second
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"127.0.0.1"}, c.Hosts)
+	assert.Equal(t, 29042, c.Port)
+	assert.Equal(t, gocql.PasswordAuthenticator{Username: "token", Password: "AstraCS:secret"}, c.Authenticator)
+	assert.Nil(t, c.SslOpts)
+	assert.Equal(t, gocql.Quorum, c.Consistency)
+	require.IsType(t, &astraHostDialer{}, c.HostDialer)
+	assert.Equal(t, []string{"host-1", "host-2"}, c.HostDialer.(*astraHostDialer).contactPoints)
+	assert.Equal(t, 30*time.Second, c.HostDialer.(*astraHostDialer).dialer.KeepAlive)
+	assert.Equal(t, 5*time.Second, c.HostDialer.(*astraHostDialer).connectTimeout)
+	assert.NotNil(t, c.PoolConfig.HostSelectionPolicy)
+}
+
+func TestNewClusterAstraNoContactPoints(t *testing.T) {
+	certs := newTestCertificates(t)
+	bundle := newTestMetadataService(t, certs, astraContactInfo{SNIProxyAddress: "127.0.0.1:29042"})
+
+	_, err := newCluster(context.Background(), withDefaultConfig(func(config *Config) {
+		config.Astra = &Astra{SecureConnectBundle: bundle, Token: "AstraCS:secret"}
+	}))
+	require.ErrorIs(t, err, errAstraNoContactPoints)
+}
+
+func TestLoadAstraBundle(t *testing.T) {
+	certs := newTestCertificates(t)
+	files := map[string][]byte{
+		"config.json": []byte(`{"host": "db.example.com", "port": 29080}`),
+		"ca.crt":      certs.caPEM,
+		"cert":        certs.clientPEM,
+		"key":         certs.keyPEM,
+	}
+
+	bundle, err := loadAstraBundle(writeTestBundle(t, files))
+	require.NoError(t, err)
+	assert.Equal(t, "db.example.com", bundle.host)
+	assert.Equal(t, 29080, bundle.port)
+	assert.Len(t, bundle.tlsConfig.Certificates, 1)
+
+	_, err = loadAstraBundle(filepath.Join(t.TempDir(), "missing.zip"))
+	require.ErrorContains(t, err, "open secure connect bundle")
+
+	delete(files, "key")
+	_, err = loadAstraBundle(writeTestBundle(t, files))
+	require.ErrorContains(t, err, "secure connect bundle has no key")
+}
+
+func TestAstraHostDialer(t *testing.T) {
+	certs := newTestCertificates(t)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{certs.server},
+		MinVersion:   tls.VersionTLS12,
+	})
+	require.NoError(t, err)
+	defer listener.Close()
+	serverNames := make(chan string, 3)
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			tlsConn := conn.(*tls.Conn)
+			if tlsConn.Handshake() == nil {
+				serverNames <- tlsConn.ConnectionState().ServerName
+			}
+			_ = conn.Close()
+		}
+	}()
+
+	bundle, err := loadAstraBundle(writeTestBundle(t, map[string][]byte{
+		"config.json": []byte(`{"host": "127.0.0.1", "port": 29080}`),
+		"ca.crt":      certs.caPEM,
+		"cert":        certs.clientPEM,
+		"key":         certs.keyPEM,
+	}))
+	require.NoError(t, err)
+	dialer := &astraHostDialer{
+		sniProxyAddress: listener.Addr().String(),
+		contactPoints:   []string{"host-1", "host-2"},
+		tlsConfig:       bundle.tlsConfig,
+	}
+
+	known := &gocql.HostInfo{}
+	known.SetHostID("host-3")
+	for _, host := range []*gocql.HostInfo{{}, {}, known} {
+		dialed, err := dialer.DialHost(context.Background(), host)
+		require.NoError(t, err)
+		assert.True(t, dialed.DisableCoalesce)
+		_ = dialed.Conn.Close()
+	}
+	assert.Equal(t, "host-1", <-serverNames)
+	assert.Equal(t, "host-2", <-serverNames)
+	assert.Equal(t, "host-3", <-serverNames)
+}
+
+func TestAstraHostDialerUntrustedProxy(t *testing.T) {
+	certs := newTestCertificates(t)
+	other := newTestCertificates(t)
+	listener, err := tls.Listen("tcp", "127.0.0.1:0", &tls.Config{
+		Certificates: []tls.Certificate{other.server},
+		MinVersion:   tls.VersionTLS12,
+	})
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		_ = conn.(*tls.Conn).Handshake()
+		_ = conn.Close()
+	}()
+
+	bundle, err := loadAstraBundle(writeTestBundle(t, map[string][]byte{
+		"config.json": []byte(`{"host": "127.0.0.1", "port": 29080}`),
+		"ca.crt":      certs.caPEM,
+		"cert":        certs.clientPEM,
+		"key":         certs.keyPEM,
+	}))
+	require.NoError(t, err)
+	dialer := &astraHostDialer{
+		sniProxyAddress: listener.Addr().String(),
+		contactPoints:   []string{"host-1"},
+		tlsConfig:       bundle.tlsConfig,
+	}
+	_, err = dialer.DialHost(context.Background(), &gocql.HostInfo{})
+	require.Error(t, err)
+}
+
+func TestAstraHostDialerHandshakeTimeout(t *testing.T) {
+	certs := newTestCertificates(t)
+	// The proxy accepts connections but never answers the handshake.
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		var conns []net.Conn
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				for _, conn := range conns {
+					_ = conn.Close()
+				}
+				return
+			}
+			conns = append(conns, conn)
+		}
+	}()
+
+	bundle, err := loadAstraBundle(writeTestBundle(t, map[string][]byte{
+		"config.json": []byte(`{"host": "127.0.0.1", "port": 29080}`),
+		"ca.crt":      certs.caPEM,
+		"cert":        certs.clientPEM,
+		"key":         certs.keyPEM,
+	}))
+	require.NoError(t, err)
+	dialer := &astraHostDialer{
+		sniProxyAddress: listener.Addr().String(),
+		contactPoints:   []string{"host-1"},
+		tlsConfig:       bundle.tlsConfig,
+		connectTimeout:  50 * time.Millisecond,
+	}
+
+	start := time.Now()
+	_, err = dialer.DialHost(context.Background(), &gocql.HostInfo{})
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), 5*time.Second)
+}
@@ -0,0 +1,447 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+	"golang.org/x/sync/errgroup"
+)
+
+// maxReportedErrors bounds the number of insert failures carried by the error
+// returned from a push, the remaining ones are only counted.
+const maxReportedErrors = 5
+
+// statement is a single insert waiting to be added to a batch.
+type statement struct {
+	partitionKey string
+	query        string
+	args         []any
+
+	// timestamp and service identify the record the row belongs to when a
+	// failure is logged. Rows without a record timestamp leave it zero.
+	timestamp time.Time
+	service   string
+}
+
+// insertErrors accumulates the failures of a single push. It is safe for
+// concurrent use by the batch workers.
+type insertErrors struct {
+	mu        sync.Mutex
+	errs      []error
+	failed    int
+	retryable bool
+}
+
+// add records a failure. Only its classification is kept, the error itself
+// is stored as is so that a permanent failure does not make the joined error
+// of a push that is retried look permanent.
+func (ie *insertErrors) add(err error) {
+	permanent := consumererror.IsPermanent(classifyCassandraError(err))
+	ie.mu.Lock()
+	defer ie.mu.Unlock()
+	ie.failed++
+	if !permanent {
+		ie.retryable = true
+	}
+	if len(ie.errs) < maxReportedErrors {
+		ie.errs = append(ie.errs, err)
+	}
+}
+
+// err returns nil when nothing failed. The error is only permanent when none
+// of the failures can succeed on retry, otherwise the whole push is retried.
+func (ie *insertErrors) err() error {
+	ie.mu.Lock()
+	defer ie.mu.Unlock()
+	if ie.failed == 0 {
+		return nil
+	}
+	err := fmt.Errorf("%d inserts failed: %w", ie.failed, errors.Join(ie.errs...))
+	if !ie.retryable {
+		return consumererror.NewPermanent(err)
+	}
+	return err
+}
+
+// classifyCassandraError marks err permanent when retrying the failed request
+// cannot succeed: the statement or its values are rejected, or the user lacks
+// the rights to run it. Anything else, such as timeouts, unavailable replicas
+// or an overloaded coordinator, is left retryable.
+func classifyCassandraError(err error) error {
+	if err == nil || consumererror.IsPermanent(err) {
+		return err
+	}
+	var reqErr gocql.RequestError
+	if errors.As(err, &reqErr) {
+		switch reqErr.Code() {
+		case gocql.ErrCodeSyntax, gocql.ErrCodeInvalid, gocql.ErrCodeUnauthorized,
+			gocql.ErrCodeConfig, gocql.ErrCodeCredentials, gocql.ErrCodeProtocol:
+			return consumererror.NewPermanent(err)
+		}
+		return err
+	}
+	var marshalErr gocql.MarshalError
+	if errors.As(err, &marshalErr) {
+		return consumererror.NewPermanent(err)
+	}
+	switch {
+	case errors.Is(err, gocql.ErrTooManyStmts), errors.Is(err, gocql.ErrKeyspaceDoesNotExist),
+		errors.Is(err, gocql.ErrNoKeyspace), errors.Is(err, gocql.ErrUnsupported):
+		return consumererror.NewPermanent(err)
+	}
+	return err
+}
+
+// executeBatches writes the statements as batches of at most cfg.BatchSize
+// entries, of the type chosen by cfg.BatchMode, running at most
+// cfg.NumWorkers batches at the same time. Statements are
+// grouped by partition key first so that rows of the same partition end up in
+// the same batch whenever it fits. A failed batch does not stop the remaining
+// ones, its error is recorded in errs. Once ctx is done no further batch is
+// sent and the context error is recorded instead. The outcome of every batch is
+// reported to telemetry, and every failed batch is logged with the records it
+// holds. A batch the cluster rejects, because one of its rows cannot be
+// marshaled or is invalid, is split and its rows inserted one by one, so that
+// a single bad row does not take the rest of its batch with it.
+func executeBatches(ctx context.Context, session cqlSession, cfg *Config, stmts []statement, errs *insertErrors, telemetry *insertTelemetry, logger *zap.Logger) {
+	policy := speculativeExecutionPolicy(cfg.SpeculativeExecution)
+	if cfg.BatchMode == batchModeNone {
+		executeQueries(ctx, session, cfg, policy, stmts, errs, telemetry, logger)
+		return
+	}
+	sort.SliceStable(stmts, func(i, j int) bool {
+		return stmts[i].partitionKey < stmts[j].partitionKey
+	})
+
+	typ := gocql.UnloggedBatch
+	if cfg.BatchMode == batchModeLogged {
+		typ = gocql.LoggedBatch
+	}
+	newBatch := func() batchExecutor {
+		return session.NewBatch(typ).WithContext(ctx).SpeculativeExecutionPolicy(policy)
+	}
+	var g errgroup.Group
+	g.SetLimit(cfg.NumWorkers)
+	for first := 0; first < len(stmts); {
+		if err := ctx.Err(); err != nil {
+			errs.add(err)
+			telemetry.recordFailed(ctx, len(stmts)-first)
+			break
+		}
+		end := batchEnd(stmts, first, cfg)
+		rows := stmts[first:end]
+		batch := newBatch()
+		for _, row := range rows {
+			batch.Query(row.query, row.args...)
+		}
+		g.Go(func() error {
+			start := time.Now()
+			err := execDowngrading(ctx, cfg, logger, batch.Exec, func(consistency gocql.Consistency) {
+				batch.Consistency(consistency)
+			})
+			if len(rows) > 1 && isRowRejected(err) {
+				logger.Warn("batch rejected, inserting its records one by one", zap.Int("records", len(rows)), zap.Error(err))
+				for _, row := range rows {
+					execQuery(ctx, session, cfg, policy, row, errs, telemetry, logger)
+				}
+				return nil
+			}
+			telemetry.recordBatch(ctx, batch.Size(), time.Since(start), err)
+			if err != nil {
+				logInsertFailure(logger, rows, err)
+				errs.add(err)
+			}
+			return nil
+		})
+		first = end
+	}
+	_ = g.Wait()
+}
+
+// batchEnd returns the end of the batch starting at first, which holds at most
+// cfg.BatchSize statements and, unless its first statement alone exceeds it,
+// at most cfg.MaxBatchBytes of bound values.
+func batchEnd(stmts []statement, first int, cfg *Config) int {
+	size := 0
+	for end := first; end < len(stmts); end++ {
+		if end-first == cfg.BatchSize {
+			return end
+		}
+		size += statementSize(stmts[end])
+		if cfg.MaxBatchBytes > 0 && size > cfg.MaxBatchBytes && end > first {
+			return end
+		}
+	}
+	return len(stmts)
+}
+
+// statementSize approximates the number of bytes the bound values of the
+// statement take in a batch, which is what Cassandra compares to its batch
+// size thresholds.
+func statementSize(stmt statement) int {
+	size := 0
+	for _, arg := range stmt.args {
+		size += valueSize(arg)
+	}
+	return size
+}
+
+func valueSize(v any) int {
+	switch v := v.(type) {
+	case nil:
+		return 0
+	case string:
+		return len(v)
+	case []byte:
+		return len(v)
+	case bool:
+		return 1
+	case int32, uint32, float32:
+		return 4
+	case map[string]string:
+		size := 0
+		for k, e := range v {
+			size += len(k) + len(e)
+		}
+		return size
+	case udtAttributes:
+		size := 0
+		for k, e := range v {
+			size += len(k) + len(e.AsString())
+		}
+		return size
+	case []int64:
+		return 8 * len(v)
+	case []float64:
+		return 8 * len(v)
+	default:
+		// Timestamps, 64-bit numbers and anything else of a fixed size.
+		return 8
+	}
+}
+
+// execWithRetry runs exec up to retry.MaxAttempts times, waiting a jittered
+// backoff doubling after every attempt. Permanent errors are not retried, and
+// neither is any error once ctx is done or when its deadline would pass during
+// the wait.
+func execWithRetry(ctx context.Context, retry InsertRetry, exec func() error) error {
+	backoff := retry.Backoff
+	for attempt := 1; ; attempt++ {
+		err := exec()
+		if err == nil || attempt >= retry.MaxAttempts || ctx.Err() != nil ||
+			consumererror.IsPermanent(classifyCassandraError(err)) {
+			return err
+		}
+		wait := jitter(backoff)
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < wait {
+			return err
+		}
+		timer := time.NewTimer(wait)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+	}
+}
+
+// execDowngrading runs exec with execWithRetry. With downgrade_consistency,
+// an exec still failing on unavailable replicas once its attempts are
+// exhausted is run again at consistency ONE after downgrade is called,
+// trading durability for keeping the records when a datacenter lost quorum.
+// With consistency_fallback_chain, an exec failing on unavailable replicas or
+// a write timeout walks the levels of the chain instead, until one succeeds.
+func execDowngrading(ctx context.Context, cfg *Config, logger *zap.Logger, exec func() error, downgrade func(gocql.Consistency)) error {
+	err := execWithRetry(ctx, cfg.InsertRetry, exec)
+	if len(cfg.ConsistencyFallbackChain) > 0 {
+		current := cfg.Consistency
+		for _, next := range cfg.ConsistencyFallbackChain {
+			if (!isUnavailable(err) && !isWriteTimeout(err)) || ctx.Err() != nil {
+				return err
+			}
+			// Checked by Validate.
+			level, _ := parseConsistency(next)
+			logger.Warn("retrying insert at the next consistency of the fallback chain",
+				zap.String("consistency", current), zap.String("fallback", next), zap.Error(err))
+			downgrade(level)
+			current = next
+			err = execWithRetry(ctx, cfg.InsertRetry, exec)
+		}
+		return err
+	}
+	if !cfg.DowngradeConsistency || !isUnavailable(err) || ctx.Err() != nil || !canDowngrade(cfg.Consistency) {
+		return err
+	}
+	logger.Warn("replicas unavailable, retrying insert at consistency ONE",
+		zap.String("consistency", cfg.Consistency), zap.Error(err))
+	downgrade(gocql.One)
+	return execWithRetry(ctx, cfg.InsertRetry, exec)
+}
+
+// isRowRejected reports whether err rejects the values of a row rather than
+// the request as a whole: a value that cannot be marshaled to its column, or
+// one Cassandra finds invalid, such as an empty partition key.
+func isRowRejected(err error) bool {
+	var marshalErr gocql.MarshalError
+	if errors.As(err, &marshalErr) {
+		return true
+	}
+	var reqErr gocql.RequestError
+	return errors.As(err, &reqErr) && reqErr.Code() == gocql.ErrCodeInvalid
+}
+
+func isUnavailable(err error) bool {
+	var reqErr gocql.RequestError
+	return errors.As(err, &reqErr) && reqErr.Code() == gocql.ErrCodeUnavailable
+}
+
+func isWriteTimeout(err error) bool {
+	var reqErr gocql.RequestError
+	return errors.As(err, &reqErr) && reqErr.Code() == gocql.ErrCodeWriteTimeout
+}
+
+// canDowngrade reports whether consistency is stronger than ONE.
+func canDowngrade(consistency string) bool {
+	level, err := parseConsistency(consistency)
+	if err != nil {
+		return false
+	}
+	switch level {
+	case gocql.Any, gocql.One, gocql.LocalOne:
+		return false
+	}
+	return true
+}
+
+// jitter returns a random delay between half of d and d, so that the
+// batches failing together do not retry together.
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// executeQueries sends every statement as a query of its own, running at most
+// cfg.NumWorkers queries at the same time, with the same error and telemetry
+// handling as executeBatches.
+func executeQueries(ctx context.Context, session cqlSession, cfg *Config, policy gocql.SpeculativeExecutionPolicy, stmts []statement, errs *insertErrors, telemetry *insertTelemetry, logger *zap.Logger) {
+	var g errgroup.Group
+	g.SetLimit(cfg.NumWorkers)
+	for i, stmt := range stmts {
+		if err := ctx.Err(); err != nil {
+			errs.add(err)
+			telemetry.recordFailed(ctx, len(stmts)-i)
+			break
+		}
+		g.Go(func() error {
+			execQuery(ctx, session, cfg, policy, stmt, errs, telemetry, logger)
+			return nil
+		})
+	}
+	_ = g.Wait()
+}
+
+// execQuery sends a single statement as a query of its own.
+func execQuery(ctx context.Context, session cqlSession, cfg *Config, policy gocql.SpeculativeExecutionPolicy, stmt statement, errs *insertErrors, telemetry *insertTelemetry, logger *zap.Logger) {
+	start := time.Now()
+	query := session.Query(stmt.query, stmt.args...).WithContext(ctx).Idempotent(true).SpeculativeExecutionPolicy(policy)
+	err := execDowngrading(ctx, cfg, logger, func() error {
+		return query.Exec()
+	}, func(consistency gocql.Consistency) {
+		query = query.Consistency(consistency)
+	})
+	telemetry.recordBatch(ctx, 1, time.Since(start), err)
+	if err != nil {
+		logInsertFailure(logger, []statement{stmt}, err)
+		errs.add(err)
+	}
+}
+
+// logInsertFailure logs a failed batch or query with the keyspace and tables
+// it writes to, the services and time range of its records and, when known,
+// the coordinator that answered the failed attempt.
+func logInsertFailure(logger *zap.Logger, rows []statement, err error) {
+	ce := logger.Check(zap.ErrorLevel, "failed to insert records")
+	if ce == nil {
+		return
+	}
+	var keyspace string
+	var tables, services []string
+	var firstTimestamp, lastTimestamp time.Time
+	for _, row := range rows {
+		var table string
+		keyspace, table = insertTarget(row.query)
+		tables = appendDistinct(tables, table)
+		if row.service != "" {
+			services = appendDistinct(services, row.service)
+		}
+		if row.timestamp.IsZero() {
+			continue
+		}
+		if firstTimestamp.IsZero() || row.timestamp.Before(firstTimestamp) {
+			firstTimestamp = row.timestamp
+		}
+		if row.timestamp.After(lastTimestamp) {
+			lastTimestamp = row.timestamp
+		}
+	}
+	fields := []zap.Field{
+		zap.String("keyspace", keyspace),
+		zap.Strings("tables", tables),
+		zap.Strings("service_names", services),
+		zap.Int("records", len(rows)),
+	}
+	if !firstTimestamp.IsZero() {
+		fields = append(fields, zap.Time("first_timestamp", firstTimestamp), zap.Time("last_timestamp", lastTimestamp))
+	}
+	var coordinatorErr *coordinatorError
+	if errors.As(err, &coordinatorErr) {
+		fields = append(fields, zap.String("host", coordinatorErr.host))
+	}
+	ce.Write(append(fields, zap.Error(err))...)
+}
+
+// insertTarget returns the keyspace and table of an insert statement, as
+// rendered by parseInsertSQL. Identifiers are validated to hold no dots or
+// spaces, so the first dot separates the keyspace from the table.
+func insertTarget(query string) (keyspace, table string) {
+	if strings.HasPrefix(query, "-- ") {
+		_, query, _ = strings.Cut(query, "\n")
+	}
+	target, _, _ := strings.Cut(strings.TrimPrefix(query, "INSERT INTO "), " ")
+	keyspace, table, _ = strings.Cut(target, ".")
+	return keyspace, table
+}
+
+func appendDistinct(values []string, value string) []string {
+	if slices.Contains(values, value) {
+		return values
+	}
+	return append(values, value)
+}
+
+// speculativeExecutionPolicy returns the policy sending additional executions
+// of a batch that did not complete within the delay, inserts are idempotent so
+// whichever execution completes first is kept.
+func speculativeExecutionPolicy(cfg SpeculativeExecution) gocql.SpeculativeExecutionPolicy {
+	if cfg.MaxAttempts == 0 {
+		return &gocql.NonSpeculativeExecution{}
+	}
+	return &gocql.SimpleSpeculativeExecution{NumAttempts: cfg.MaxAttempts, TimeoutDelay: cfg.Delay}
+}
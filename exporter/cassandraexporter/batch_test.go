@@ -0,0 +1,526 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+type fakeRequestError struct {
+	code int
+}
+
+func (e fakeRequestError) Code() int       { return e.code }
+func (e fakeRequestError) Message() string { return "fake request error" }
+func (e fakeRequestError) Error() string   { return e.Message() }
+
+func TestExecuteBatches(t *testing.T) {
+	session := &fakeSession{}
+	stmts := []statement{
+		{partitionKey: "b", query: "q", args: []any{1}},
+		{partitionKey: "a", query: "q", args: []any{2}},
+		{partitionKey: "b", query: "q", args: []any{3}},
+		{partitionKey: "a", query: "q", args: []any{4}},
+		{partitionKey: "c", query: "q", args: []any{5}},
+	}
+
+	var errs insertErrors
+	executeBatches(context.Background(), session, withDefaultConfig(func(config *Config) {
+		config.BatchSize = 2
+	}), stmts, &errs, newTestInsertTelemetry(t), zap.NewNop())
+	require.NoError(t, errs.err())
+
+	require.Len(t, session.batches, 3)
+	var got [][]any
+	for _, batch := range session.batches {
+		var args []any
+		for _, stmt := range batch {
+			args = append(args, stmt.values[0])
+		}
+		got = append(got, args)
+	}
+	assert.Equal(t, [][]any{{2, 4}, {1, 3}, {5}}, got)
+}
+
+func TestExecuteBatchesWorkers(t *testing.T) {
+	const workers = 3
+	var inFlight, maxInFlight atomic.Int32
+	session := &fakeSession{fail: func([]fakeStatement) error {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			highest := maxInFlight.Load()
+			if n <= highest || maxInFlight.CompareAndSwap(highest, n) {
+				break
+			}
+		}
+		time.Sleep(time.Millisecond)
+		return nil
+	}}
+	stmts := make([]statement, 50)
+	for i := range stmts {
+		stmts[i] = statement{partitionKey: strconv.Itoa(i), query: "q", args: []any{i}}
+	}
+
+	var errs insertErrors
+	executeBatches(context.Background(), session, withDefaultConfig(func(config *Config) {
+		config.BatchSize = 2
+		config.NumWorkers = workers
+	}), stmts, &errs, newTestInsertTelemetry(t), zap.NewNop())
+	require.NoError(t, errs.err())
+	assert.Len(t, session.statements(), len(stmts))
+	assert.LessOrEqual(t, maxInFlight.Load(), int32(workers))
+}
+
+func newTestInsertTelemetry(tb testing.TB) *insertTelemetry {
+	telemetry, err := newInsertTelemetry(componenttest.NewNopTelemetrySettings(), signalLogs)
+	require.NoError(tb, err)
+	return telemetry
+}
+
+func TestExecuteBatchesSpeculativeExecution(t *testing.T) {
+	stmts := []statement{{partitionKey: "a", query: "q"}}
+
+	session := &fakeSession{}
+	var errs insertErrors
+	executeBatches(context.Background(), session, withDefaultConfig(), stmts, &errs, newTestInsertTelemetry(t), zap.NewNop())
+	require.NoError(t, errs.err())
+	assert.Equal(t, []gocql.SpeculativeExecutionPolicy{&gocql.NonSpeculativeExecution{}}, session.policies)
+
+	session = &fakeSession{}
+	executeBatches(context.Background(), session, withDefaultConfig(func(config *Config) {
+		config.SpeculativeExecution = SpeculativeExecution{MaxAttempts: 2, Delay: 50 * time.Millisecond}
+	}), stmts, &errs, newTestInsertTelemetry(t), zap.NewNop())
+	require.NoError(t, errs.err())
+	assert.Equal(t, []gocql.SpeculativeExecutionPolicy{
+		&gocql.SimpleSpeculativeExecution{NumAttempts: 2, TimeoutDelay: 50 * time.Millisecond},
+	}, session.policies)
+}
+
+func TestExecuteBatchesBatchMode(t *testing.T) {
+	stmts := []statement{
+		{partitionKey: "a", query: "q", args: []any{1}},
+		{partitionKey: "b", query: "q", args: []any{2}},
+	}
+	tests := []struct {
+		mode    string
+		types   []gocql.BatchType
+		queries int
+	}{
+		{mode: batchModeUnlogged, types: []gocql.BatchType{gocql.UnloggedBatch}},
+		{mode: batchModeLogged, types: []gocql.BatchType{gocql.LoggedBatch}},
+		{mode: batchModeNone, queries: 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			session := &fakeSession{}
+			var errs insertErrors
+			executeBatches(context.Background(), session, withDefaultConfig(func(config *Config) {
+				config.BatchMode = tt.mode
+			}), stmts, &errs, newTestInsertTelemetry(t), zap.NewNop())
+			require.NoError(t, errs.err())
+			assert.Equal(t, tt.types, session.types)
+			assert.Len(t, session.queries, tt.queries)
+			assert.Len(t, session.policies, len(tt.types)+tt.queries)
+		})
+	}
+}
+
+func TestExecuteQueriesFailure(t *testing.T) {
+	session := &fakeSession{fail: func(stmts []fakeStatement) error {
+		if stmts[0].values[0] == 2 {
+			return gocql.ErrTimeoutNoResponse
+		}
+		return nil
+	}}
+	stmts := []statement{
+		{partitionKey: "a", query: "q", args: []any{1}},
+		{partitionKey: "a", query: "q", args: []any{2}},
+		{partitionKey: "a", query: "q", args: []any{3}},
+	}
+	var errs insertErrors
+	executeBatches(context.Background(), session, withDefaultConfig(func(config *Config) {
+		config.BatchMode = batchModeNone
+		config.NumWorkers = 2
+	}), stmts, &errs, newTestInsertTelemetry(t), zap.NewNop())
+	require.ErrorIs(t, errs.err(), gocql.ErrTimeoutNoResponse)
+	assert.Len(t, session.queries, 2)
+}
+
+func TestExecuteBatchesSplitsRejectedBatch(t *testing.T) {
+	for name, rejection := range map[string]error{
+		"marshal": gocql.MarshalError("can not marshal int64 into int"),
+		"invalid": fakeRequestError{code: gocql.ErrCodeInvalid},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// The cluster rejects any request holding the second row.
+			session := &fakeSession{fail: func(stmts []fakeStatement) error {
+				for _, stmt := range stmts {
+					if stmt.values[0] == 2 {
+						return rejection
+					}
+				}
+				return nil
+			}}
+			stmts := []statement{
+				{partitionKey: "a", query: "q", args: []any{1}},
+				{partitionKey: "a", query: "q", args: []any{2}},
+				{partitionKey: "a", query: "q", args: []any{3}},
+			}
+			var errs insertErrors
+			executeBatches(context.Background(), session, withDefaultConfig(), stmts, &errs, newTestInsertTelemetry(t), zap.NewNop())
+			err := errs.err()
+			require.ErrorIs(t, err, rejection)
+			assert.True(t, consumererror.IsPermanent(err))
+			assert.Contains(t, err.Error(), "1 inserts failed")
+			assert.Empty(t, session.statements())
+			require.Len(t, session.queries, 2)
+			assert.Equal(t, []any{1}, session.queries[0].values)
+			assert.Equal(t, []any{3}, session.queries[1].values)
+		})
+	}
+
+	// Other failures fail the batch as a whole.
+	session := &fakeSession{fail: func([]fakeStatement) error {
+		return fakeRequestError{code: gocql.ErrCodeUnauthorized}
+	}}
+	var errs insertErrors
+	executeBatches(context.Background(), session, withDefaultConfig(), []statement{
+		{partitionKey: "a", query: "q", args: []any{1}},
+		{partitionKey: "a", query: "q", args: []any{2}},
+	}, &errs, newTestInsertTelemetry(t), zap.NewNop())
+	assert.Contains(t, errs.err().Error(), "1 inserts failed")
+	assert.Empty(t, session.queries)
+}
+
+func TestExecuteBatchesMaxBatchBytes(t *testing.T) {
+	small := strings.Repeat("s", 100)
+	large := strings.Repeat("l", 600)
+	var stmts []statement
+	for _, body := range []string{small, small, large, small, large, large, small} {
+		stmts = append(stmts, statement{partitionKey: "a", query: "q", args: []any{body}})
+	}
+
+	session := &fakeSession{}
+	var errs insertErrors
+	executeBatches(context.Background(), session, withDefaultConfig(func(config *Config) {
+		config.MaxBatchBytes = 1000
+	}), stmts, &errs, newTestInsertTelemetry(t), zap.NewNop())
+	require.NoError(t, errs.err())
+
+	var sizes []int
+	for _, batch := range session.batches {
+		size := 0
+		for _, stmt := range batch {
+			size += len(stmt.values[0].(string))
+		}
+		sizes = append(sizes, size)
+	}
+	assert.Equal(t, []int{900, 600, 700}, sizes)
+
+	session = &fakeSession{}
+	executeBatches(context.Background(), session, withDefaultConfig(func(config *Config) {
+		config.MaxBatchBytes = 0
+	}), stmts, &errs, newTestInsertTelemetry(t), zap.NewNop())
+	assert.Len(t, session.batches, 1)
+}
+
+func TestStatementSize(t *testing.T) {
+	stmt := statement{args: []any{
+		time.Now(),
+		"0102030405060708",
+		uint32(1),
+		int32(9),
+		[]byte("body"),
+		map[string]string{"service.name": "checkout"},
+		nil,
+		true,
+		[]int64{1, 2},
+		[]float64{0.5},
+	}}
+	assert.Equal(t, 8+16+4+4+4+20+0+1+16+8, statementSize(stmt))
+}
+
+func TestExecuteBatchesInsertRetry(t *testing.T) {
+	for _, mode := range []string{batchModeUnlogged, batchModeNone} {
+		t.Run(mode, func(t *testing.T) {
+			var calls atomic.Int32
+			session := &fakeSession{fail: func([]fakeStatement) error {
+				if calls.Add(1) <= 2 {
+					return &gocql.RequestErrWriteTimeout{}
+				}
+				return nil
+			}}
+			var errs insertErrors
+			executeBatches(context.Background(), session, withDefaultConfig(func(config *Config) {
+				config.BatchMode = mode
+				config.InsertRetry = InsertRetry{MaxAttempts: 3, Backoff: time.Millisecond}
+			}), []statement{{partitionKey: "a", query: "q", args: []any{1}}}, &errs, newTestInsertTelemetry(t), zap.NewNop())
+			require.NoError(t, errs.err())
+			assert.EqualValues(t, 3, calls.Load())
+			assert.Len(t, append(session.statements(), session.queries...), 1)
+		})
+	}
+}
+
+func TestExecuteBatchesDowngradeConsistency(t *testing.T) {
+	for _, mode := range []string{batchModeUnlogged, batchModeNone} {
+		t.Run(mode, func(t *testing.T) {
+			for _, downgrade := range []bool{false, true} {
+				var calls atomic.Int32
+				session := &fakeSession{fail: func([]fakeStatement) error {
+					if calls.Add(1) <= 2 {
+						return fakeRequestError{code: gocql.ErrCodeUnavailable}
+					}
+					return nil
+				}}
+				core, logs := observer.New(zapcore.WarnLevel)
+				var errs insertErrors
+				executeBatches(context.Background(), session, withDefaultConfig(func(config *Config) {
+					config.BatchMode = mode
+					config.Consistency = "LOCAL_QUORUM"
+					config.DowngradeConsistency = downgrade
+					config.InsertRetry = InsertRetry{MaxAttempts: 2, Backoff: time.Millisecond}
+				}), []statement{{partitionKey: "a", query: "q", args: []any{1}}}, &errs, newTestInsertTelemetry(t), zap.New(core))
+				if !downgrade {
+					require.Error(t, errs.err())
+					assert.EqualValues(t, 2, calls.Load())
+					assert.Empty(t, session.consistencies)
+					assert.Zero(t, logs.FilterMessage("replicas unavailable, retrying insert at consistency ONE").Len())
+					continue
+				}
+				require.NoError(t, errs.err())
+				assert.EqualValues(t, 3, calls.Load())
+				assert.Equal(t, []string{"ONE"}, session.consistencies)
+				require.Equal(t, 1, logs.FilterMessage("replicas unavailable, retrying insert at consistency ONE").Len())
+			}
+		})
+	}
+}
+
+func TestCanDowngrade(t *testing.T) {
+	assert.True(t, canDowngrade("LOCAL_QUORUM"))
+	assert.True(t, canDowngrade("QUORUM"))
+	assert.False(t, canDowngrade("ONE"))
+	assert.False(t, canDowngrade("LOCAL_ONE"))
+	assert.False(t, canDowngrade("ANY"))
+}
+
+func TestExecuteBatchesLogsFailures(t *testing.T) {
+	start := time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)
+	stmts := []statement{
+		{partitionKey: "checkout", query: "INSERT INTO otel.otel_logs (body) VALUES (?)", timestamp: start.Add(time.Second), service: "checkout"},
+		{partitionKey: "checkout", query: "INSERT INTO otel.otel_logs (body) VALUES (?)", timestamp: start, service: "checkout"},
+		{partitionKey: "resource", query: "INSERT INTO otel.otel_resources (resourceid) VALUES (?)"},
+		{partitionKey: "z", query: "INSERT INTO otel.otel_logs (body) VALUES (?)", timestamp: start, service: "payment"},
+	}
+	failure := withCoordinator(&gocql.RequestErrWriteTimeout{}, &gocql.HostInfo{})
+	session := &fakeSession{fail: func(stmts []fakeStatement) error {
+		if len(stmts) == 3 {
+			return failure
+		}
+		return nil
+	}}
+	core, logs := observer.New(zap.ErrorLevel)
+	var errs insertErrors
+	executeBatches(context.Background(), session, withDefaultConfig(func(config *Config) {
+		config.BatchSize = 3
+	}), stmts, &errs, newTestInsertTelemetry(t), zap.New(core))
+	require.Error(t, errs.err())
+
+	require.Equal(t, 1, logs.Len())
+	entry := logs.All()[0]
+	assert.Equal(t, "failed to insert records", entry.Message)
+	assert.Equal(t, map[string]any{
+		"keyspace":        "otel",
+		"tables":          []any{"otel_logs", "otel_resources"},
+		"service_names":   []any{"checkout"},
+		"records":         int64(3),
+		"first_timestamp": start,
+		"last_timestamp":  start.Add(time.Second),
+		"host":            failure.(*coordinatorError).host,
+		"error":           failure.Error(),
+	}, entry.ContextMap())
+}
+
+func TestInsertTarget(t *testing.T) {
+	keyspace, table := insertTarget(parseInsertSQL(withDefaultConfig(), insertLogTableSQL, "otel_logs"))
+	assert.Equal(t, "otel", keyspace)
+	assert.Equal(t, "otel_logs", table)
+	keyspace, table = insertTarget(`INSERT INTO "Telemetry"."Logs" (body) VALUES (?)`)
+	assert.Equal(t, `"Telemetry"`, keyspace)
+	assert.Equal(t, `"Logs"`, table)
+	keyspace, table = insertTarget(parseInsertSQL(withDefaultConfig(func(config *Config) {
+		config.QueryComment = "tenant=acme"
+	}), insertLogTableSQL, "otel_logs"))
+	assert.Equal(t, "otel", keyspace)
+	assert.Equal(t, "otel_logs", table)
+}
+
+func TestCoordinatorObserver(t *testing.T) {
+	var observed []gocql.ObservedBatch
+	next := batchObserverFunc(func(_ context.Context, b gocql.ObservedBatch) {
+		observed = append(observed, b)
+	})
+	coordinator := &coordinatorObserver{next: next}
+	assert.Nil(t, coordinator.coordinator())
+	host := &gocql.HostInfo{}
+	coordinator.ObserveBatch(context.Background(), gocql.ObservedBatch{Host: host, Attempt: 1})
+	assert.Same(t, host, coordinator.coordinator())
+	assert.Len(t, observed, 1)
+
+	err := withCoordinator(gocql.ErrTimeoutNoResponse, host)
+	assert.ErrorIs(t, err, gocql.ErrTimeoutNoResponse)
+	assert.Equal(t, gocql.ErrTimeoutNoResponse.Error(), err.Error())
+	assert.Nil(t, withCoordinator(nil, host))
+	assert.Same(t, gocql.ErrTimeoutNoResponse, withCoordinator(gocql.ErrTimeoutNoResponse, nil))
+}
+
+type batchObserverFunc func(ctx context.Context, b gocql.ObservedBatch)
+
+func (f batchObserverFunc) ObserveBatch(ctx context.Context, b gocql.ObservedBatch) {
+	f(ctx, b)
+}
+
+func TestExecWithRetry(t *testing.T) {
+	retry := InsertRetry{MaxAttempts: 3, Backoff: time.Millisecond}
+	failing := func(errs ...error) (func() error, *int) {
+		calls := 0
+		return func() error {
+			calls++
+			if calls <= len(errs) {
+				return errs[calls-1]
+			}
+			return nil
+		}, &calls
+	}
+
+	t.Run("recovers", func(t *testing.T) {
+		exec, calls := failing(gocql.ErrTimeoutNoResponse, &gocql.RequestErrUnavailable{})
+		require.NoError(t, execWithRetry(context.Background(), retry, exec))
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("gives_up", func(t *testing.T) {
+		exec, calls := failing(gocql.ErrTimeoutNoResponse, gocql.ErrTimeoutNoResponse, gocql.ErrTimeoutNoResponse)
+		require.ErrorIs(t, execWithRetry(context.Background(), retry, exec), gocql.ErrTimeoutNoResponse)
+		assert.Equal(t, 3, *calls)
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		exec, calls := failing(gocql.ErrTimeoutNoResponse)
+		require.Error(t, execWithRetry(context.Background(), InsertRetry{MaxAttempts: 1}, exec))
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("permanent", func(t *testing.T) {
+		exec, calls := failing(fakeRequestError{code: gocql.ErrCodeInvalid})
+		require.Error(t, execWithRetry(context.Background(), retry, exec))
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		exec, calls := failing(gocql.ErrTimeoutNoResponse)
+		cancel()
+		require.Error(t, execWithRetry(ctx, retry, exec))
+		assert.Equal(t, 1, *calls)
+	})
+
+	t.Run("deadline_before_backoff", func(t *testing.T) {
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+		exec, calls := failing(gocql.ErrTimeoutNoResponse)
+		start := time.Now()
+		require.Error(t, execWithRetry(ctx, InsertRetry{MaxAttempts: 3, Backoff: time.Minute}, exec))
+		assert.Equal(t, 1, *calls)
+		assert.Less(t, time.Since(start), 50*time.Millisecond)
+	})
+}
+
+func TestJitter(t *testing.T) {
+	assert.Zero(t, jitter(0))
+	for i := 0; i < 100; i++ {
+		d := jitter(100 * time.Millisecond)
+		assert.GreaterOrEqual(t, d, 50*time.Millisecond)
+		assert.LessOrEqual(t, d, 100*time.Millisecond)
+	}
+}
+
+func TestClassifyCassandraError(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		permanent bool
+	}{
+		{name: "syntax", err: fakeRequestError{code: gocql.ErrCodeSyntax}, permanent: true},
+		{name: "invalid", err: fakeRequestError{code: gocql.ErrCodeInvalid}, permanent: true},
+		{name: "unauthorized", err: fakeRequestError{code: gocql.ErrCodeUnauthorized}, permanent: true},
+		{name: "config", err: fakeRequestError{code: gocql.ErrCodeConfig}, permanent: true},
+		{name: "credentials", err: fakeRequestError{code: gocql.ErrCodeCredentials}, permanent: true},
+		{name: "protocol", err: fakeRequestError{code: gocql.ErrCodeProtocol}, permanent: true},
+		{name: "unavailable", err: &gocql.RequestErrUnavailable{}},
+		{name: "write_timeout", err: &gocql.RequestErrWriteTimeout{}},
+		{name: "overloaded", err: fakeRequestError{code: gocql.ErrCodeOverloaded}},
+		{name: "bootstrapping", err: fakeRequestError{code: gocql.ErrCodeBootstrapping}},
+		{name: "server", err: fakeRequestError{code: gocql.ErrCodeServer}},
+		{name: "marshal", err: gocql.MarshalError("can not marshal string into int"), permanent: true},
+		{name: "too_many_statements", err: gocql.ErrTooManyStmts, permanent: true},
+		{name: "keyspace_does_not_exist", err: gocql.ErrKeyspaceDoesNotExist, permanent: true},
+		{name: "no_connections", err: gocql.ErrNoConnections},
+		{name: "no_response", err: gocql.ErrTimeoutNoResponse},
+		{name: "connection_closed", err: gocql.ErrConnectionClosed},
+		{name: "deadline_exceeded", err: context.DeadlineExceeded},
+		{name: "wrapped", err: fmt.Errorf("batch: %w", fakeRequestError{code: gocql.ErrCodeSyntax}), permanent: true},
+		{name: "already_permanent", err: consumererror.NewPermanent(errors.New("bad record")), permanent: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := classifyCassandraError(tt.err)
+			assert.ErrorIs(t, err, tt.err)
+			assert.Equal(t, tt.permanent, consumererror.IsPermanent(err))
+		})
+	}
+	assert.NoError(t, classifyCassandraError(nil))
+}
+
+func TestInsertErrors(t *testing.T) {
+	syntaxErr := fakeRequestError{code: gocql.ErrCodeSyntax}
+
+	var errs insertErrors
+	require.NoError(t, errs.err())
+
+	errs.add(syntaxErr)
+	err := errs.err()
+	require.Error(t, err)
+	assert.True(t, consumererror.IsPermanent(err))
+
+	errs.add(gocql.ErrNoConnections)
+	err = errs.err()
+	require.Error(t, err)
+	assert.False(t, consumererror.IsPermanent(err))
+	assert.ErrorIs(t, err, gocql.ErrNoConnections)
+
+	for i := 0; i < 2*maxReportedErrors; i++ {
+		errs.add(errors.New("timeout"))
+	}
+	assert.Len(t, errs.errs, maxReportedErrors)
+	assert.ErrorContains(t, errs.err(), "12 inserts failed")
+}
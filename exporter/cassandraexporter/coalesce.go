@@ -0,0 +1,102 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// statementBuffer coalesces the inserts of several pushes, so that a pipeline
+// sending many tiny exports writes them in few batches. The buffered inserts
+// are flushed once size of them are waiting, every interval and on shutdown.
+type statementBuffer struct {
+	size  int
+	flush func(ctx context.Context, stmts []statement)
+
+	mu    sync.Mutex
+	stmts []statement
+
+	stop    chan struct{}
+	stopped chan struct{}
+}
+
+// newStatementBuffer returns a buffer flushing every interval until shutdown
+// is called.
+func newStatementBuffer(size int, interval, timeout time.Duration, flush func(context.Context, []statement)) *statementBuffer {
+	b := &statementBuffer{
+		size:    size,
+		flush:   flush,
+		stop:    make(chan struct{}),
+		stopped: make(chan struct{}),
+	}
+	go b.run(interval, timeout)
+	return b
+}
+
+// add buffers the statements. Once the buffer is full it is flushed right
+// away with ctx, which slows the pushes down to the pace of the cluster.
+func (b *statementBuffer) add(ctx context.Context, stmts []statement) {
+	b.mu.Lock()
+	b.stmts = append(b.stmts, stmts...)
+	if len(b.stmts) < b.size {
+		b.mu.Unlock()
+		return
+	}
+	full := b.take()
+	b.mu.Unlock()
+	b.flush(ctx, full)
+}
+
+// take empties the buffer, b.mu must be held.
+func (b *statementBuffer) take() []statement {
+	stmts := b.stmts
+	b.stmts = nil
+	return stmts
+}
+
+func (b *statementBuffer) run(interval, timeout time.Duration) {
+	defer close(b.stopped)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-b.stop:
+			return
+		case <-ticker.C:
+			b.flushWithTimeout(timeout)
+		}
+	}
+}
+
+// flushWithTimeout flushes the buffer on behalf of no push, bounding the
+// inserts by timeout as the export timeout would.
+func (b *statementBuffer) flushWithTimeout(timeout time.Duration) {
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	b.flushBuffered(ctx)
+}
+
+func (b *statementBuffer) flushBuffered(ctx context.Context) int {
+	b.mu.Lock()
+	stmts := b.take()
+	b.mu.Unlock()
+	if len(stmts) > 0 {
+		b.flush(ctx, stmts)
+	}
+	return len(stmts)
+}
+
+// shutdown stops the timer and flushes what is left in the buffer, it
+// returns the number of statements flushed. It must only be called once.
+func (b *statementBuffer) shutdown(ctx context.Context) int {
+	close(b.stop)
+	<-b.stopped
+	return b.flushBuffered(ctx)
+}
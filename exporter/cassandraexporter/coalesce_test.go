@@ -0,0 +1,72 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// recordingFlush collects the statements of every flush.
+type recordingFlush struct {
+	mu      sync.Mutex
+	flushes [][]statement
+}
+
+func (r *recordingFlush) flush(_ context.Context, stmts []statement) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.flushes = append(r.flushes, stmts)
+}
+
+func (r *recordingFlush) sizes() []int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sizes := make([]int, 0, len(r.flushes))
+	for _, stmts := range r.flushes {
+		sizes = append(sizes, len(stmts))
+	}
+	return sizes
+}
+
+func TestStatementBufferFlushesWhenFull(t *testing.T) {
+	var r recordingFlush
+	b := newStatementBuffer(3, time.Hour, 0, r.flush)
+
+	b.add(context.Background(), make([]statement, 2))
+	assert.Empty(t, r.sizes())
+	b.add(context.Background(), make([]statement, 2))
+	assert.Equal(t, []int{4}, r.sizes())
+
+	b.shutdown(context.Background())
+	assert.Equal(t, []int{4}, r.sizes())
+}
+
+func TestStatementBufferFlushesOnInterval(t *testing.T) {
+	var r recordingFlush
+	b := newStatementBuffer(100, 10*time.Millisecond, time.Second, r.flush)
+	defer b.shutdown(context.Background())
+
+	b.add(context.Background(), make([]statement, 2))
+	assert.Eventually(t, func() bool {
+		sizes := r.sizes()
+		return len(sizes) == 1 && sizes[0] == 2
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestStatementBufferFlushesOnShutdown(t *testing.T) {
+	var r recordingFlush
+	b := newStatementBuffer(100, time.Hour, 0, r.flush)
+
+	b.add(context.Background(), make([]statement, 1))
+	b.add(context.Background(), make([]statement, 1))
+	require.Empty(t, r.sizes())
+	b.shutdown(context.Background())
+	assert.Equal(t, []int{2}, r.sizes())
+}
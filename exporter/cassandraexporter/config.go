@@ -3,33 +3,888 @@
 
 package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
 import (
+	"errors"
+	"fmt"
+	"net"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
 	"time"
 
+	"github.com/gocql/gocql"
 	"go.opentelemetry.io/collector/config/configopaque"
+	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/config/configtls"
+	"go.opentelemetry.io/collector/exporter/exporterhelper"
 )
 
 type Config struct {
-	DSN         string        `mapstructure:"dsn"`
-	Port        int           `mapstructure:"port"`
-	Timeout     time.Duration `mapstructure:"timeout"`
-	Keyspace    string        `mapstructure:"keyspace"`
-	TraceTable  string        `mapstructure:"trace_table"`
-	LogsTable   string        `mapstructure:"logs_table"`
-	Replication Replication   `mapstructure:"replication"`
-	Compression Compression   `mapstructure:"compression"`
-	Auth        Auth          `mapstructure:"auth"`
+	exporterhelper.TimeoutSettings `mapstructure:",squash"`
+	configretry.BackOffConfig      `mapstructure:"retry_on_failure"`
+	exporterhelper.QueueSettings   `mapstructure:"sending_queue"`
+
+	DSN                      string                 `mapstructure:"dsn"`
+	Endpoints                []string               `mapstructure:"endpoints"`
+	Port                     int                    `mapstructure:"port"`
+	Keyspace                 string                 `mapstructure:"keyspace"`
+	LogsKeyspace             string                 `mapstructure:"logs_keyspace"`
+	TracesKeyspace           string                 `mapstructure:"traces_keyspace"`
+	MetricsKeyspace          string                 `mapstructure:"metrics_keyspace"`
+	TraceTable               string                 `mapstructure:"trace_table"`
+	LogsTable                string                 `mapstructure:"logs_table"`
+	ResourcesTable           string                 `mapstructure:"resources_table"`
+	LogsByTraceTable         string                 `mapstructure:"logs_by_trace_table"`
+	MetricsTable             string                 `mapstructure:"metrics_table"`
+	Replication              Replication            `mapstructure:"replication"`
+	DurableWrites            *bool                  `mapstructure:"durable_writes"`
+	Compression              Compression            `mapstructure:"compression"`
+	Compaction               Compaction             `mapstructure:"compaction"`
+	GCGraceSeconds           int                    `mapstructure:"gc_grace_seconds"`
+	SpeculativeRetry         string                 `mapstructure:"speculative_retry"`
+	Auth                     Auth                   `mapstructure:"auth"`
+	TLS                      configtls.ClientConfig `mapstructure:"tls"`
+	Astra                    *Astra                 `mapstructure:"astra"`
+	Consistency              string                 `mapstructure:"consistency"`
+	LogsConsistency          string                 `mapstructure:"logs_consistency"`
+	TracesConsistency        string                 `mapstructure:"traces_consistency"`
+	MetricsConsistency       string                 `mapstructure:"metrics_consistency"`
+	DowngradeConsistency     bool                   `mapstructure:"downgrade_consistency"`
+	ConsistencyFallbackChain []string               `mapstructure:"consistency_fallback_chain"`
+	SerialConsistency        string                 `mapstructure:"serial_consistency"`
+	LocalDC                  string                 `mapstructure:"local_dc"`
+	HostFilter               HostFilter             `mapstructure:"host_filter"`
+	TokenAware               bool                   `mapstructure:"token_aware"`
+	BatchSize                int                    `mapstructure:"batch_size"`
+	MaxBatchBytes            int                    `mapstructure:"max_batch_bytes"`
+	BatchMode                string                 `mapstructure:"batch_mode"`
+	Coalescing               Coalescing             `mapstructure:"coalescing"`
+	NumWorkers               int                    `mapstructure:"num_workers"`
+	NumConns                 int                    `mapstructure:"num_conns"`
+	SpeculativeExecution     SpeculativeExecution   `mapstructure:"speculative_execution"`
+	TTL                      time.Duration          `mapstructure:"ttl"`
+	DefaultTTL               time.Duration          `mapstructure:"default_ttl"`
+	UseEventTimestamp        bool                   `mapstructure:"use_event_timestamp"`
+	ConnectTimeout           time.Duration          `mapstructure:"connect_timeout"`
+	SocketKeepalive          time.Duration          `mapstructure:"socket_keepalive"`
+	StartupTimeout           time.Duration          `mapstructure:"startup_timeout"`
+	ProtoVersion             int                    `mapstructure:"proto_version"`
+	Reconnection             Reconnection           `mapstructure:"reconnection"`
+	HealthCheck              HealthCheck            `mapstructure:"health_check"`
+	InsertRetry              InsertRetry            `mapstructure:"insert_retry"`
+	ShutdownTimeout          time.Duration          `mapstructure:"shutdown_timeout"`
+	EnableQueryObserver      bool                   `mapstructure:"enable_query_observer"`
+	CreateSchema             bool                   `mapstructure:"create_schema"`
+	CreateKeyspace           bool                   `mapstructure:"create_keyspace"`
+	CheckVersion             bool                   `mapstructure:"check_version"`
+	PartitionBy              string                 `mapstructure:"partition_by"`
+	ShardCount               int                    `mapstructure:"shard_count"`
+	ClusteringOrder          string                 `mapstructure:"clustering_order"`
+	BodyEncoding             string                 `mapstructure:"body_encoding"`
+	BodyJSON                 BodyJSON               `mapstructure:"body_json"`
+	AttributesFormat         string                 `mapstructure:"attributes_format"`
+	AttributesUDT            string                 `mapstructure:"attributes_udt"`
+	DeduplicateResources     bool                   `mapstructure:"deduplicate_resources"`
+	StoreResourceAttributes  bool                   `mapstructure:"store_resource_attributes"`
+	StoreLogAttributes       bool                   `mapstructure:"store_log_attributes"`
+	IndexLogsByTrace         bool                   `mapstructure:"index_logs_by_trace"`
+	MaxBodySize              int                    `mapstructure:"max_body_size"`
+	MaxAttributes            int                    `mapstructure:"max_attributes"`
+	StoreRawOTLP             bool                   `mapstructure:"store_raw_otlp"`
+	SchemaTemplate           string                 `mapstructure:"schema_template"`
+	InstanceID               string                 `mapstructure:"instance_id"`
+	QueryComment             string                 `mapstructure:"query_comment"`
+	InvalidSpanIDs           string                 `mapstructure:"invalid_span_ids"`
+	TimestampFormat          string                 `mapstructure:"timestamp_format"`
+}
+
+// Coalescing buffers the log inserts of several pushes and writes them
+// together. Pushes succeed once buffered, so a failed flush is only logged
+// and counted, not retried.
+type Coalescing struct {
+	Enabled       bool          `mapstructure:"enabled"`
+	FlushInterval time.Duration `mapstructure:"flush_interval"`
+}
+
+// BodyJSON holds the options of the JSON form of the log bodies.
+type BodyJSON struct {
+	// EscapeHTML escapes <, > and & in strings as \u003c, \u003e and
+	// \u0026, for bodies embedded in HTML.
+	EscapeHTML bool `mapstructure:"escape_html"`
+	// Indent indents every nesting level of the JSON with it, empty keeps
+	// the JSON on a single line.
+	Indent string `mapstructure:"indent"`
+}
+
+// HostFilter restricts the nodes the exporter connects to. A node has to match
+// every option set.
+type HostFilter struct {
+	DataCenter string `mapstructure:"data_center"`
+	Rack       string `mapstructure:"rack"`
+	// CIDRs are the networks, such as 10.1.0.0/16, the node address has to
+	// be in one of.
+	CIDRs []string `mapstructure:"cidrs"`
+}
+
+// Reconnection is the exponential backoff between attempts to connect to the
+// cluster at startup and to nodes that went down.
+type Reconnection struct {
+	InitialInterval time.Duration `mapstructure:"initial_interval"`
+	MaxInterval     time.Duration `mapstructure:"max_interval"`
+	MaxRetries      int           `mapstructure:"max_retries"`
+}
+
+// HealthCheck probes the cluster in the background and rebuilds the session
+// of an exporter once enough probes in a row failed.
+type HealthCheck struct {
+	// Interval is the time between probes, jittered, 0 disables the health
+	// check.
+	Interval time.Duration `mapstructure:"interval"`
+	// FailureThreshold is the number of probes in a row that have to fail
+	// before the session is rebuilt.
+	FailureThreshold int `mapstructure:"failure_threshold"`
+}
+
+// InsertRetry retries a batch failing with a transient error in place,
+// before the export fails and the pipeline retries it as a whole.
+type InsertRetry struct {
+	// MaxAttempts is the number of times a batch is sent, 1 disables
+	// retrying.
+	MaxAttempts int `mapstructure:"max_attempts"`
+	// Backoff is the delay before the first retry, doubled for every further
+	// one and jittered.
+	Backoff time.Duration `mapstructure:"backoff"`
+}
+
+// SpeculativeExecution sends an insert to additional hosts when the first one
+// is slow to answer.
+type SpeculativeExecution struct {
+	// MaxAttempts is the number of additional executions, 0 disables
+	// speculative execution.
+	MaxAttempts int           `mapstructure:"max_attempts"`
+	Delay       time.Duration `mapstructure:"delay"`
+}
+
+// Astra connects to a DataStax Astra database through its secure connect bundle.
+type Astra struct {
+	SecureConnectBundle string              `mapstructure:"secure_connect_bundle"`
+	Token               configopaque.String `mapstructure:"token"`
+	// TokenFile is read at start instead of Token.
+	TokenFile string `mapstructure:"token_file"`
 }
 
 type Replication struct {
-	Class             string `mapstructure:"class"`
-	ReplicationFactor int    `mapstructure:"replication_factor"`
+	Class             string         `mapstructure:"class"`
+	ReplicationFactor int            `mapstructure:"replication_factor"`
+	DataCenters       map[string]int `mapstructure:"data_centers"`
 }
 
+const networkTopologyStrategy = "NetworkTopologyStrategy"
+
+// Keyspaces and tables are interpolated into the CQL statements, so they must
+// be plain identifiers, which are quoted when they hold upper-case letters or
+// start with a digit, or already double quoted ones.
+var identifierPattern = regexp.MustCompile(`^(?:[a-zA-Z0-9_]+|"[a-zA-Z0-9_]+")$`)
+
+// The replication class and data center names are interpolated into the
+// replication map of the keyspace as quoted strings.
+var replicationNamePattern = regexp.MustCompile(`^[a-zA-Z0-9_.-]+$`)
+
+// speculativeRetryPattern matches the speculative_retry values Cassandra
+// accepts: NONE, ALWAYS, a latency such as 50ms, a percentile such as
+// 99PERCENTILE or 99p, or the MIN or MAX of a latency and a percentile.
+var speculativeRetryPattern = regexp.MustCompile(`^(?i:NONE|ALWAYS|` + speculativeRetryThreshold +
+	`|(?:MIN|MAX)\(` + speculativeRetryThreshold + `, ?` + speculativeRetryThreshold + `\))$`)
+
+const speculativeRetryThreshold = `\d+(?:\.\d+)?(?:ms|p|PERCENTILE)`
+
+// The granularities of the time bucket partitioning the logs of a service.
+const (
+	partitionByHour = "hour"
+	partitionByDay  = "day"
+)
+
+// How spans with an all-zero trace or span id are handled.
+const (
+	invalidSpanIDsStore = "store"
+	invalidSpanIDsDrop  = "drop"
+)
+
+// The types of the TimeStamp column of the logs tables.
+const (
+	timestampFormatTimestamp   = "timestamp"
+	timestampFormatBigintNanos = "bigint_nanos"
+)
+
+// The orders of the records within a logs partition.
+const (
+	clusteringOrderAsc  = "ASC"
+	clusteringOrderDesc = "DESC"
+)
+
 type Compression struct {
+	// Algorithm is the compressor class of the tables, empty disables compression.
 	Algorithm string `mapstructure:"algorithm"`
+	// ChunkLength is the size of the compressed chunks in KiB, 0 keeps the
+	// default of Cassandra.
+	ChunkLength int `mapstructure:"chunk_length_in_kb"`
+}
+
+// compressionAlgorithms are the compressor classes shipped with Cassandra.
+var compressionAlgorithms = []string{
+	"LZ4Compressor",
+	"SnappyCompressor",
+	"DeflateCompressor",
+	"ZstdCompressor",
+}
+
+// How the inserts of an export are grouped.
+const (
+	batchModeUnlogged = "unlogged"
+	batchModeLogged   = "logged"
+	batchModeNone     = "none"
+)
+
+// The formats of the attribute columns.
+const (
+	attributesFormatMap  = "map"
+	attributesFormatJSON = "json"
+)
+
+// The encodings of the log body column.
+const (
+	bodyEncodingJSON = "json"
+	bodyEncodingText = "text"
+	bodyEncodingBlob = "blob"
+)
+
+// Compaction is the compaction strategy of the tables created by the exporter.
+type Compaction struct {
+	Strategy string `mapstructure:"strategy"`
+	// CompactionWindowSize and CompactionWindowUnit size the time windows of
+	// the TimeWindowCompactionStrategy, other strategies ignore them.
+	CompactionWindowSize int    `mapstructure:"compaction_window_size"`
+	CompactionWindowUnit string `mapstructure:"compaction_window_unit"`
+}
+
+const timeWindowCompactionStrategy = "TimeWindowCompactionStrategy"
+
+// compactionStrategies are the compaction strategies shipped with Cassandra.
+var compactionStrategies = []string{
+	"SizeTieredCompactionStrategy",
+	"LeveledCompactionStrategy",
+	timeWindowCompactionStrategy,
+	"UnifiedCompactionStrategy",
 }
 
+var compactionWindowUnits = []string{"MINUTES", "HOURS", "DAYS"}
+
 type Auth struct {
 	UserName string              `mapstructure:"username"`
 	Password configopaque.String `mapstructure:"password"`
+	// PasswordFile is read at start instead of Password, so the password can
+	// be mounted from a secret rather than written in the configuration.
+	PasswordFile string `mapstructure:"password_file"`
+	SigV4        *SigV4 `mapstructure:"sigv4"`
+}
+
+// SigV4 authenticates against Amazon Keyspaces with AWS Signature Version 4,
+// using the credentials of the default AWS credential chain.
+type SigV4 struct {
+	Region string `mapstructure:"region"`
+}
+
+var (
+	errConfigNoEndpoint               = errors.New("endpoints or dsn must be specified")
+	errConfigInvalidEndpoint          = errors.New("invalid endpoint")
+	errConfigInvalidPort              = errors.New("port must be between 1 and 65535")
+	errConfigNoDataCenters            = errors.New("replication.data_centers must not be empty with NetworkTopologyStrategy")
+	errConfigInvalidReplication       = errors.New("replication factor must be greater than zero")
+	errConfigEmptyPassword            = errors.New("empty auth.password and auth.password_file")
+	errConfigEmptyUserName            = errors.New("empty auth.username")
+	errConfigTokenAwareNoDC           = errors.New("token_aware requires local_dc")
+	errConfigSigV4NoRegion            = errors.New("auth.sigv4.region must be specified")
+	errConfigSigV4Password            = errors.New("auth.sigv4 cannot be combined with auth.username and auth.password")
+	errConfigSigV4Insecure            = errors.New("auth.sigv4 requires tls, set tls.insecure to false")
+	errConfigAstraNoBundle            = errors.New("astra.secure_connect_bundle must be specified")
+	errConfigAstraNoToken             = errors.New("astra.token or astra.token_file must be specified")
+	errConfigAstraAuth                = errors.New("astra cannot be combined with auth, it authenticates with astra.token")
+	errConfigInvalidConsistency       = errors.New("invalid consistency")
+	errConfigFallbackChainDowngrade   = errors.New("consistency_fallback_chain replaces downgrade_consistency, which must be left disabled")
+	errConfigInvalidSerialConsistency = errors.New("invalid serial_consistency")
+	errConfigInvalidBatchSize         = errors.New("batch_size must be greater than zero")
+	errConfigNegativeMaxBatchBytes    = errors.New("max_batch_bytes must not be negative")
+	errConfigInvalidNumWorkers        = errors.New("num_workers must be greater than zero")
+	errConfigInvalidNumConns          = errors.New("num_conns must be greater than zero")
+	errConfigNegativeTTL              = errors.New("ttl must not be negative")
+	errConfigNegativeDefaultTTL       = errors.New("default_ttl must not be negative")
+	errConfigNegativeConnectTimeout   = errors.New("connect_timeout must not be negative")
+	errConfigNegativeStartupTimeout   = errors.New("startup_timeout must not be negative")
+	errConfigNegativeSocketKeepalive  = errors.New("socket_keepalive must not be negative")
+	errConfigNegativeTimeout          = errors.New("timeout must not be negative")
+	errConfigEmptyKeyspace            = errors.New("keyspace must be specified, unless logs_keyspace, traces_keyspace and metrics_keyspace all are")
+	errConfigEmptyTable               = errors.New("table name must be specified")
+	errConfigInvalidIdentifier        = errors.New("must be a cassandra identifier of letters, digits and underscores, optionally double quoted")
+	errConfigInvalidReplicationName   = errors.New("must only contain letters, digits, underscores, dots and dashes")
+	errConfigInvalidCompression       = errors.New("invalid compression.algorithm")
+	errConfigNegativeChunkLength      = errors.New("compression.chunk_length_in_kb must not be negative")
+	errConfigChunkLengthPowerOfTwo    = errors.New("compression.chunk_length_in_kb must be a power of two")
+	errConfigChunkLengthDisabled      = errors.New("compression.chunk_length_in_kb requires compression.algorithm")
+	errConfigInvalidPartitionBy       = errors.New("partition_by must be either hour or day")
+	errConfigInvalidSpanIDs           = errors.New("invalid_span_ids must be either store or drop")
+	errConfigInvalidTimestampFormat   = errors.New("timestamp_format must be either timestamp or bigint_nanos")
+	errConfigNegativeShardCount       = errors.New("shard_count must not be negative")
+	errConfigInvalidClusteringOrder   = errors.New("clustering_order must be either ASC or DESC")
+	errConfigInvalidCompaction        = errors.New("invalid compaction.strategy")
+	errConfigInvalidWindowSize        = errors.New("compaction.compaction_window_size must be greater than zero")
+	errConfigInvalidWindowUnit        = errors.New("invalid compaction.compaction_window_unit")
+	errConfigNegativeGCGrace          = errors.New("gc_grace_seconds must not be negative")
+	errConfigInvalidSpeculativeRetry  = errors.New("speculative_retry must be NONE, ALWAYS, a latency such as 50ms or a percentile such as 99PERCENTILE")
+	errConfigInvalidBodyEncoding      = errors.New("body_encoding must be one of json, text or blob")
+	errConfigInvalidBodyIndent        = errors.New("body_json.indent must only hold spaces and tabs")
+	errConfigInvalidAttributesFormat  = errors.New("attributes_format must be either map or json")
+	errConfigAttributesUDTFormat      = errors.New("attributes_udt replaces attributes_format, which must be left to map")
+	errConfigDeduplicateResources     = errors.New("deduplicate_resources requires store_resource_attributes")
+	errConfigNegativeMaxBodySize      = errors.New("max_body_size must not be negative")
+	errConfigNegativeMaxAttributes    = errors.New("max_attributes must not be negative")
+	errConfigMultilineQueryComment    = errors.New("query_comment must not contain line breaks")
+	errConfigInvalidBatchMode         = errors.New("batch_mode must be one of unlogged, logged or none")
+	errConfigInvalidFlushInterval     = errors.New("coalescing.flush_interval must be greater than zero")
+	errConfigInvalidReconnectInit     = errors.New("reconnection.initial_interval must be greater than zero")
+	errConfigInvalidReconnectMax      = errors.New("reconnection.max_interval must not be less than reconnection.initial_interval")
+	errConfigNegativeReconnectRetry   = errors.New("reconnection.max_retries must not be negative")
+	errConfigNegativeHealthInterval   = errors.New("health_check.interval must not be negative")
+	errConfigInvalidHealthThreshold   = errors.New("health_check.failure_threshold must be at least 1")
+	errConfigInvalidHostFilterCIDR    = errors.New("invalid host_filter.cidrs")
+	errConfigInvalidInsertAttempts    = errors.New("insert_retry.max_attempts must be at least 1")
+	errConfigNegativeInsertBackoff    = errors.New("insert_retry.backoff must not be negative")
+	errConfigNegativeShutdown         = errors.New("shutdown_timeout must not be negative")
+	errConfigNegativeSpeculative      = errors.New("speculative_execution.max_attempts must not be negative")
+	errConfigSpeculativeDelay         = errors.New("speculative_execution.delay must be greater than zero")
+	errConfigInvalidProtoVersion      = errors.New("proto_version must be 0 or between 2 and 5")
+)
+
+var consistencyLevels = []gocql.Consistency{
+	gocql.Any,
+	gocql.One,
+	gocql.Two,
+	gocql.Three,
+	gocql.Quorum,
+	gocql.All,
+	gocql.LocalQuorum,
+	gocql.EachQuorum,
+	gocql.LocalOne,
+}
+
+// Validate checks the Cassandra exporter configuration.
+func (cfg *Config) Validate() (err error) {
+	if !validPort(cfg.Port) {
+		err = errors.Join(err, errConfigInvalidPort)
+	}
+	if cfg.Astra != nil {
+		err = errors.Join(err, cfg.validateAstra())
+	} else {
+		err = errors.Join(err, cfg.validateEndpoints())
+	}
+	if cfg.SchemaTemplate != "" {
+		if _, e := loadSchemaTemplate(cfg.SchemaTemplate); e != nil {
+			err = errors.Join(err, e)
+		}
+	}
+	if cfg.Keyspace == "" && (cfg.LogsKeyspace == "" || cfg.TracesKeyspace == "" || cfg.MetricsKeyspace == "") {
+		err = errors.Join(err, errConfigEmptyKeyspace)
+	}
+	for _, table := range []struct{ option, name string }{
+		{"trace_table", cfg.TraceTable},
+		{"logs_table", cfg.LogsTable},
+		{"metrics_table", cfg.MetricsTable},
+		{"resources_table", cfg.ResourcesTable},
+		{"logs_by_trace_table", cfg.LogsByTraceTable},
+	} {
+		if table.option == "resources_table" && !cfg.DeduplicateResources {
+			// Only written when deduplicating resources.
+			continue
+		}
+		if table.option == "logs_by_trace_table" && !cfg.IndexLogsByTrace {
+			continue
+		}
+		if table.name == "" {
+			err = errors.Join(err, fmt.Errorf("%w: %s", errConfigEmptyTable, table.option))
+			continue
+		}
+		if !identifierPattern.MatchString(table.name) {
+			err = errors.Join(err, fmt.Errorf("%s %q %w", table.option, table.name, errConfigInvalidIdentifier))
+		}
+	}
+	for _, keyspace := range []struct{ option, name string }{
+		{"keyspace", cfg.Keyspace},
+		{"logs_keyspace", cfg.LogsKeyspace},
+		{"traces_keyspace", cfg.TracesKeyspace},
+		{"metrics_keyspace", cfg.MetricsKeyspace},
+	} {
+		if keyspace.name != "" && !identifierPattern.MatchString(keyspace.name) {
+			err = errors.Join(err, fmt.Errorf("%s %q %w", keyspace.option, keyspace.name, errConfigInvalidIdentifier))
+		}
+	}
+	if e := cfg.Replication.validate(); e != nil {
+		err = errors.Join(err, e)
+	}
+	if e := cfg.Compression.validate(); e != nil {
+		err = errors.Join(err, e)
+	}
+	if e := cfg.Compaction.validate(); e != nil {
+		err = errors.Join(err, e)
+	}
+	if cfg.GCGraceSeconds < 0 {
+		err = errors.Join(err, errConfigNegativeGCGrace)
+	}
+	if cfg.SpeculativeRetry != "" && !speculativeRetryPattern.MatchString(cfg.SpeculativeRetry) {
+		err = errors.Join(err, fmt.Errorf("%w, got %q", errConfigInvalidSpeculativeRetry, cfg.SpeculativeRetry))
+	}
+	hasPassword := cfg.Auth.Password != "" || cfg.Auth.PasswordFile != ""
+	if cfg.Auth.UserName != "" && !hasPassword {
+		err = errors.Join(err, errConfigEmptyPassword)
+	}
+	if hasPassword && cfg.Auth.UserName == "" {
+		err = errors.Join(err, errConfigEmptyUserName)
+	}
+	if cfg.Auth.SigV4 != nil {
+		if cfg.Auth.SigV4.Region == "" {
+			err = errors.Join(err, errConfigSigV4NoRegion)
+		}
+		if cfg.Auth.UserName != "" || hasPassword {
+			err = errors.Join(err, errConfigSigV4Password)
+		}
+		if cfg.TLS.Insecure {
+			err = errors.Join(err, errConfigSigV4Insecure)
+		}
+	}
+	if cfg.TokenAware && cfg.LocalDC == "" {
+		err = errors.Join(err, errConfigTokenAwareNoDC)
+	}
+	if _, e := parseConsistency(cfg.Consistency); e != nil {
+		err = errors.Join(err, e)
+	}
+	for _, consistency := range []struct{ option, name string }{
+		{"logs_consistency", cfg.LogsConsistency},
+		{"traces_consistency", cfg.TracesConsistency},
+		{"metrics_consistency", cfg.MetricsConsistency},
+	} {
+		if consistency.name == "" {
+			continue
+		}
+		if _, e := parseConsistency(consistency.name); e != nil {
+			err = errors.Join(err, fmt.Errorf("%s: %w", consistency.option, e))
+		}
+	}
+	for i, consistency := range cfg.ConsistencyFallbackChain {
+		if _, e := parseConsistency(consistency); e != nil {
+			err = errors.Join(err, fmt.Errorf("consistency_fallback_chain[%d]: %w", i, e))
+		}
+	}
+	if len(cfg.ConsistencyFallbackChain) > 0 && cfg.DowngradeConsistency {
+		err = errors.Join(err, errConfigFallbackChainDowngrade)
+	}
+	if _, e := parseSerialConsistency(cfg.SerialConsistency); e != nil {
+		err = errors.Join(err, e)
+	}
+	if cfg.BatchSize <= 0 {
+		err = errors.Join(err, errConfigInvalidBatchSize)
+	}
+	if cfg.MaxBatchBytes < 0 {
+		err = errors.Join(err, errConfigNegativeMaxBatchBytes)
+	}
+	if cfg.NumWorkers <= 0 {
+		err = errors.Join(err, errConfigInvalidNumWorkers)
+	}
+	if cfg.NumConns <= 0 {
+		err = errors.Join(err, errConfigInvalidNumConns)
+	}
+	if cfg.SpeculativeExecution.MaxAttempts < 0 {
+		err = errors.Join(err, errConfigNegativeSpeculative)
+	}
+	if cfg.SpeculativeExecution.MaxAttempts > 0 && cfg.SpeculativeExecution.Delay <= 0 {
+		err = errors.Join(err, errConfigSpeculativeDelay)
+	}
+	if cfg.TTL < 0 {
+		err = errors.Join(err, errConfigNegativeTTL)
+	}
+	if cfg.DefaultTTL < 0 {
+		err = errors.Join(err, errConfigNegativeDefaultTTL)
+	}
+	if cfg.PartitionBy != partitionByHour && cfg.PartitionBy != partitionByDay {
+		err = errors.Join(err, fmt.Errorf("%w, got %q", errConfigInvalidPartitionBy, cfg.PartitionBy))
+	}
+	if cfg.InvalidSpanIDs != invalidSpanIDsStore && cfg.InvalidSpanIDs != invalidSpanIDsDrop {
+		err = errors.Join(err, fmt.Errorf("%w, got %q", errConfigInvalidSpanIDs, cfg.InvalidSpanIDs))
+	}
+	if cfg.TimestampFormat != timestampFormatTimestamp && cfg.TimestampFormat != timestampFormatBigintNanos {
+		err = errors.Join(err, fmt.Errorf("%w, got %q", errConfigInvalidTimestampFormat, cfg.TimestampFormat))
+	}
+	if cfg.ClusteringOrder != clusteringOrderAsc && cfg.ClusteringOrder != clusteringOrderDesc {
+		err = errors.Join(err, fmt.Errorf("%w, got %q", errConfigInvalidClusteringOrder, cfg.ClusteringOrder))
+	}
+	switch cfg.BodyEncoding {
+	case bodyEncodingJSON, bodyEncodingText, bodyEncodingBlob:
+	default:
+		err = errors.Join(err, fmt.Errorf("%w, got %q", errConfigInvalidBodyEncoding, cfg.BodyEncoding))
+	}
+	if strings.Trim(cfg.BodyJSON.Indent, " \t") != "" {
+		err = errors.Join(err, errConfigInvalidBodyIndent)
+	}
+	if cfg.AttributesFormat != attributesFormatMap && cfg.AttributesFormat != attributesFormatJSON {
+		err = errors.Join(err, fmt.Errorf("%w, got %q", errConfigInvalidAttributesFormat, cfg.AttributesFormat))
+	}
+	if cfg.AttributesUDT != "" {
+		if cfg.AttributesFormat != attributesFormatMap {
+			err = errors.Join(err, errConfigAttributesUDTFormat)
+		}
+		if !identifierPattern.MatchString(cfg.AttributesUDT) {
+			err = errors.Join(err, fmt.Errorf("attributes_udt %q %w", cfg.AttributesUDT, errConfigInvalidIdentifier))
+		}
+	}
+	if cfg.DeduplicateResources && !cfg.StoreResourceAttributes {
+		err = errors.Join(err, errConfigDeduplicateResources)
+	}
+	switch cfg.BatchMode {
+	case batchModeUnlogged, batchModeLogged, batchModeNone:
+	default:
+		err = errors.Join(err, fmt.Errorf("%w, got %q", errConfigInvalidBatchMode, cfg.BatchMode))
+	}
+	if cfg.Coalescing.Enabled && cfg.Coalescing.FlushInterval <= 0 {
+		err = errors.Join(err, errConfigInvalidFlushInterval)
+	}
+	if cfg.MaxBodySize < 0 {
+		err = errors.Join(err, errConfigNegativeMaxBodySize)
+	}
+	if cfg.MaxAttributes < 0 {
+		err = errors.Join(err, errConfigNegativeMaxAttributes)
+	}
+	if cfg.ShardCount < 0 {
+		err = errors.Join(err, errConfigNegativeShardCount)
+	}
+	if strings.ContainsAny(cfg.QueryComment, "\r\n") {
+		err = errors.Join(err, errConfigMultilineQueryComment)
+	}
+	if e := cfg.Reconnection.validate(); e != nil {
+		err = errors.Join(err, e)
+	}
+	if e := cfg.HealthCheck.validate(); e != nil {
+		err = errors.Join(err, e)
+	}
+	if e := cfg.HostFilter.validate(); e != nil {
+		err = errors.Join(err, e)
+	}
+	if e := cfg.InsertRetry.validate(); e != nil {
+		err = errors.Join(err, e)
+	}
+	if cfg.Timeout < 0 {
+		err = errors.Join(err, errConfigNegativeTimeout)
+	}
+	if cfg.ShutdownTimeout < 0 {
+		err = errors.Join(err, errConfigNegativeShutdown)
+	}
+	if cfg.ProtoVersion != 0 && (cfg.ProtoVersion < 2 || cfg.ProtoVersion > 5) {
+		err = errors.Join(err, errConfigInvalidProtoVersion)
+	}
+	if cfg.ConnectTimeout < 0 {
+		err = errors.Join(err, errConfigNegativeConnectTimeout)
+	}
+	if cfg.StartupTimeout < 0 {
+		err = errors.Join(err, errConfigNegativeStartupTimeout)
+	}
+	if cfg.SocketKeepalive < 0 {
+		err = errors.Join(err, errConfigNegativeSocketKeepalive)
+	}
+	return err
+}
+
+// validateEndpoints checks the contact points, Astra ignores them.
+func (cfg *Config) validateEndpoints() (err error) {
+	contactPoints := cfg.contactPoints()
+	if len(contactPoints) == 0 {
+		err = errors.Join(err, errConfigNoEndpoint)
+	}
+	for _, endpoint := range contactPoints {
+		if _, _, e := parseEndpoint(endpoint, cfg.Port); e != nil {
+			err = errors.Join(err, e)
+		}
+	}
+	return err
+}
+
+func (cfg *Config) validateAstra() (err error) {
+	if cfg.Astra.SecureConnectBundle == "" {
+		err = errors.Join(err, errConfigAstraNoBundle)
+	}
+	if cfg.Astra.Token == "" && cfg.Astra.TokenFile == "" {
+		err = errors.Join(err, errConfigAstraNoToken)
+	}
+	if cfg.Auth.UserName != "" || cfg.Auth.Password != "" || cfg.Auth.PasswordFile != "" || cfg.Auth.SigV4 != nil {
+		err = errors.Join(err, errConfigAstraAuth)
+	}
+	return err
+}
+
+func (r Replication) validate() error {
+	if !replicationNamePattern.MatchString(r.Class) {
+		return fmt.Errorf("replication.class %q %w", r.Class, errConfigInvalidReplicationName)
+	}
+	if r.Class != networkTopologyStrategy {
+		if r.ReplicationFactor <= 0 {
+			return fmt.Errorf("%w: replication.replication_factor", errConfigInvalidReplication)
+		}
+		return nil
+	}
+	if len(r.DataCenters) == 0 {
+		return errConfigNoDataCenters
+	}
+	var err error
+	for dc, factor := range r.DataCenters {
+		if !replicationNamePattern.MatchString(dc) {
+			err = errors.Join(err, fmt.Errorf("replication.data_centers %q %w", dc, errConfigInvalidReplicationName))
+		}
+		if factor <= 0 {
+			err = errors.Join(err, fmt.Errorf("%w: replication.data_centers.%s", errConfigInvalidReplication, dc))
+		}
+	}
+	return err
+}
+
+func (c Compression) validate() (err error) {
+	if c.Algorithm != "" && !slices.Contains(compressionAlgorithms, c.Algorithm) {
+		err = errors.Join(err, fmt.Errorf("%w %q, must be empty or one of: %s",
+			errConfigInvalidCompression, c.Algorithm, strings.Join(compressionAlgorithms, ", ")))
+	}
+	if c.ChunkLength < 0 {
+		err = errors.Join(err, errConfigNegativeChunkLength)
+	}
+	// Cassandra rejects the other chunk lengths when creating the table.
+	if c.ChunkLength > 0 && c.ChunkLength&(c.ChunkLength-1) != 0 {
+		err = errors.Join(err, fmt.Errorf("%w, got %d", errConfigChunkLengthPowerOfTwo, c.ChunkLength))
+	}
+	if c.ChunkLength != 0 && c.Algorithm == "" {
+		err = errors.Join(err, errConfigChunkLengthDisabled)
+	}
+	return err
+}
+
+func (r Reconnection) validate() (err error) {
+	if r.InitialInterval <= 0 {
+		err = errors.Join(err, errConfigInvalidReconnectInit)
+	}
+	if r.MaxInterval < r.InitialInterval {
+		err = errors.Join(err, errConfigInvalidReconnectMax)
+	}
+	if r.MaxRetries < 0 {
+		err = errors.Join(err, errConfigNegativeReconnectRetry)
+	}
+	return err
+}
+
+func (h HealthCheck) validate() (err error) {
+	if h.Interval < 0 {
+		err = errors.Join(err, errConfigNegativeHealthInterval)
+	}
+	if h.Interval > 0 && h.FailureThreshold < 1 {
+		err = errors.Join(err, errConfigInvalidHealthThreshold)
+	}
+	return err
+}
+
+func (f HostFilter) validate() (err error) {
+	for _, cidr := range f.CIDRs {
+		if _, _, e := net.ParseCIDR(cidr); e != nil {
+			err = errors.Join(err, fmt.Errorf("%w %q", errConfigInvalidHostFilterCIDR, cidr))
+		}
+	}
+	return err
+}
+
+func (r InsertRetry) validate() (err error) {
+	if r.MaxAttempts < 1 {
+		err = errors.Join(err, errConfigInvalidInsertAttempts)
+	}
+	if r.Backoff < 0 {
+		err = errors.Join(err, errConfigNegativeInsertBackoff)
+	}
+	return err
+}
+
+func (c Compaction) validate() (err error) {
+	if !slices.Contains(compactionStrategies, c.Strategy) {
+		return fmt.Errorf("%w %q, must be one of: %s",
+			errConfigInvalidCompaction, c.Strategy, strings.Join(compactionStrategies, ", "))
+	}
+	if c.Strategy != timeWindowCompactionStrategy {
+		return nil
+	}
+	if c.CompactionWindowSize <= 0 {
+		err = errors.Join(err, errConfigInvalidWindowSize)
+	}
+	if !slices.Contains(compactionWindowUnits, c.CompactionWindowUnit) {
+		err = errors.Join(err, fmt.Errorf("%w %q, must be one of: %s",
+			errConfigInvalidWindowUnit, c.CompactionWindowUnit, strings.Join(compactionWindowUnits, ", ")))
+	}
+	return err
+}
+
+// contactPoints returns the hosts the cluster is discovered from. The endpoints
+// list takes precedence over the deprecated single dsn.
+func (cfg *Config) contactPoints() []string {
+	if len(cfg.Endpoints) > 0 {
+		return cfg.Endpoints
+	}
+	if cfg.DSN != "" {
+		return []string{cfg.DSN}
+	}
+	return nil
+}
+
+// parseEndpoint splits an endpoint given as host or host:port. Endpoints
+// without a port use defaultPort.
+func parseEndpoint(endpoint string, defaultPort int) (string, int, error) {
+	host, portStr, err := splitEndpoint(endpoint)
+	if err != nil {
+		return "", 0, err
+	}
+	if portStr == "" {
+		return host, defaultPort, nil
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil || !validPort(port) {
+		return "", 0, fmt.Errorf("%w %q: invalid port %q", errConfigInvalidEndpoint, endpoint, portStr)
+	}
+	return host, port, nil
+}
+
+// splitEndpoint splits an endpoint into its host and its possibly empty port.
+// IPv6 literals are given bare, as in ::1, or bracketed, as in [::1] or
+// [::1]:9042; the returned host is never bracketed.
+func splitEndpoint(endpoint string) (string, string, error) {
+	host, port, err := net.SplitHostPort(endpoint)
+	if err != nil {
+		// No port, the whole endpoint is the host.
+		host, port = endpoint, ""
+		if len(host) > 1 && host[0] == '[' && host[len(host)-1] == ']' {
+			host = host[1 : len(host)-1]
+		}
+	}
+	if host == "" {
+		return "", "", fmt.Errorf("%w %q: empty host", errConfigInvalidEndpoint, endpoint)
+	}
+	if strings.ContainsAny(host, "[]") {
+		return "", "", fmt.Errorf("%w %q: unbalanced brackets", errConfigInvalidEndpoint, endpoint)
+	}
+	return host, port, nil
+}
+
+// contactPointAddresses returns the contact points in the form gocql parses,
+// host or host:port with IPv6 hosts bracketed only when a port follows.
+func (cfg *Config) contactPointAddresses() ([]string, error) {
+	contactPoints := cfg.contactPoints()
+	addresses := make([]string, 0, len(contactPoints))
+	for _, endpoint := range contactPoints {
+		host, port, err := splitEndpoint(endpoint)
+		if err != nil {
+			return nil, err
+		}
+		if port == "" {
+			addresses = append(addresses, host)
+			continue
+		}
+		addresses = append(addresses, net.JoinHostPort(host, port))
+	}
+	return addresses, nil
+}
+
+func validPort(port int) bool {
+	return port > 0 && port <= 65535
+}
+
+// createsKeyspace reports whether the exporter creates its keyspace on start.
+func (cfg *Config) createsKeyspace() bool {
+	return cfg.CreateSchema && cfg.CreateKeyspace
+}
+
+// forSignal returns a copy of cfg writing to the given keyspace with the given
+// consistency, each falling back to the one of cfg when empty, with every
+// identifier quoted as needed. Each exporter opens its own session with the
+// configuration of its signal, so the DDL, the inserts and the session all
+// follow the overrides.
+func (cfg *Config) forSignal(keyspace, consistency string) *Config {
+	signalCfg := *cfg
+	if keyspace != "" {
+		signalCfg.Keyspace = keyspace
+	}
+	if consistency != "" {
+		signalCfg.Consistency = consistency
+	}
+	for _, identifier := range []*string{
+		&signalCfg.Keyspace,
+		&signalCfg.TraceTable,
+		&signalCfg.LogsTable,
+		&signalCfg.ResourcesTable,
+		&signalCfg.LogsByTraceTable,
+		&signalCfg.MetricsTable,
+		&signalCfg.AttributesUDT,
+	} {
+		*identifier = quoteIdentifier(*identifier)
+	}
+	return &signalCfg
+}
+
+// parseConsistency maps a consistency name such as "LOCAL_QUORUM" onto its gocql level.
+func parseConsistency(consistency string) (gocql.Consistency, error) {
+	c, err := gocql.ParseConsistencyWrapper(consistency)
+	if err != nil {
+		names := make([]string, 0, len(consistencyLevels))
+		for _, level := range consistencyLevels {
+			names = append(names, level.String())
+		}
+		return c, fmt.Errorf("%w %q, must be one of: %s", errConfigInvalidConsistency, consistency, strings.Join(names, ", "))
+	}
+	return c, nil
+}
+
+// parseSerialConsistency maps "SERIAL" or "LOCAL_SERIAL" onto its gocql level.
+// An empty name returns 0, which leaves the gocql default in place.
+func parseSerialConsistency(consistency string) (gocql.SerialConsistency, error) {
+	if consistency == "" {
+		return 0, nil
+	}
+	var c gocql.SerialConsistency
+	if err := c.UnmarshalText([]byte(strings.ToUpper(consistency))); err != nil {
+		return c, fmt.Errorf("%w %q, must be one of: %s, %s", errConfigInvalidSerialConsistency, consistency, gocql.Serial, gocql.LocalSerial)
+	}
+	return c, nil
+}
+
+// suffixTable returns the name of the table derived from table with suffix,
+// keeping a quoted name quoted.
+func suffixTable(table, suffix string) string {
+	if unquoted, ok := strings.CutSuffix(table, `"`); ok {
+		return unquoted + suffix + `"`
+	}
+	return table + suffix
+}
+
+// unquotedIdentifierPattern matches the identifiers Cassandra keeps as they
+// are; it lower-cases any other unquoted one.
+var unquotedIdentifierPattern = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// quoteIdentifier double quotes a valid identifier that Cassandra would
+// otherwise lower-case or reject, such as MyLogs or 2024_logs, so that it
+// names the table or keyspace as written.
+func quoteIdentifier(identifier string) string {
+	if identifier == "" || strings.HasPrefix(identifier, `"`) || unquotedIdentifierPattern.MatchString(identifier) {
+		return identifier
+	}
+	return `"` + identifier + `"`
+}
+
+// unquoteIdentifier returns the identifier without its double quotes, as
+// gocql expects the keyspace of a session.
+func unquoteIdentifier(identifier string) string {
+	return strings.Trim(identifier, `"`)
 }
@@ -6,6 +6,7 @@ package cassandraexporter
 import (
 	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
@@ -28,6 +29,24 @@ func TestLoadConfig(t *testing.T) {
 	defaultCfg.(*Config).DSN = defaultDSN
 	defaultCfg.(*Config).Port = defaultPort
 
+	helperCfg := createDefaultConfig().(*Config)
+	helperCfg.TimeoutSettings.Timeout = 5 * time.Second
+	helperCfg.ConnectTimeout = 2 * time.Second
+	helperCfg.QueueSettings.Enabled = true
+	helperCfg.QueueSettings.NumConsumers = 2
+	helperCfg.QueueSettings.QueueSize = 100
+	helperCfg.BackOffConfig.Enabled = true
+	helperCfg.BackOffConfig.InitialInterval = time.Second
+	helperCfg.BackOffConfig.MaxInterval = 10 * time.Second
+	helperCfg.BackOffConfig.MaxElapsedTime = time.Minute
+
+	topologyCfg := createDefaultConfig().(*Config)
+	topologyCfg.Replication = Replication{
+		Class:             "NetworkTopologyStrategy",
+		ReplicationFactor: 1,
+		DataCenters:       map[string]int{"dc1": 3, "dc2": 2},
+	}
+
 	tests := []struct {
 		id       component.ID
 		expected component.Config
@@ -37,6 +56,14 @@ func TestLoadConfig(t *testing.T) {
 			id:       component.NewIDWithName(metadata.Type, ""),
 			expected: defaultCfg,
 		},
+		{
+			id:       component.NewIDWithName(metadata.Type, "exporterhelper"),
+			expected: helperCfg,
+		},
+		{
+			id:       component.NewIDWithName(metadata.Type, "network_topology"),
+			expected: topologyCfg,
+		},
 	}
 
 	for _, tt := range tests {
@@ -53,3 +80,805 @@ func TestLoadConfig(t *testing.T) {
 		})
 	}
 }
+
+func TestConfigValidate(t *testing.T) {
+	testCases := map[string]struct {
+		cfg         *Config
+		expectedErr error
+	}{
+		"default": {
+			cfg: withDefaultConfig(),
+		},
+		"endpoints": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Endpoints = []string{"cassandra-1", "cassandra-2:9142", "[::1]:9042"}
+			}),
+		},
+		"no_endpoint": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.DSN = ""
+			}),
+			expectedErr: errConfigNoEndpoint,
+		},
+		"invalid_endpoint_port": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Endpoints = []string{"cassandra-1:cql"}
+			}),
+			expectedErr: errConfigInvalidEndpoint,
+		},
+		"endpoint_port_out_of_range": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Endpoints = []string{"cassandra-1:70000"}
+			}),
+			expectedErr: errConfigInvalidEndpoint,
+		},
+		"zero_port": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Port = 0
+			}),
+			expectedErr: errConfigInvalidPort,
+		},
+		"port_out_of_range": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Port = 65536
+			}),
+			expectedErr: errConfigInvalidPort,
+		},
+		"empty_endpoint": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Endpoints = []string{"cassandra-1", ""}
+			}),
+			expectedErr: errConfigInvalidEndpoint,
+		},
+		"network_topology_strategy": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Replication.Class = "NetworkTopologyStrategy"
+				config.Replication.DataCenters = map[string]int{"dc1": 3}
+			}),
+		},
+		"network_topology_strategy_without_data_centers": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Replication.Class = "NetworkTopologyStrategy"
+			}),
+			expectedErr: errConfigNoDataCenters,
+		},
+		"zero_data_center_replication_factor": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Replication.Class = "NetworkTopologyStrategy"
+				config.Replication.DataCenters = map[string]int{"dc1": 3, "dc2": 0}
+			}),
+			expectedErr: errConfigInvalidReplication,
+		},
+		"zero_replication_factor": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Replication.ReplicationFactor = 0
+			}),
+			expectedErr: errConfigInvalidReplication,
+		},
+		"token_aware": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.LocalDC = "dc1"
+				config.TokenAware = true
+			}),
+		},
+		"token_aware_without_local_dc": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.TokenAware = true
+			}),
+			expectedErr: errConfigTokenAwareNoDC,
+		},
+		"sigv4": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Auth.SigV4 = &SigV4{Region: "us-east-1"}
+				config.TLS.Insecure = false
+			}),
+		},
+		"sigv4_without_region": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Auth.SigV4 = &SigV4{}
+				config.TLS.Insecure = false
+			}),
+			expectedErr: errConfigSigV4NoRegion,
+		},
+		"sigv4_with_password": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Auth.SigV4 = &SigV4{Region: "us-east-1"}
+				config.Auth.UserName = "user"
+				config.Auth.Password = "pass"
+				config.TLS.Insecure = false
+			}),
+			expectedErr: errConfigSigV4Password,
+		},
+		"sigv4_without_tls": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Auth.SigV4 = &SigV4{Region: "us-east-1"}
+			}),
+			expectedErr: errConfigSigV4Insecure,
+		},
+		"astra": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.DSN = ""
+				config.Astra = &Astra{SecureConnectBundle: "secure-connect.zip", Token: "AstraCS:secret"}
+			}),
+		},
+		"astra_without_bundle": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Astra = &Astra{Token: "AstraCS:secret"}
+			}),
+			expectedErr: errConfigAstraNoBundle,
+		},
+		"astra_without_token": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Astra = &Astra{SecureConnectBundle: "secure-connect.zip"}
+			}),
+			expectedErr: errConfigAstraNoToken,
+		},
+		"astra_token_file": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Astra = &Astra{SecureConnectBundle: "secure-connect.zip", TokenFile: "/var/run/secrets/astra-token"}
+			}),
+		},
+		"astra_with_auth": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Astra = &Astra{SecureConnectBundle: "secure-connect.zip", Token: "AstraCS:secret"}
+				config.Auth.UserName = "user"
+				config.Auth.Password = "pass"
+			}),
+			expectedErr: errConfigAstraAuth,
+		},
+		"empty_username": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Auth.Password = "pass"
+			}),
+			expectedErr: errConfigEmptyUserName,
+		},
+		"empty_password": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Auth.UserName = "user"
+			}),
+			expectedErr: errConfigEmptyPassword,
+		},
+		"password_file": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Auth.UserName = "user"
+				config.Auth.PasswordFile = "/var/run/secrets/cassandra-password"
+			}),
+		},
+		"password_file_without_username": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Auth.PasswordFile = "/var/run/secrets/cassandra-password"
+			}),
+			expectedErr: errConfigEmptyUserName,
+		},
+		"success_auth": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Auth.UserName = "user"
+				config.Auth.Password = "pass"
+			}),
+		},
+		"lowercase_consistency": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Consistency = "local_quorum"
+			}),
+		},
+		"invalid_consistency": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Consistency = "MOST"
+			}),
+			expectedErr: errConfigInvalidConsistency,
+		},
+		"signal_consistency": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.LogsConsistency = "ONE"
+				config.TracesConsistency = "local_quorum"
+				config.MetricsConsistency = "LOCAL_ONE"
+			}),
+		},
+		"invalid_signal_consistency": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.TracesConsistency = "MOST"
+			}),
+			expectedErr: errConfigInvalidConsistency,
+		},
+		"lowercase_serial_consistency": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.SerialConsistency = "serial"
+			}),
+		},
+		"invalid_serial_consistency": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.SerialConsistency = "QUORUM"
+			}),
+			expectedErr: errConfigInvalidSerialConsistency,
+		},
+		"schema_template_without_keyspace": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.SchemaTemplate = "CREATE TABLE otel.{{.Table}} (id int PRIMARY KEY)"
+			}),
+			expectedErr: errConfigSchemaTemplatePlaceholders,
+		},
+		"signal_keyspaces_only": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Keyspace = ""
+				config.LogsKeyspace = "logs"
+				config.TracesKeyspace = "traces"
+				config.MetricsKeyspace = "metrics"
+			}),
+		},
+		"signal_keyspace_missing": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Keyspace = ""
+				config.LogsKeyspace = "logs"
+				config.TracesKeyspace = "traces"
+			}),
+			expectedErr: errConfigEmptyKeyspace,
+		},
+		"negative_max_body_size": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.MaxBodySize = -1
+			}),
+			expectedErr: errConfigNegativeMaxBodySize,
+		},
+		"invalid_batch_mode": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.BatchMode = "counter"
+			}),
+			expectedErr: errConfigInvalidBatchMode,
+		},
+		"coalescing_without_flush_interval": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Coalescing = Coalescing{Enabled: true}
+			}),
+			expectedErr: errConfigInvalidFlushInterval,
+		},
+		"zero_batch_size": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.BatchSize = 0
+			}),
+			expectedErr: errConfigInvalidBatchSize,
+		},
+		"negative_connect_timeout": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.ConnectTimeout = -time.Second
+			}),
+			expectedErr: errConfigNegativeConnectTimeout,
+		},
+		"host_filter": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.HostFilter = HostFilter{DataCenter: "dc1", Rack: "rack1", CIDRs: []string{"10.1.0.0/16", "fd00::/8"}}
+			}),
+		},
+		"invalid_host_filter_cidr": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.HostFilter.CIDRs = []string{"10.1.0.0/16", "10.2.0.0"}
+			}),
+			expectedErr: errConfigInvalidHostFilterCIDR,
+		},
+		"body_json_indent": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.BodyJSON = BodyJSON{EscapeHTML: true, Indent: "\t"}
+			}),
+		},
+		"invalid_body_json_indent": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.BodyJSON.Indent = "--"
+			}),
+			expectedErr: errConfigInvalidBodyIndent,
+		},
+		"negative_startup_timeout": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.StartupTimeout = -time.Second
+			}),
+			expectedErr: errConfigNegativeStartupTimeout,
+		},
+		"negative_max_attributes": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.MaxAttributes = -1
+			}),
+			expectedErr: errConfigNegativeMaxAttributes,
+		},
+		"negative_socket_keepalive": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.SocketKeepalive = -time.Second
+			}),
+			expectedErr: errConfigNegativeSocketKeepalive,
+		},
+		"zero_num_conns": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.NumConns = 0
+			}),
+			expectedErr: errConfigInvalidNumConns,
+		},
+		"zero_num_workers": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.NumWorkers = 0
+			}),
+			expectedErr: errConfigInvalidNumWorkers,
+		},
+		"negative_ttl": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.TTL = -time.Hour
+			}),
+			expectedErr: errConfigNegativeTTL,
+		},
+		"negative_max_batch_bytes": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.MaxBatchBytes = -1
+			}),
+			expectedErr: errConfigNegativeMaxBatchBytes,
+		},
+		"negative_default_ttl": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.DefaultTTL = -time.Hour
+			}),
+			expectedErr: errConfigNegativeDefaultTTL,
+		},
+		"compression_algorithms": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Compression = Compression{Algorithm: "SnappyCompressor", ChunkLength: 16}
+			}),
+		},
+		"compression_disabled": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Compression.Algorithm = ""
+			}),
+		},
+		"invalid_compression": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Compression.Algorithm = "LZ4Compressor'} AND comment = '"
+			}),
+			expectedErr: errConfigInvalidCompression,
+		},
+		"negative_chunk_length": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Compression.ChunkLength = -1
+			}),
+			expectedErr: errConfigNegativeChunkLength,
+		},
+		"chunk_length_not_power_of_two": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Compression.ChunkLength = 48
+			}),
+			expectedErr: errConfigChunkLengthPowerOfTwo,
+		},
+		"chunk_length_without_compression": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Compression = Compression{ChunkLength: 16}
+			}),
+			expectedErr: errConfigChunkLengthDisabled,
+		},
+		"partition_by_hour": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.PartitionBy = partitionByHour
+			}),
+		},
+		"invalid_partition_by": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.PartitionBy = "week"
+			}),
+			expectedErr: errConfigInvalidPartitionBy,
+		},
+		"invalid_span_ids": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.InvalidSpanIDs = "flag"
+			}),
+			expectedErr: errConfigInvalidSpanIDs,
+		},
+		"multiline_query_comment": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.QueryComment = "tenant=acme\nDROP KEYSPACE otel;"
+			}),
+			expectedErr: errConfigMultilineQueryComment,
+		},
+		"deduplicate_resources_without_resource_attributes": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.DeduplicateResources = true
+				config.StoreResourceAttributes = false
+			}),
+			expectedErr: errConfigDeduplicateResources,
+		},
+		"health_check": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.HealthCheck.Interval = 30 * time.Second
+			}),
+		},
+		"negative_health_check_interval": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.HealthCheck.Interval = -time.Second
+			}),
+			expectedErr: errConfigNegativeHealthInterval,
+		},
+		"invalid_health_check_failure_threshold": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.HealthCheck = HealthCheck{Interval: 30 * time.Second}
+			}),
+			expectedErr: errConfigInvalidHealthThreshold,
+		},
+		"consistency_fallback_chain": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Consistency = "LOCAL_QUORUM"
+				config.ConsistencyFallbackChain = []string{"QUORUM", "ONE"}
+			}),
+		},
+		"invalid_consistency_fallback_chain": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.ConsistencyFallbackChain = []string{"QUORUM", "MOST"}
+			}),
+			expectedErr: errConfigInvalidConsistency,
+		},
+		"consistency_fallback_chain_with_downgrade": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.ConsistencyFallbackChain = []string{"ONE"}
+				config.DowngradeConsistency = true
+			}),
+			expectedErr: errConfigFallbackChainDowngrade,
+		},
+		"invalid_timestamp_format": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.TimestampFormat = "unix"
+			}),
+			expectedErr: errConfigInvalidTimestampFormat,
+		},
+		"negative_shard_count": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.ShardCount = -1
+			}),
+			expectedErr: errConfigNegativeShardCount,
+		},
+		"size_tiered_compaction": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Compaction = Compaction{Strategy: "SizeTieredCompactionStrategy"}
+			}),
+		},
+		"invalid_compaction": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Compaction.Strategy = "DateTieredCompactionStrategy"
+			}),
+			expectedErr: errConfigInvalidCompaction,
+		},
+		"zero_compaction_window_size": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Compaction.CompactionWindowSize = 0
+			}),
+			expectedErr: errConfigInvalidWindowSize,
+		},
+		"invalid_compaction_window_unit": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Compaction.CompactionWindowUnit = "WEEKS"
+			}),
+			expectedErr: errConfigInvalidWindowUnit,
+		},
+		"speculative_retry": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.SpeculativeRetry = "MAX(99p, 50ms)"
+			}),
+		},
+		"invalid_speculative_retry": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.SpeculativeRetry = "99PERCENTILE' AND comment = 'x"
+			}),
+			expectedErr: errConfigInvalidSpeculativeRetry,
+		},
+		"negative_gc_grace_seconds": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.GCGraceSeconds = -1
+			}),
+			expectedErr: errConfigNegativeGCGrace,
+		},
+		"blob_body_encoding": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.BodyEncoding = bodyEncodingBlob
+			}),
+		},
+		"invalid_body_encoding": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.BodyEncoding = "xml"
+			}),
+			expectedErr: errConfigInvalidBodyEncoding,
+		},
+		"desc_clustering_order": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.ClusteringOrder = clusteringOrderDesc
+			}),
+		},
+		"invalid_clustering_order": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.ClusteringOrder = "desc"
+			}),
+			expectedErr: errConfigInvalidClusteringOrder,
+		},
+		"attributes_udt": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.AttributesUDT = "otel_attributes"
+			}),
+		},
+		"attributes_udt_with_json": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.AttributesUDT = "otel_attributes"
+				config.AttributesFormat = attributesFormatJSON
+			}),
+			expectedErr: errConfigAttributesUDTFormat,
+		},
+		"attributes_udt_injection": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.AttributesUDT = "otel_attributes>, x text"
+			}),
+			expectedErr: errConfigInvalidIdentifier,
+		},
+		"json_attributes_format": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.AttributesFormat = attributesFormatJSON
+			}),
+		},
+		"invalid_attributes_format": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.AttributesFormat = "list"
+			}),
+			expectedErr: errConfigInvalidAttributesFormat,
+		},
+		"quoted_identifiers": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Keyspace = `"Telemetry"`
+				config.LogsTable = `"Logs_2024"`
+				config.TraceTable = "spans"
+			}),
+		},
+		"keyspace_injection": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Keyspace = "otel WITH REPLICATION = {'class': 'SimpleStrategy', 'replication_factor': 1}; DROP KEYSPACE prod; --"
+			}),
+			expectedErr: errConfigInvalidIdentifier,
+		},
+		"logs_keyspace_with_dot": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.LogsKeyspace = "system.local"
+			}),
+			expectedErr: errConfigInvalidIdentifier,
+		},
+		"table_injection": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.LogsTable = "otel_logs (id int PRIMARY KEY); DROP TABLE otel.otel_spans; --"
+			}),
+			expectedErr: errConfigInvalidIdentifier,
+		},
+		"table_unbalanced_quote": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.TraceTable = `"otel_spans`
+			}),
+			expectedErr: errConfigInvalidIdentifier,
+		},
+		"table_quote_escape": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.MetricsTable = `"otel" ; "metrics"`
+			}),
+			expectedErr: errConfigInvalidIdentifier,
+		},
+		"replication_class_injection": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Replication.Class = "SimpleStrategy', 'replication_factor' : 3 } AND durable_writes = false; --"
+			}),
+			expectedErr: errConfigInvalidReplicationName,
+		},
+		"data_center_injection": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Replication = Replication{
+					Class:       networkTopologyStrategy,
+					DataCenters: map[string]int{"eu-west' : 3 }; --": 3},
+				}
+			}),
+			expectedErr: errConfigInvalidReplicationName,
+		},
+		"empty_resources_table": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.DeduplicateResources = true
+				config.ResourcesTable = ""
+			}),
+			expectedErr: errConfigEmptyTable,
+		},
+		"empty_resources_table_unused": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.ResourcesTable = ""
+			}),
+		},
+		"empty_logs_by_trace_table": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.IndexLogsByTrace = true
+				config.LogsByTraceTable = ""
+			}),
+			expectedErr: errConfigEmptyTable,
+		},
+		"insert_retry": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.InsertRetry = InsertRetry{MaxAttempts: 3, Backoff: 0}
+			}),
+		},
+		"zero_insert_retry_attempts": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.InsertRetry.MaxAttempts = 0
+			}),
+			expectedErr: errConfigInvalidInsertAttempts,
+		},
+		"negative_insert_retry_backoff": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.InsertRetry.Backoff = -time.Second
+			}),
+			expectedErr: errConfigNegativeInsertBackoff,
+		},
+		"zero_reconnection_initial_interval": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Reconnection.InitialInterval = 0
+			}),
+			expectedErr: errConfigInvalidReconnectInit,
+		},
+		"reconnection_max_interval_below_initial": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Reconnection.MaxInterval = 500 * time.Millisecond
+			}),
+			expectedErr: errConfigInvalidReconnectMax,
+		},
+		"negative_reconnection_max_retries": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Reconnection.MaxRetries = -1
+			}),
+			expectedErr: errConfigNegativeReconnectRetry,
+		},
+		"negative_shutdown_timeout": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.ShutdownTimeout = -time.Second
+			}),
+			expectedErr: errConfigNegativeShutdown,
+		},
+		"speculative_execution": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.SpeculativeExecution = SpeculativeExecution{MaxAttempts: 2, Delay: 100 * time.Millisecond}
+			}),
+		},
+		"negative_speculative_attempts": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.SpeculativeExecution.MaxAttempts = -1
+			}),
+			expectedErr: errConfigNegativeSpeculative,
+		},
+		"speculative_execution_without_delay": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.SpeculativeExecution.MaxAttempts = 1
+			}),
+			expectedErr: errConfigSpeculativeDelay,
+		},
+		"proto_version": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.ProtoVersion = 4
+			}),
+		},
+		"proto_version_too_low": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.ProtoVersion = 1
+			}),
+			expectedErr: errConfigInvalidProtoVersion,
+		},
+		"proto_version_too_high": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.ProtoVersion = 6
+			}),
+			expectedErr: errConfigInvalidProtoVersion,
+		},
+		"negative_timeout": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Timeout = -time.Second
+			}),
+			expectedErr: errConfigNegativeTimeout,
+		},
+		"empty_keyspace": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.Keyspace = ""
+			}),
+			expectedErr: errConfigEmptyKeyspace,
+		},
+		"empty_trace_table": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.TraceTable = ""
+			}),
+			expectedErr: errConfigEmptyTable,
+		},
+		"empty_logs_table": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.LogsTable = ""
+			}),
+			expectedErr: errConfigEmptyTable,
+		},
+		"empty_metrics_table": {
+			cfg: withDefaultConfig(func(config *Config) {
+				config.MetricsTable = ""
+			}),
+			expectedErr: errConfigEmptyTable,
+		},
+	}
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := test.cfg.Validate()
+			if test.expectedErr == nil {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, test.expectedErr)
+		})
+	}
+}
+
+func TestParseEndpoint(t *testing.T) {
+	tests := map[string]struct {
+		endpoint string
+		host     string
+		port     int
+		err      bool
+	}{
+		"ipv4":                 {endpoint: "10.0.0.1", host: "10.0.0.1", port: 9042},
+		"ipv4_port":            {endpoint: "10.0.0.1:9142", host: "10.0.0.1", port: 9142},
+		"hostname":             {endpoint: "cassandra-1", host: "cassandra-1", port: 9042},
+		"hostname_port":        {endpoint: "cassandra-1:9142", host: "cassandra-1", port: 9142},
+		"ipv6":                 {endpoint: "::1", host: "::1", port: 9042},
+		"ipv6_full":            {endpoint: "2001:db8::8a2e:370:7334", host: "2001:db8::8a2e:370:7334", port: 9042},
+		"ipv6_bracketed":       {endpoint: "[::1]", host: "::1", port: 9042},
+		"ipv6_bracketed_port":  {endpoint: "[::1]:9142", host: "::1", port: 9142},
+		"ipv6_unclosed":        {endpoint: "[::1", err: true},
+		"ipv6_bracketed_empty": {endpoint: "[]", err: true},
+		"ipv6_invalid_port":    {endpoint: "[::1]:cql", err: true},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			host, port, err := parseEndpoint(test.endpoint, 9042)
+			if test.err {
+				require.ErrorIs(t, err, errConfigInvalidEndpoint)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, test.host, host)
+			assert.Equal(t, test.port, port)
+		})
+	}
+}
+
+func TestSuffixTable(t *testing.T) {
+	assert.Equal(t, "otel_spans_events", suffixTable("otel_spans", eventsTableSuffix))
+	assert.Equal(t, `"Spans_events"`, suffixTable(`"Spans"`, eventsTableSuffix))
+	assert.Equal(t, "Telemetry", unquoteIdentifier(`"Telemetry"`))
+	assert.Equal(t, "otel", unquoteIdentifier("otel"))
+}
+
+func TestQuoteIdentifier(t *testing.T) {
+	for identifier, expected := range map[string]string{
+		"otel_logs":  "otel_logs",
+		"MyLogs":     `"MyLogs"`,
+		"otel_Logs":  `"otel_Logs"`,
+		"2024_logs":  `"2024_logs"`,
+		"_logs":      `"_logs"`,
+		`"MyLogs"`:   `"MyLogs"`,
+		`"otel"`:     `"otel"`,
+		"":           "",
+		"logs_2024":  "logs_2024",
+		"OTEL_SPANS": `"OTEL_SPANS"`,
+	} {
+		assert.Equal(t, expected, quoteIdentifier(identifier), identifier)
+	}
+}
+
+func TestForSignal(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.LogsTable = "MyLogs"
+		config.AttributesUDT = "Attributes"
+	})
+	logsCfg := cfg.forSignal("Telemetry", "")
+	assert.Equal(t, `"Telemetry"`, logsCfg.Keyspace)
+	assert.Equal(t, `"MyLogs"`, logsCfg.LogsTable)
+	assert.Equal(t, `"Attributes"`, logsCfg.AttributesUDT)
+	assert.Equal(t, "otel_spans", logsCfg.TraceTable)
+	// The configuration of the user is left as is.
+	assert.Equal(t, "otel", cfg.Keyspace)
+	assert.Equal(t, "MyLogs", cfg.LogsTable)
+
+	assert.Equal(t, "otel", cfg.forSignal("", "").Keyspace)
+
+	assert.Equal(t, "QUORUM", logsCfg.Consistency)
+	assert.Equal(t, "ONE", cfg.forSignal("", "ONE").Consistency)
+	assert.Equal(t, "QUORUM", cfg.Consistency)
+}
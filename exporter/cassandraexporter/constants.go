@@ -5,17 +5,65 @@ package cassandraexporter // import "github.com/open-telemetry/opentelemetry-col
 
 const (
 	// language=SQL
-	createDatabaseSQL = `CREATE KEYSPACE IF NOT EXISTS %s WITH REPLICATION = { 'class' : '%s', 'replication_factor' : %d };`
+	releaseVersionSQL = `SELECT release_version FROM system.local`
+	// language=SQL
+	localTopologySQL = `SELECT cluster_name, data_center FROM system.local`
+	// language=SQL
+	countPeersSQL = `SELECT count(*) FROM system.peers`
+	// language=SQL
+	createDatabaseSQL = `CREATE KEYSPACE IF NOT EXISTS %s WITH REPLICATION = { %s }%s;`
 	// language=SQL
 	createEventTypeSQL = `CREATE TYPE IF NOT EXISTS %s.Events (Timestamp Date, Name text, Attributes map<text, text>);`
 	// language=SQL
 	createLinksTypeSQL = `CREATE TYPE IF NOT EXISTS %s.Links (TraceId text, SpanId text, TraceState text, Attributes map<text, text>);`
 	// language=SQL
-	createSpanTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (TimeStamp DATE, TraceId text, SpanId text, ParentSpanId text, TraceState text, SpanName text, SpanKind text, ResourceAttributes map<text, text>, SpanAttributes map<text, text>, Duration int, StatusCode text, StatusMessage text, Events frozen<Events>, Links frozen<Links>, PRIMARY KEY (SpanId)) WITH COMPRESSION = {'class': '%s'}`
+	createSpanTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (TimeStamp DATE, TraceId text, SpanId text, ParentSpanId text, TraceState text, SpanName text, SpanKind text, ResourceAttributes %s, SpanAttributes %s, Duration bigint, StatusCode text, StatusMessage text, Events frozen<Events>, Links frozen<Links>, ScopeName text, ScopeVersion text, ResourceSchemaUrl text, DroppedAttributesCount int, ScopeDroppedAttributesCount int, ResourceDroppedAttributesCount int, ResourceId text, SpanFlags int, Sampled boolean, PRIMARY KEY (SpanId)) WITH %s`
+	// language=SQL
+	insertSpanSQL = `INSERT INTO %s.%s (timestamp, traceid, spanid, parentspanid, tracestate, spanname, spankind, resourceattributes, spanattributes, duration, statuscode, statusmessage, scopename, scopeversion, resourceschemaurl, droppedattributescount, scopedroppedattributescount, resourcedroppedattributescount, resourceid, spanflags, sampled) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	// language=SQL
+	createSpanEventsTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (TraceId text, SpanId text, EventIndex int, Timestamp TimeStamp, Name text, Attributes %s, PRIMARY KEY ((TraceId, SpanId), EventIndex)) WITH %s`
+	// language=SQL
+	insertSpanEventSQL = `INSERT INTO %s.%s (traceid, spanid, eventindex, timestamp, name, attributes) VALUES (?, ?, ?, ?, ?, ?)`
+	// language=SQL
+	createSpanLinksTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (TraceId text, SpanId text, LinkIndex int, LinkedTraceId text, LinkedSpanId text, TraceState text, Attributes %s, PRIMARY KEY ((TraceId, SpanId), LinkIndex)) WITH %s`
+	// language=SQL
+	insertSpanLinkSQL = `INSERT INTO %s.%s (traceid, spanid, linkindex, linkedtraceid, linkedspanid, tracestate, attributes) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	// language=SQL
+	createLogTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (TimeStamp %s, TraceId text, SpanId text, TraceFlags int, SeverityText text, SeverityNumber int, ServiceName text, Body %s, ResourceAttributes %s, LogAttributes %s, DateBucket TimeStamp, ScopeName text, ScopeVersion text, ResourceSchemaUrl text, DroppedAttributesCount int, ScopeDroppedAttributesCount int, ResourceDroppedAttributesCount int, BodyTruncated boolean, ResourceId text, Sampled boolean, RecordId text%s, PRIMARY KEY ((ServiceName, DateBucket%s), TimeStamp, SpanId, SeverityNumber, RecordId)) WITH CLUSTERING ORDER BY (TimeStamp %s) AND %s`
+	// language=SQL
+	insertLogTableSQL = `INSERT INTO %s.%s (timestamp, traceid, spanid, traceflags, severitytext, severitynumber, servicename, body, resourceattributes, logattributes, datebucket, scopename, scopeversion, resourceschemaurl, droppedattributescount, scopedroppedattributescount, resourcedroppedattributescount, bodytruncated, resourceid, sampled, recordid) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	// language=SQL
+	insertShardedLogTableSQL = `INSERT INTO %s.%s (timestamp, traceid, spanid, traceflags, severitytext, severitynumber, servicename, body, resourceattributes, logattributes, datebucket, scopename, scopeversion, resourceschemaurl, droppedattributescount, scopedroppedattributescount, resourcedroppedattributescount, bodytruncated, resourceid, sampled, recordid, shard) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	// language=SQL
+	createLogsByTraceTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (TraceId text, TimeStamp %s, ServiceName text, SpanId text, SeverityNumber int, RecordId text, DateBucket TimeStamp%s, PRIMARY KEY (TraceId, TimeStamp, ServiceName, SpanId, SeverityNumber, RecordId)) WITH %s`
+	// language=SQL
+	insertLogsByTraceSQL = `INSERT INTO %s.%s (traceid, timestamp, servicename, spanid, severitynumber, recordid, datebucket) VALUES (?, ?, ?, ?, ?, ?, ?)`
+	// language=SQL
+	createResourceTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (ResourceId text, ResourceAttributes %s, PRIMARY KEY (ResourceId)) WITH %s`
+	// language=SQL
+	insertResourceSQL = `INSERT INTO %s.%s (resourceid, resourceattributes) VALUES (?, ?)`
+	// language=SQL
+	createGaugeTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (ResourceAttributes %s, ScopeName text, ScopeVersion text, MetricName text, MetricDescription text, MetricUnit text, SeriesId text, Attributes %s, StartTimeUnix TimeStamp, TimeUnix TimeStamp, Value double, Flags int, ResourceSchemaUrl text, PRIMARY KEY ((MetricName, SeriesId), TimeUnix)) WITH %s`
+	// language=SQL
+	insertGaugeSQL = `INSERT INTO %s.%s (resourceattributes, scopename, scopeversion, metricname, metricdescription, metricunit, seriesid, attributes, starttimeunix, timeunix, value, flags, resourceschemaurl) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	// language=SQL
+	createSumTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (ResourceAttributes %s, ScopeName text, ScopeVersion text, MetricName text, MetricDescription text, MetricUnit text, SeriesId text, Attributes %s, StartTimeUnix TimeStamp, TimeUnix TimeStamp, Value double, Flags int, AggregationTemporality int, IsMonotonic boolean, ResourceSchemaUrl text, PRIMARY KEY ((MetricName, SeriesId), TimeUnix)) WITH %s`
+	// language=SQL
+	insertSumSQL = `INSERT INTO %s.%s (resourceattributes, scopename, scopeversion, metricname, metricdescription, metricunit, seriesid, attributes, starttimeunix, timeunix, value, flags, aggregationtemporality, ismonotonic, resourceschemaurl) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	// language=SQL
+	createHistogramTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (ResourceAttributes %s, ScopeName text, ScopeVersion text, MetricName text, MetricDescription text, MetricUnit text, SeriesId text, Attributes %s, StartTimeUnix TimeStamp, TimeUnix TimeStamp, Count bigint, Sum double, BucketCounts list<bigint>, ExplicitBounds list<double>, Min double, Max double, Flags int, AggregationTemporality int, ResourceSchemaUrl text, PRIMARY KEY ((MetricName, SeriesId), TimeUnix)) WITH %s`
+	// language=SQL
+	insertHistogramSQL = `INSERT INTO %s.%s (resourceattributes, scopename, scopeversion, metricname, metricdescription, metricunit, seriesid, attributes, starttimeunix, timeunix, count, sum, bucketcounts, explicitbounds, min, max, flags, aggregationtemporality, resourceschemaurl) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	// language=SQL
+	createSummaryTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (ResourceAttributes %s, ScopeName text, ScopeVersion text, MetricName text, MetricDescription text, MetricUnit text, SeriesId text, Attributes %s, StartTimeUnix TimeStamp, TimeUnix TimeStamp, Count bigint, Sum double, Quantiles list<double>, QuantileValues list<double>, Flags int, ResourceSchemaUrl text, PRIMARY KEY ((MetricName, SeriesId), TimeUnix)) WITH %s`
+	// language=SQL
+	insertSummarySQL = `INSERT INTO %s.%s (resourceattributes, scopename, scopeversion, metricname, metricdescription, metricunit, seriesid, attributes, starttimeunix, timeunix, count, sum, quantiles, quantilevalues, flags, resourceschemaurl) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	// language=SQL
+	createExemplarTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (MetricName text, SeriesId text, TimeUnix TimeStamp, ExemplarIndex int, ExemplarTimeUnix TimeStamp, Value double, TraceId text, SpanId text, FilteredAttributes %s, PRIMARY KEY ((MetricName, SeriesId), TimeUnix, ExemplarIndex)) WITH %s`
 	// language=SQL
-	insertSpanSQL = `INSERT INTO %s.%s (timestamp, traceid, spanid, parentspanid, tracestate, spanname, spankind, resourceattributes, spanattributes, duration, statuscode, statusmessage) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertExemplarSQL = `INSERT INTO %s.%s (metricname, seriesid, timeunix, exemplarindex, exemplartimeunix, value, traceid, spanid, filteredattributes) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`
 	// language=SQL
-	createLogTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (TimeStamp TimeStamp, TraceId text, SpanId text, TraceFlags int, SeverityText text, SeverityNumber int, Body text, ResourceAttributes map<text, text>, LogAttributes map<text, text>, PRIMARY KEY (SpanId, SeverityNumber)) WITH COMPRESSION = {'class': '%s'}`
+	createExponentialHistogramTableSQL = `CREATE TABLE IF NOT EXISTS %s.%s (ResourceAttributes %s, ScopeName text, ScopeVersion text, MetricName text, MetricDescription text, MetricUnit text, SeriesId text, Attributes %s, StartTimeUnix TimeStamp, TimeUnix TimeStamp, Count bigint, Sum double, Scale int, ZeroCount bigint, ZeroThreshold double, PositiveOffset int, PositiveBucketCounts list<bigint>, NegativeOffset int, NegativeBucketCounts list<bigint>, Min double, Max double, Flags int, AggregationTemporality int, ResourceSchemaUrl text, PRIMARY KEY ((MetricName, SeriesId), TimeUnix)) WITH %s`
 	// language=SQL
-	insertLogTableSQL = `INSERT INTO %s.%s (timestamp, traceid, spanid, traceflags, severitytext, severitynumber, body, resourceattributes, logattributes) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?)`
+	insertExponentialHistogramSQL = `INSERT INTO %s.%s (resourceattributes, scopename, scopeversion, metricname, metricdescription, metricunit, seriesid, attributes, starttimeunix, timeunix, count, sum, scale, zerocount, zerothreshold, positiveoffset, positivebucketcounts, negativeoffset, negativebucketcounts, min, max, flags, aggregationtemporality, resourceschemaurl) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`
 )
@@ -3,5 +3,5 @@
 
 //go:generate mdatagen metadata.yaml
 
-// Package cassandraexporter exports trace and log data to an Apache Cassandra instance.
+// Package cassandraexporter exports trace, metric and log data to an Apache Cassandra instance.
 package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
@@ -0,0 +1,78 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/consumer/consumererror"
+)
+
+var (
+	errExporterShutdown = errors.New("exporter is shut down")
+	errDrainTimeout     = errors.New("timed out waiting for in-flight inserts")
+)
+
+// inflightPushes tracks the pushes writing to the session, so that Shutdown
+// can wait for their batches before closing it. The zero value is ready to
+// use.
+type inflightPushes struct {
+	mu      sync.Mutex
+	closed  bool
+	running int
+	wg      sync.WaitGroup
+}
+
+// start registers a push, it fails once the exporter is shutting down. Every
+// successful start must be followed by a call to done.
+func (p *inflightPushes) start() error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.closed {
+		return consumererror.NewPermanent(errExporterShutdown)
+	}
+	p.running++
+	p.wg.Add(1)
+	return nil
+}
+
+func (p *inflightPushes) done() {
+	p.mu.Lock()
+	p.running--
+	p.mu.Unlock()
+	p.wg.Done()
+}
+
+// drain stops accepting pushes and waits for the running ones to finish, at
+// most for timeout when it is positive and until ctx is done. It returns the
+// number of pushes that were running.
+func (p *inflightPushes) drain(ctx context.Context, timeout time.Duration) (int, error) {
+	p.mu.Lock()
+	p.closed = true
+	running := p.running
+	p.mu.Unlock()
+
+	finished := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(finished)
+	}()
+	var expired <-chan time.Time
+	if timeout > 0 {
+		timer := time.NewTimer(timeout)
+		defer timer.Stop()
+		expired = timer.C
+	}
+	select {
+	case <-finished:
+		return running, nil
+	case <-expired:
+		return running, errDrainTimeout
+	case <-ctx.Done():
+		return running, ctx.Err()
+	}
+}
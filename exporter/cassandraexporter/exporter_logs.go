@@ -4,149 +4,700 @@
 package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
 
 import (
+	"bytes"
 	"context"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
-	"errors"
 	"fmt"
+	"hash/fnv"
+	"net"
+	"strconv"
+	"sync"
 	"time"
+	"unicode/utf8"
 
 	"github.com/gocql/gocql"
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pcommon"
 	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
 	"go.uber.org/zap"
 
 	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/coreinternal/traceutil"
 )
 
 type logsExporter struct {
-	client *gocql.Session
-	logger *zap.Logger
-	cfg    *Config
-}
-
-func newLogsExporter(logger *zap.Logger, cfg *Config) *logsExporter {
+	client            cqlSession
+	newSession        sessionFactory
+	borrowed          cqlSession
+	insertSQL         string
+	insertResourceSQL string
+	// insertLogsByTraceSQL writes the rows correlating a trace with its
+	// logs, empty unless index_logs_by_trace is set.
+	insertLogsByTraceSQL string
 
-	return &logsExporter{logger: logger, cfg: cfg}
+	pushes inflightPushes
+	// closing runs the shutdown once, the collector may call Shutdown again
+	// on its error paths.
+	closing sync.Once
+	// buffer coalesces the inserts of several pushes, nil unless
+	// coalescing is enabled.
+	buffer    *statementBuffer
+	telemetry *insertTelemetry
+	logger    *zap.Logger
+	cfg       *Config
 }
 
-func initializeLogKernel(cfg *Config) error {
-	ctx := context.Background()
-	cluster, err := newCluster(cfg)
+func newLogsExporter(set component.TelemetrySettings, cfg *Config) (*logsExporter, error) {
+	telemetry, err := newInsertTelemetry(set, signalLogs)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	cluster.Consistency = gocql.Quorum
-	cluster.Port = cfg.Port
-	cluster.Timeout = cfg.Timeout
+	cfg = cfg.forSignal(cfg.LogsKeyspace, cfg.LogsConsistency)
+	insertLogSQL := insertLogTableSQL
+	if cfg.ShardCount > 0 {
+		insertLogSQL = insertShardedLogTableSQL
+	}
+	if !cfg.StoreResourceAttributes {
+		insertLogSQL = withoutInsertColumn(insertLogSQL, "resourceattributes")
+	}
+	if !cfg.StoreLogAttributes {
+		insertLogSQL = withoutInsertColumn(insertLogSQL, "logattributes")
+	}
+	insertLogSQL = withInstanceIDInsert(cfg, insertLogSQL)
+	if cfg.StoreRawOTLP {
+		insertLogSQL = withInsertColumn(insertLogSQL, "rawotlp")
+	}
+	insertLogSQL = withAttributesTruncatedInsert(cfg, insertLogSQL)
+	insertSQL := parseInsertSQL(cfg, insertLogSQL, cfg.LogsTable)
+	if cfg.UseEventTimestamp {
+		insertSQL = withWriteTimestamp(cfg, insertSQL)
+	}
+	exp := &logsExporter{
+		insertSQL:         insertSQL,
+		insertResourceSQL: parseInsertSQL(cfg, insertResourceSQL, cfg.ResourcesTable),
+		logger:            set.Logger,
+		cfg:               cfg,
+		newSession:        createGocqlSession,
+		telemetry:         telemetry,
+	}
+	if cfg.IndexLogsByTrace {
+		insertByTraceSQL := insertLogsByTraceSQL
+		if cfg.ShardCount > 0 {
+			insertByTraceSQL = withInsertColumn(insertByTraceSQL, "shard")
+		}
+		exp.insertLogsByTraceSQL = parseInsertSQL(cfg, insertByTraceSQL, cfg.LogsByTraceTable)
+	}
+	return exp, nil
+}
 
-	session, err := cluster.CreateSession()
+// initializeLogKernel creates the keyspace and the logs table on the writer
+// session, so DDL and inserts share the same authentication, TLS and
+// consistency settings.
+func (e *logsExporter) initializeLogKernel(ctx context.Context, session cqlSession) error {
+	createLogTableSQL, err := parseCreateLogTableStatement(e.cfg)
 	if err != nil {
 		return err
 	}
-
-	defer session.Close()
-
-	createDatabaseError := session.Query(parseCreateDatabaseSQL(cfg)).WithContext(ctx).Exec()
-	if createDatabaseError != nil {
-		return createDatabaseError
+	stmts := append(keyspaceSchema(e.cfg), createLogTableSQL)
+	if e.cfg.DeduplicateResources {
+		stmts = append(stmts, parseCreateResourceTableSQL(e.cfg))
 	}
-	createLogTableError := session.Query(parseCreateLogTableSQL(cfg)).WithContext(ctx).Exec()
-	if createLogTableError != nil {
-		return createLogTableError
+	if e.cfg.IndexLogsByTrace {
+		stmts = append(stmts, parseCreateLogsByTraceTableSQL(e.cfg))
 	}
-
-	return nil
+	return execSchema(ctx, session, stmts...)
 }
 
-func newCluster(cfg *Config) (*gocql.ClusterConfig, error) {
-	cluster := gocql.NewCluster(cfg.DSN)
-	if cfg.Auth.UserName != "" && cfg.Auth.Password == "" {
-		return nil, errors.New("empty auth.password")
+func newCluster(ctx context.Context, cfg *Config) (*gocql.ClusterConfig, error) {
+	var cluster *gocql.ClusterConfig
+	var err error
+	if cfg.Astra != nil {
+		// The secure connect bundle carries the address, port, TLS and
+		// routing settings of the database.
+		cluster, err = newAstraCluster(ctx, cfg.Astra)
+	} else {
+		cluster, err = newEndpointsCluster(ctx, cfg)
+	}
+	if err != nil {
+		return nil, err
+	}
+	consistency, err := parseConsistency(cfg.Consistency)
+	if err != nil {
+		return nil, err
+	}
+	cluster.Consistency = consistency
+	serialConsistency, err := parseSerialConsistency(cfg.SerialConsistency)
+	if err != nil {
+		return nil, err
+	}
+	if serialConsistency != 0 {
+		cluster.SerialConsistency = serialConsistency
+	}
+	cluster.Timeout = cfg.TimeoutSettings.Timeout
+	cluster.NumConns = cfg.NumConns
+	if cfg.ConnectTimeout > 0 {
+		cluster.ConnectTimeout = cfg.ConnectTimeout
+	}
+	if cfg.SocketKeepalive > 0 {
+		cluster.SocketKeepalive = cfg.SocketKeepalive
+	}
+	// gocql only applies the connect timeout and keepalive to its own dialer.
+	if dialer, ok := cluster.HostDialer.(*astraHostDialer); ok {
+		dialer.connectTimeout = cluster.ConnectTimeout
+		if cfg.SocketKeepalive > 0 {
+			dialer.dialer.KeepAlive = cfg.SocketKeepalive
+		}
+	}
+	if cfg.ProtoVersion != 0 {
+		// Pinned for clusters and proxies that cannot negotiate the version.
+		cluster.ProtoVersion = cfg.ProtoVersion
 	}
-	if cfg.Auth.Password != "" && cfg.Auth.UserName == "" {
-		return nil, errors.New("empty auth.username")
+	cluster.ReconnectionPolicy = &gocql.ExponentialReconnectionPolicy{
+		MaxRetries:      cfg.Reconnection.MaxRetries,
+		InitialInterval: cfg.Reconnection.InitialInterval,
+		MaxInterval:     cfg.Reconnection.MaxInterval,
 	}
+	return cluster, nil
+}
+
+// newEndpointsCluster returns the cluster configuration reaching the
+// configured endpoints.
+func newEndpointsCluster(ctx context.Context, cfg *Config) (*gocql.ClusterConfig, error) {
+	contactPoints, err := cfg.contactPointAddresses()
+	if err != nil {
+		return nil, err
+	}
+	cluster := gocql.NewCluster(contactPoints...)
 	if cfg.Auth.UserName != "" && cfg.Auth.Password != "" {
 		cluster.Authenticator = gocql.PasswordAuthenticator{
 			Username: cfg.Auth.UserName,
 			Password: string(cfg.Auth.Password),
 		}
 	}
-	cluster.Consistency = gocql.Quorum
+	if cfg.Auth.SigV4 != nil {
+		authenticator, err := newSigV4Authenticator(ctx, cfg.Auth.SigV4.Region)
+		if err != nil {
+			return nil, err
+		}
+		cluster.Authenticator = authenticator
+	}
+	tlsConfig, err := cfg.TLS.LoadTLSConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("cassandra tls: %w", err)
+	}
+	if tlsConfig != nil {
+		// The tls.Config already carries the CA, client certificate and
+		// InsecureSkipVerify settings; gocql only needs it handed over.
+		cluster.SslOpts = &gocql.SslOptions{Config: tlsConfig}
+	}
 	cluster.Port = cfg.Port
 	return cluster, nil
 }
 
-func (e *logsExporter) Start(_ context.Context, _ component.Host) error {
-	cluster, err := newCluster(e.cfg)
+// newSessionCluster returns the cluster configuration of the session creating
+// the schema and writing the rows, which prefers the hosts of the local
+// datacenter when one is configured.
+func newSessionCluster(ctx context.Context, cfg *Config) (*gocql.ClusterConfig, error) {
+	cluster, err := newCluster(ctx, cfg)
 	if err != nil {
-		return err
+		return nil, err
+	}
+	// The keyspace may not exist before the schema is created, so the
+	// session is only bound to it when the keyspace is provisioned
+	// beforehand. Every statement names its keyspace either way.
+	if !cfg.createsKeyspace() {
+		cluster.Keyspace = unquoteIdentifier(cfg.Keyspace)
+	}
+	if cfg.LocalDC != "" {
+		policy := gocql.DCAwareRoundRobinPolicy(cfg.LocalDC)
+		if cfg.TokenAware {
+			policy = gocql.TokenAwareHostPolicy(policy)
+		}
+		cluster.PoolConfig.HostSelectionPolicy = policy
+	}
+	if filter, ok := newHostFilter(cfg.HostFilter); ok {
+		cluster.HostFilter = filter
+	}
+	return cluster, nil
+}
+
+// hostFilter accepts the nodes matching the host_filter options.
+type hostFilter struct {
+	dataCenter string
+	rack       string
+	networks   []*net.IPNet
+}
+
+// newHostFilter returns the filter of the host_filter options, it reports
+// false when none is set.
+func newHostFilter(filter HostFilter) (hostFilter, bool) {
+	f := hostFilter{dataCenter: filter.DataCenter, rack: filter.Rack}
+	for _, cidr := range filter.CIDRs {
+		// Checked by Validate.
+		if _, network, err := net.ParseCIDR(cidr); err == nil {
+			f.networks = append(f.networks, network)
+		}
+	}
+	return f, f.dataCenter != "" || f.rack != "" || len(f.networks) > 0
+}
+
+func (f hostFilter) Accept(host *gocql.HostInfo) bool {
+	return f.accept(host.DataCenter(), host.Rack(), host.ConnectAddress())
+}
+
+func (f hostFilter) accept(dataCenter, rack string, address net.IP) bool {
+	if f.dataCenter != "" && dataCenter != f.dataCenter {
+		return false
+	}
+	if f.rack != "" && rack != f.rack {
+		return false
 	}
-	cluster.Keyspace = e.cfg.Keyspace
-	cluster.Consistency = gocql.Quorum
-	cluster.Port = e.cfg.Port
-	cluster.Timeout = e.cfg.Timeout
+	if len(f.networks) == 0 {
+		return true
+	}
+	for _, network := range f.networks {
+		if network.Contains(address) {
+			return true
+		}
+	}
+	return false
+}
 
-	session, err := cluster.CreateSession()
+func (e *logsExporter) Start(ctx context.Context, _ component.Host) error {
+	session, err := startSession(ctx, e.cfg, e.logger, e.telemetry, e.newSession, e.borrowed, e.initializeLogKernel)
 	if err != nil {
 		return err
 	}
 	e.client = session
-	initializeErr := initializeLogKernel(e.cfg)
-	return initializeErr
+	if e.cfg.Coalescing.Enabled {
+		e.buffer = newStatementBuffer(e.cfg.BatchSize, e.cfg.Coalescing.FlushInterval, e.cfg.TimeoutSettings.Timeout, e.flushBuffered)
+	}
+	return nil
 }
 
-func (e *logsExporter) Shutdown(_ context.Context) error {
-	if e.client != nil {
-		e.client.Close()
+// flushBuffered writes the coalesced inserts. The pushes they come from have
+// already succeeded, so failures can only be logged.
+func (e *logsExporter) flushBuffered(ctx context.Context, stmts []statement) {
+	var errs insertErrors
+	executeBatches(ctx, e.client, e.cfg, stmts, &errs, e.telemetry, e.logger)
+	if err := errs.err(); err != nil {
+		e.logger.Error("failed to flush buffered logs", zap.Int("records", len(stmts)), zap.Error(err))
 	}
+}
 
-	return nil
+func (e *logsExporter) Shutdown(ctx context.Context) error {
+	// Let the running pushes write their batches before the session goes
+	// away, new pushes are rejected from now on.
+	drained, err := e.pushes.drain(ctx, e.cfg.ShutdownTimeout)
+	e.closing.Do(func() {
+		var flushed int
+		if e.buffer != nil {
+			flushed = e.buffer.shutdown(ctx)
+		}
+		if e.client != nil {
+			e.client.Close()
+		}
+		e.logger.Info("cassandra exporter shut down", zap.String("signal", signalLogs),
+			zap.Int("drained_pushes", drained), zap.Int("flushed_records", flushed))
+	})
+
+	return err
+}
+
+// parseCreateLogTableStatement returns the DDL of the logs table, rendered
+// from schema_template when one is configured.
+func parseCreateLogTableStatement(cfg *Config) (string, error) {
+	if cfg.SchemaTemplate != "" {
+		return renderSchemaTemplate(cfg.SchemaTemplate, cfg.Keyspace, cfg.LogsTable)
+	}
+	return parseCreateLogTableSQL(cfg), nil
 }
 
 func parseCreateLogTableSQL(cfg *Config) string {
-	return fmt.Sprintf(createLogTableSQL, cfg.Keyspace, cfg.LogsTable, cfg.Compression.Algorithm)
+	bodyType := "text"
+	if cfg.BodyEncoding == bodyEncodingBlob {
+		bodyType = "blob"
+	}
+	attrType := attributesColumnType(cfg)
+	var columns, shardKey string
+	if cfg.ShardCount > 0 {
+		columns, shardKey = ", Shard int", ", Shard"
+	}
+	if cfg.StoreRawOTLP {
+		columns += ", RawOtlp blob"
+	}
+	ddl := fmt.Sprintf(createLogTableSQL, cfg.Keyspace, cfg.LogsTable, logTimestampType(cfg), bodyType, attrType, attrType, columns, shardKey, cfg.ClusteringOrder, parseTableOptions(cfg))
+	if !cfg.StoreResourceAttributes {
+		ddl = withoutColumn(ddl, "ResourceAttributes "+attrType)
+	}
+	if !cfg.StoreLogAttributes {
+		ddl = withoutColumn(ddl, "LogAttributes "+attrType)
+	}
+	return withAttributesTruncatedColumn(cfg, withInstanceIDColumn(cfg, ddl))
+}
+
+// parseCreateLogsByTraceTableSQL creates the table listing the logs of each
+// trace. Its rows hold the primary key of the log rows, which are then read
+// from the logs table.
+func parseCreateLogsByTraceTableSQL(cfg *Config) string {
+	var shardColumn string
+	if cfg.ShardCount > 0 {
+		shardColumn = ", Shard int"
+	}
+	return fmt.Sprintf(createLogsByTraceTableSQL, cfg.Keyspace, cfg.LogsByTraceTable, logTimestampType(cfg), shardColumn, parseTableOptions(cfg))
+}
+
+// logTimestamp returns the time of the record, falling back to the time it
+// was observed at and then to the current time when a source sets neither.
+func logTimestamp(r plog.LogRecord) time.Time {
+	if ts := r.Timestamp(); ts != 0 {
+		return ts.AsTime()
+	}
+	if ts := r.ObservedTimestamp(); ts != 0 {
+		return ts.AsTime()
+	}
+	return time.Now()
+}
+
+// logTimestampType returns the CQL type of the TimeStamp column of the logs
+// tables, a bigint of nanoseconds since the epoch with the bigint_nanos
+// timestamp_format.
+func logTimestampType(cfg *Config) string {
+	if cfg.TimestampFormat == timestampFormatBigintNanos {
+		return "bigint"
+	}
+	return "TimeStamp"
+}
+
+// logTimestampValue returns the value bound to the TimeStamp column. Cassandra
+// timestamps only keep milliseconds, bigint_nanos keeps the full precision of
+// the record.
+func logTimestampValue(cfg *Config, timestamp time.Time) any {
+	if cfg.TimestampFormat == timestampFormatBigintNanos {
+		return timestamp.UnixNano()
+	}
+	return timestamp
+}
+
+// logSeverityText returns the severity text of the record, deriving the
+// canonical text of its severity number when a source only sets the number.
+func logSeverityText(r plog.LogRecord) string {
+	if text := r.SeverityText(); text != "" {
+		return text
+	}
+	switch n := r.SeverityNumber(); {
+	case n >= plog.SeverityNumberFatal:
+		return "FATAL"
+	case n >= plog.SeverityNumberError:
+		return "ERROR"
+	case n >= plog.SeverityNumberWarn:
+		return "WARN"
+	case n >= plog.SeverityNumberInfo:
+		return "INFO"
+	case n >= plog.SeverityNumberDebug:
+		return "DEBUG"
+	case n >= plog.SeverityNumberTrace:
+		return "TRACE"
+	default:
+		return ""
+	}
+}
+
+// encodeLogBody renders the body of a record for the body column. json keeps
+// the type of the body, text stores strings as they are and blob stores bytes
+// as they are, every other body falls back to its JSON form.
+func encodeLogBody(body pcommon.Value, encoding string, encoder *bodyEncoder) (any, error) {
+	switch encoding {
+	case bodyEncodingText:
+		if body.Type() != pcommon.ValueTypeMap && body.Type() != pcommon.ValueTypeSlice {
+			return body.AsString(), nil
+		}
+		bodyByte, err := encoder.marshal(body.AsRaw())
+		if err != nil {
+			return nil, err
+		}
+		return string(bodyByte), nil
+	case bodyEncodingBlob:
+		switch body.Type() {
+		case pcommon.ValueTypeBytes:
+			return body.Bytes().AsRaw(), nil
+		case pcommon.ValueTypeStr:
+			return []byte(body.Str()), nil
+		default:
+			bodyByte, err := encoder.marshal(body.AsRaw())
+			if err != nil {
+				return nil, err
+			}
+			return bytes.Clone(bodyByte), nil
+		}
+	default:
+		bodyByte, err := encoder.marshal(body.AsRaw())
+		if err != nil {
+			return nil, err
+		}
+		return string(bodyByte), nil
+	}
+}
+
+// bodyEncoder renders log bodies to JSON with the body_json options, reusing
+// its buffer across the records of a push. It is not safe for concurrent use.
+type bodyEncoder struct {
+	buf     bytes.Buffer
+	encoder *json.Encoder
+}
+
+func newBodyEncoder(options BodyJSON) *bodyEncoder {
+	e := &bodyEncoder{}
+	e.encoder = json.NewEncoder(&e.buf)
+	e.encoder.SetEscapeHTML(options.EscapeHTML)
+	e.encoder.SetIndent("", options.Indent)
+	return e
+}
+
+// marshal returns the JSON form of v, which is only valid until the next
+// call.
+func (e *bodyEncoder) marshal(v any) ([]byte, error) {
+	e.buf.Reset()
+	if err := e.encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	// Encode terminates every value with a newline.
+	return bytes.TrimSuffix(e.buf.Bytes(), []byte("\n")), nil
+}
+
+// truncateLogBody cuts an encoded body down to limit bytes and returns the
+// number of bytes dropped. Text is cut on a rune boundary so it stays valid
+// UTF-8.
+func truncateLogBody(body any, limit int) (any, int) {
+	switch b := body.(type) {
+	case string:
+		if len(b) <= limit {
+			return b, 0
+		}
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(b[cut]) {
+			cut--
+		}
+		return b[:cut], len(b) - cut
+	case []byte:
+		if len(b) <= limit {
+			return b, 0
+		}
+		return b[:limit], len(b) - limit
+	default:
+		return body, 0
+	}
+}
+
+// rawLogRecord marshals the record into an OTLP export request of the
+// record alone, together with its resource and scope, so the stored bytes
+// can be decoded without the other records of the push.
+func rawLogRecord(logs plog.ResourceLogs, scopeLogs plog.ScopeLogs, r plog.LogRecord) ([]byte, error) {
+	ld := plog.NewLogs()
+	rl := ld.ResourceLogs().AppendEmpty()
+	logs.Resource().CopyTo(rl.Resource())
+	rl.SetSchemaUrl(logs.SchemaUrl())
+	sl := rl.ScopeLogs().AppendEmpty()
+	scopeLogs.Scope().CopyTo(sl.Scope())
+	sl.SetSchemaUrl(scopeLogs.SchemaUrl())
+	r.CopyTo(sl.LogRecords().AppendEmpty())
+	return plogotlp.NewExportRequestFromLogs(ld).MarshalProto()
+}
+
+// logDateBucket returns the start of the time bucket a record at ts is
+// partitioned into, so a single service never grows an unbounded partition.
+func logDateBucket(ts time.Time, partitionBy string) time.Time {
+	ts = ts.UTC()
+	if partitionBy == partitionByHour {
+		return ts.Truncate(time.Hour)
+	}
+	return time.Date(ts.Year(), ts.Month(), ts.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+// logRecordID tells apart the records sharing the rest of the primary key of
+// the logs table, such as the records without a span logged in the same
+// millisecond at the same severity, by hashing their observed timestamp, body
+// and attributes. A replayed record hashes the same and still overwrites its
+// first write.
+func logRecordID(r plog.LogRecord) string {
+	h := fnv.New64a()
+	_, _ = h.Write(binary.BigEndian.AppendUint64(nil, uint64(r.ObservedTimestamp())))
+	_, _ = h.Write([]byte(r.Body().AsString()))
+	_, _ = h.Write([]byte{0})
+	hashAttributes(h, attributesToMap(r.Attributes()))
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// logShard returns the shard in [0, count) a record is written to, spreading
+// the partition of a busy service over count partitions. Records of a trace
+// share a shard, the others are spread by their timestamp. The shard of a
+// record carrying a trace id, a timestamp or an observed timestamp only
+// depends on the record, so replaying it overwrites its first write; the
+// others fall back to the time of the push and may land on another shard.
+func logShard(traceID string, ts time.Time, count int) int32 {
+	h := fnv.New32a()
+	if traceID != "" {
+		_, _ = h.Write([]byte(traceID))
+	} else {
+		_, _ = h.Write(binary.BigEndian.AppendUint64(nil, uint64(ts.UnixNano())))
+	}
+	return int32(h.Sum32() % uint32(count))
 }
 
 func (e *logsExporter) pushLogsData(ctx context.Context, ld plog.Logs) error {
+	if err := e.pushes.start(); err != nil {
+		return err
+	}
+	defer e.pushes.done()
+	if e.client == nil {
+		return errSessionNotOpen
+	}
+
 	start := time.Now()
 
+	var errs insertErrors
+	resources := newResourceRows(e.cfg, e.insertResourceSQL)
+	encoder := newBodyEncoder(e.cfg.BodyJSON)
 	for i := 0; i < ld.ResourceLogs().Len(); i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
 		logs := ld.ResourceLogs().At(i)
 		res := logs.Resource()
-		resAttr := attributesToMap(res.Attributes().AsRaw())
+		stmts, resID, resAttr := resources.columns(nil, res.Attributes())
+		serviceName := resourceServiceName(res)
 
 		for j := 0; j < logs.ScopeLogs().Len(); j++ {
+			scope := logs.ScopeLogs().At(j).Scope()
 			rs := logs.ScopeLogs().At(j).LogRecords()
 			for k := 0; k < rs.Len(); k++ {
 				r := rs.At(k)
-				logAttr := attributesToMap(r.Attributes().AsRaw())
-				bodyByte, err := json.Marshal(r.Body().AsRaw())
+				var logAttr any
+				var droppedAttributes int
+				if e.cfg.StoreLogAttributes {
+					var attributes pcommon.Map
+					attributes, droppedAttributes = limitAttributes(r.Attributes(), e.cfg.MaxAttributes)
+					if droppedAttributes > 0 {
+						e.telemetry.recordTruncatedAttributes(ctx, droppedAttributes)
+					}
+					logAttr = encodeAttributes(attributes, e.cfg)
+				}
+				body, err := encodeLogBody(r.Body(), e.cfg.BodyEncoding, encoder)
 				if err != nil {
-					return err
+					errs.add(consumererror.NewPermanent(fmt.Errorf("marshal log body: %w", err)))
+					e.telemetry.recordFailed(ctx, 1)
+					continue
+				}
+				var truncated int
+				if e.cfg.MaxBodySize > 0 {
+					body, truncated = truncateLogBody(body, e.cfg.MaxBodySize)
+					if truncated > 0 {
+						e.telemetry.recordTruncated(ctx, truncated)
+					}
 				}
 
-				insertLogError := e.client.Query(fmt.Sprintf(insertLogTableSQL, e.cfg.Keyspace, e.cfg.LogsTable),
-					r.Timestamp().AsTime(),
-					traceutil.TraceIDToHexOrEmptyString(r.TraceID()),
-					traceutil.SpanIDToHexOrEmptyString(r.SpanID()),
+				var raw []byte
+				if e.cfg.StoreRawOTLP {
+					raw, err = rawLogRecord(logs, logs.ScopeLogs().At(j), r)
+					if err != nil {
+						errs.add(consumererror.NewPermanent(fmt.Errorf("marshal raw log record: %w", err)))
+						e.telemetry.recordFailed(ctx, 1)
+						continue
+					}
+				}
+
+				timestamp := logTimestamp(r)
+				dateBucket := logDateBucket(timestamp, e.cfg.PartitionBy)
+				traceID := traceutil.TraceIDToHexOrEmptyString(r.TraceID())
+				spanID := traceutil.SpanIDToHexOrEmptyString(r.SpanID())
+				recordID := logRecordID(r)
+				partitionKey := serviceName + "/" + dateBucket.Format(time.RFC3339)
+				timestampColumn := logTimestampValue(e.cfg, timestamp)
+				args := []any{
+					timestampColumn,
+					traceID,
+					spanID,
 					uint32(r.Flags()),
-					r.SeverityText(),
+					logSeverityText(r),
 					int32(r.SeverityNumber()),
-					string(bodyByte),
-					resAttr,
-					logAttr,
-				).WithContext(ctx).Exec()
-
-				if insertLogError != nil {
-					e.logger.Error("insert log error", zap.Error(insertLogError))
+					serviceName,
+					body,
+				}
+				if e.cfg.StoreResourceAttributes {
+					args = append(args, resAttr)
+				}
+				if e.cfg.StoreLogAttributes {
+					args = append(args, logAttr)
+				}
+				args = append(args,
+					dateBucket,
+					scope.Name(),
+					scope.Version(),
+					logs.SchemaUrl(),
+					r.DroppedAttributesCount(),
+					scope.DroppedAttributesCount(),
+					res.DroppedAttributesCount(),
+					truncated > 0,
+					resID,
+					r.Flags().IsSampled(),
+					recordID,
+				)
+				var shard int32
+				if e.cfg.ShardCount > 0 {
+					shard = logShard(traceID, timestamp, e.cfg.ShardCount)
+					partitionKey += "/" + strconv.Itoa(int(shard))
+					args = append(args, shard)
+				}
+				args = appendInstanceID(e.cfg, args)
+				if e.cfg.StoreRawOTLP {
+					args = append(args, raw)
+				}
+				args = appendAttributesTruncated(e.cfg, args, droppedAttributes)
+				if e.cfg.UseEventTimestamp {
+					// A replayed record must not overwrite a newer write
+					// of the same row.
+					args = append(args, timestamp.UnixMicro())
+				}
+				stmts = append(stmts, statement{
+					partitionKey: partitionKey,
+					query:        e.insertSQL,
+					args:         args,
+					timestamp:    timestamp,
+					service:      serviceName,
+				})
+				if e.insertLogsByTraceSQL != "" && traceID != "" {
+					byTraceArgs := []any{traceID, timestampColumn, serviceName, spanID, int32(r.SeverityNumber()), recordID, dateBucket}
+					if e.cfg.ShardCount > 0 {
+						byTraceArgs = append(byTraceArgs, shard)
+					}
+					stmts = append(stmts, statement{
+						partitionKey: traceID,
+						query:        e.insertLogsByTraceSQL,
+						args:         byTraceArgs,
+						timestamp:    timestamp,
+						service:      serviceName,
+					})
 				}
 			}
 		}
+
+		if e.buffer != nil {
+			e.buffer.add(ctx, stmts)
+			continue
+		}
+		executeBatches(ctx, e.client, e.cfg, stmts, &errs, e.telemetry, e.logger)
 	}
 
 	duration := time.Since(start)
 	e.logger.Debug("insert logs", zap.Int("records", ld.LogRecordCount()),
 		zap.String("cost", duration.String()))
+	if err := errs.err(); err != nil {
+		return fmt.Errorf("insert logs: %w", err)
+	}
 	return nil
 }
@@ -4,59 +4,1515 @@
 package cassandraexporter
 
 import (
+	"context"
+	"crypto/tls"
 	"errors"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer/consumererror"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/plog"
+	"go.opentelemetry.io/collector/pdata/plog/plogotlp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/coreinternal/traceutil"
 )
 
 func TestNewCluster(t *testing.T) {
 	testCases := map[string]struct {
 		cfg                   *Config
 		expectedAuthenticator gocql.Authenticator
-		expectedErr           error
+		expectedSslOpts       *gocql.SslOptions
+		expectedErr           string
 	}{
 		"empty_auth": {
 			cfg:                   withDefaultConfig(),
 			expectedAuthenticator: nil,
 		},
-		"empty_username": {
+		"success_auth": {
 			cfg: withDefaultConfig(func(config *Config) {
+				config.Auth.UserName = "user"
 				config.Auth.Password = "pass"
 			}),
-			expectedAuthenticator: nil,
-			expectedErr:           errors.New("empty auth.username"),
+			expectedAuthenticator: gocql.PasswordAuthenticator{
+				Username: "user",
+				Password: "pass",
+			},
 		},
-		"empty_password": {
+		"tls_insecure_skip_verify": {
 			cfg: withDefaultConfig(func(config *Config) {
-				config.Auth.UserName = "user"
+				config.TLS.Insecure = false
+				config.TLS.InsecureSkipVerify = true
 			}),
-			expectedAuthenticator: nil,
-			expectedErr:           errors.New("empty auth.password"),
+			expectedSslOpts: &gocql.SslOptions{Config: &tls.Config{InsecureSkipVerify: true}},
 		},
-		"success_auth": {
+		"tls_missing_ca_file": {
 			cfg: withDefaultConfig(func(config *Config) {
-				config.Auth.UserName = "user"
-				config.Auth.Password = "pass"
+				config.TLS.CAFile = filepath.Join("testdata", "missing-ca.pem")
 			}),
-			expectedAuthenticator: gocql.PasswordAuthenticator{
-				Username: "user",
-				Password: "pass",
+			expectedErr: "failed to load TLS config",
+		},
+	}
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			c, err := newCluster(context.Background(), test.cfg)
+			if test.expectedErr != "" {
+				require.ErrorContains(t, err, test.expectedErr)
+				return
+			}
+			require.NoError(t, err)
+			require.Equal(t, test.expectedAuthenticator, c.Authenticator)
+			if test.expectedSslOpts == nil {
+				require.Nil(t, c.SslOpts)
+				return
+			}
+			require.NotNil(t, c.SslOpts)
+			require.Equal(t, test.expectedSslOpts.InsecureSkipVerify, c.SslOpts.InsecureSkipVerify)
+		})
+	}
+}
+
+func TestNewClusterConsistency(t *testing.T) {
+	c, err := newCluster(context.Background(), withDefaultConfig())
+	require.NoError(t, err)
+	require.Equal(t, gocql.Quorum, c.Consistency)
+
+	c, err = newCluster(context.Background(), withDefaultConfig(func(config *Config) {
+		config.Consistency = "LOCAL_ONE"
+	}))
+	require.NoError(t, err)
+	require.Equal(t, gocql.LocalOne, c.Consistency)
+}
+
+func TestNewClusterSerialConsistency(t *testing.T) {
+	c, err := newCluster(context.Background(), withDefaultConfig())
+	require.NoError(t, err)
+	require.Equal(t, gocql.NewCluster().SerialConsistency, c.SerialConsistency)
+
+	c, err = newCluster(context.Background(), withDefaultConfig(func(config *Config) {
+		config.SerialConsistency = "LOCAL_SERIAL"
+	}))
+	require.NoError(t, err)
+	require.Equal(t, gocql.LocalSerial, c.SerialConsistency)
+}
+
+func TestNewSessionClusterHostFilter(t *testing.T) {
+	c, err := newSessionCluster(context.Background(), withDefaultConfig())
+	require.NoError(t, err)
+	assert.Nil(t, c.HostFilter)
+
+	c, err = newSessionCluster(context.Background(), withDefaultConfig(func(config *Config) {
+		config.HostFilter = HostFilter{DataCenter: "dc1", CIDRs: []string{"10.1.0.0/16", "10.3.0.0/16"}}
+	}))
+	require.NoError(t, err)
+	filter, ok := c.HostFilter.(hostFilter)
+	require.True(t, ok)
+	assert.Equal(t, "dc1", filter.dataCenter)
+	assert.Empty(t, filter.rack)
+	require.Len(t, filter.networks, 2)
+	assert.Equal(t, "10.1.0.0/16", filter.networks[0].String())
+
+	assert.True(t, filter.accept("dc1", "rack1", net.ParseIP("10.1.2.3")))
+	assert.True(t, filter.accept("dc1", "rack2", net.ParseIP("10.3.0.1")))
+	assert.False(t, filter.accept("dc1", "rack1", net.ParseIP("10.2.0.1")))
+	assert.False(t, filter.accept("dc2", "rack1", net.ParseIP("10.1.2.3")))
+	// Only the address of a node can be set outside of gocql, its datacenter is empty.
+	assert.False(t, filter.Accept((&gocql.HostInfo{}).SetConnectAddress(net.ParseIP("10.1.2.3"))))
+
+	rackOnly, ok := newHostFilter(HostFilter{Rack: "rack1"})
+	require.True(t, ok)
+	assert.True(t, rackOnly.accept("dc2", "rack1", net.ParseIP("192.168.0.1")))
+	assert.False(t, rackOnly.accept("dc2", "rack2", net.ParseIP("192.168.0.1")))
+}
+
+func TestNewClusterContactPoints(t *testing.T) {
+	c, err := newCluster(context.Background(), withDefaultConfig())
+	require.NoError(t, err)
+	require.Equal(t, []string{defaultDSN}, c.Hosts)
+
+	c, err = newCluster(context.Background(), withDefaultConfig(func(config *Config) {
+		config.Endpoints = []string{"cassandra-1", "cassandra-2:9142"}
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"cassandra-1", "cassandra-2:9142"}, c.Hosts)
+
+	c, err = newCluster(context.Background(), withDefaultConfig(func(config *Config) {
+		config.Endpoints = []string{"::1", "[fd00::2]", "[fd00::3]:9142", "10.0.0.4:9042"}
+	}))
+	require.NoError(t, err)
+	require.Equal(t, []string{"::1", "fd00::2", "[fd00::3]:9142", "10.0.0.4:9042"}, c.Hosts)
+}
+
+func TestLogsExporterStart(t *testing.T) {
+	t.Run("create_schema", func(t *testing.T) {
+		sessions := &fakeSessionFactory{}
+		exp, err := newLogsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig())
+		require.NoError(t, err)
+		exp.newSession = sessions.newSession
+		require.NoError(t, exp.Start(context.Background(), componenttest.NewNopHost()))
+
+		require.Len(t, sessions.sessions, 1)
+		session := sessions.sessions[0]
+		require.Len(t, session.queries, 2)
+		assert.Contains(t, session.queries[0].stmt, "CREATE KEYSPACE")
+		assert.Contains(t, session.queries[1].stmt, "CREATE TABLE")
+		assert.False(t, session.closed)
+		assert.Empty(t, sessions.clusters[0].Keyspace)
+		assert.Same(t, session, exp.client)
+	})
+
+	t.Run("create_schema_deduplicate_resources", func(t *testing.T) {
+		sessions := &fakeSessionFactory{}
+		exp, err := newLogsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig(func(config *Config) {
+			config.DeduplicateResources = true
+		}))
+		require.NoError(t, err)
+		exp.newSession = sessions.newSession
+		require.NoError(t, exp.Start(context.Background(), componenttest.NewNopHost()))
+
+		queries := sessions.sessions[0].queries
+		require.Len(t, queries, 3)
+		assert.Contains(t, queries[2].stmt, "CREATE TABLE IF NOT EXISTS otel.otel_resources")
+	})
+
+	t.Run("skip_schema", func(t *testing.T) {
+		sessions := &fakeSessionFactory{}
+		exp, err := newLogsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig(func(config *Config) {
+			config.CreateSchema = false
+		}))
+		require.NoError(t, err)
+		exp.newSession = sessions.newSession
+		require.NoError(t, exp.Start(context.Background(), componenttest.NewNopHost()))
+
+		require.Len(t, sessions.sessions, 1)
+		assert.Empty(t, sessions.sessions[0].queries)
+		assert.Equal(t, "otel", sessions.clusters[0].Keyspace)
+		assert.Same(t, sessions.sessions[0], exp.client)
+	})
+
+	t.Run("health_check_failed", func(t *testing.T) {
+		sessions := &fakeSessionFactory{scan: func(string, ...any) error {
+			return gocql.ErrNoConnections
+		}}
+		exp, err := newLogsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig(func(config *Config) {
+			config.CreateSchema = false
+		}))
+		require.NoError(t, err)
+		exp.newSession = sessions.newSession
+		err = exp.Start(context.Background(), componenttest.NewNopHost())
+		require.ErrorIs(t, err, gocql.ErrNoConnections)
+		require.ErrorContains(t, err, "cassandra health check failed")
+		require.Len(t, sessions.sessions, 1)
+		assert.True(t, sessions.sessions[0].closed)
+		assert.Nil(t, exp.client)
+	})
+
+	t.Run("create_schema_failed", func(t *testing.T) {
+		sessions := &fakeSessionFactory{}
+		exp, err := newLogsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig())
+		require.NoError(t, err)
+		exp.newSession = func(cluster *gocql.ClusterConfig) (cqlSession, error) {
+			session, err := sessions.newSession(cluster)
+			session.(*fakeSession).fail = func([]fakeStatement) error { return errors.New("unauthorized") }
+			return session, err
+		}
+		require.ErrorContains(t, exp.Start(context.Background(), componenttest.NewNopHost()), "unauthorized")
+		require.Len(t, sessions.sessions, 1)
+		assert.True(t, sessions.sessions[0].closed)
+		assert.Nil(t, exp.client)
+	})
+
+	t.Run("cassandra_not_ready", func(t *testing.T) {
+		sessions := &fakeSessionFactory{failures: 2}
+		exp, err := newLogsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig(func(config *Config) {
+			config.Reconnection = Reconnection{InitialInterval: time.Millisecond, MaxInterval: time.Millisecond, MaxRetries: 2}
+		}))
+		require.NoError(t, err)
+		exp.newSession = sessions.newSession
+		require.NoError(t, exp.Start(context.Background(), componenttest.NewNopHost()))
+
+		assert.Equal(t, 3, sessions.attempts)
+		require.Len(t, sessions.sessions, 1)
+		assert.Same(t, sessions.sessions[0], exp.client)
+	})
+}
+
+func TestSignalKeyspaces(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.LogsKeyspace = "logs"
+		config.MetricsKeyspace = "metrics"
+	})
+	set := componenttest.NewNopTelemetrySettings()
+
+	logs, err := newLogsExporter(set, cfg)
+	require.NoError(t, err)
+	assert.Contains(t, logs.insertSQL, "INSERT INTO logs.otel_logs ")
+	traces, err := newTracesExporter(set, cfg)
+	require.NoError(t, err)
+	assert.Contains(t, traces.insertSQL, "INSERT INTO otel.otel_spans ")
+	metrics, err := newMetricsExporter(set, cfg)
+	require.NoError(t, err)
+	assert.Contains(t, metrics.insertGaugeSQL, "INSERT INTO metrics.otel_metrics_gauge ")
+	assert.Equal(t, "otel", cfg.Keyspace)
+
+	sessions := &fakeSessionFactory{}
+	logs.newSession = sessions.newSession
+	require.NoError(t, logs.Start(context.Background(), componenttest.NewNopHost()))
+	queries := sessions.sessions[0].queries
+	require.Len(t, queries, 2)
+	assert.Contains(t, queries[0].stmt, "CREATE KEYSPACE IF NOT EXISTS logs ")
+	assert.Contains(t, queries[1].stmt, "CREATE TABLE IF NOT EXISTS logs.otel_logs ")
+
+	cfg.CreateSchema = false
+	sessions = &fakeSessionFactory{}
+	traces, err = newTracesExporter(set, cfg)
+	require.NoError(t, err)
+	traces.newSession = sessions.newSession
+	require.NoError(t, traces.Start(context.Background(), componenttest.NewNopHost()))
+	assert.Equal(t, "otel", sessions.clusters[0].Keyspace)
+	metrics, err = newMetricsExporter(set, cfg)
+	require.NoError(t, err)
+	metrics.newSession = sessions.newSession
+	require.NoError(t, metrics.Start(context.Background(), componenttest.NewNopHost()))
+	assert.Equal(t, "metrics", sessions.clusters[1].Keyspace)
+}
+
+func TestSignalConsistency(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.Consistency = "LOCAL_QUORUM"
+		config.LogsConsistency = "ONE"
+		config.MetricsConsistency = "local_one"
+	})
+	set := componenttest.NewNopTelemetrySettings()
+	sessions := &fakeSessionFactory{}
+
+	logs, err := newLogsExporter(set, cfg)
+	require.NoError(t, err)
+	logs.newSession = sessions.newSession
+	require.NoError(t, logs.Start(context.Background(), componenttest.NewNopHost()))
+	traces, err := newTracesExporter(set, cfg)
+	require.NoError(t, err)
+	traces.newSession = sessions.newSession
+	require.NoError(t, traces.Start(context.Background(), componenttest.NewNopHost()))
+	metrics, err := newMetricsExporter(set, cfg)
+	require.NoError(t, err)
+	metrics.newSession = sessions.newSession
+	require.NoError(t, metrics.Start(context.Background(), componenttest.NewNopHost()))
+
+	require.Len(t, sessions.clusters, 3)
+	assert.Equal(t, gocql.One, sessions.clusters[0].Consistency)
+	assert.Equal(t, gocql.LocalQuorum, sessions.clusters[1].Consistency)
+	assert.Equal(t, gocql.LocalOne, sessions.clusters[2].Consistency)
+}
+
+func TestSchemaCreation(t *testing.T) {
+	tests := map[string]struct {
+		createSchema   bool
+		createKeyspace bool
+		ddl            []string
+		keyspace       string
+	}{
+		"keyspace_and_tables": {
+			createSchema:   true,
+			createKeyspace: true,
+			ddl:            []string{"CREATE KEYSPACE IF NOT EXISTS otel ", "CREATE TABLE IF NOT EXISTS otel.otel_logs "},
+		},
+		"tables_only": {
+			createSchema: true,
+			ddl:          []string{"CREATE TABLE IF NOT EXISTS otel.otel_logs "},
+			keyspace:     "otel",
+		},
+		"none": {
+			keyspace: "otel",
+		},
+		"none_create_keyspace_ignored": {
+			createKeyspace: true,
+			keyspace:       "otel",
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			sessions := &fakeSessionFactory{}
+			exp, err := newLogsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig(func(config *Config) {
+				config.CreateSchema = test.createSchema
+				config.CreateKeyspace = test.createKeyspace
+			}))
+			require.NoError(t, err)
+			exp.newSession = sessions.newSession
+			require.NoError(t, exp.Start(context.Background(), componenttest.NewNopHost()))
+
+			queries := sessions.sessions[0].queries
+			require.Len(t, queries, len(test.ddl))
+			for i, ddl := range test.ddl {
+				assert.True(t, strings.HasPrefix(queries[i].stmt, ddl), queries[i].stmt)
+			}
+			assert.Equal(t, test.keyspace, sessions.clusters[0].Keyspace)
+		})
+	}
+}
+
+func TestSchemaCreationTablesOnly(t *testing.T) {
+	set := componenttest.NewNopTelemetrySettings()
+	cfg := withDefaultConfig(func(config *Config) {
+		config.CreateKeyspace = false
+	})
+
+	sessions := &fakeSessionFactory{}
+	traces, err := newTracesExporter(set, cfg)
+	require.NoError(t, err)
+	traces.newSession = sessions.newSession
+	require.NoError(t, traces.Start(context.Background(), componenttest.NewNopHost()))
+	metrics, err := newMetricsExporter(set, cfg)
+	require.NoError(t, err)
+	metrics.newSession = sessions.newSession
+	require.NoError(t, metrics.Start(context.Background(), componenttest.NewNopHost()))
+
+	for _, session := range sessions.sessions {
+		require.NotEmpty(t, session.queries)
+		for _, query := range session.queries {
+			assert.NotContains(t, query.stmt, "CREATE KEYSPACE")
+		}
+	}
+	assert.Len(t, sessions.sessions[0].queries, 5)
+	assert.Len(t, sessions.sessions[1].queries, 6)
+}
+
+func TestNewSessionCluster(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.Keyspace = "telemetry"
+	})
+
+	writer, err := newSessionCluster(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Empty(t, writer.Keyspace)
+	require.Equal(t, cfg.Port, writer.Port)
+	require.Equal(t, cfg.TimeoutSettings.Timeout, writer.Timeout)
+	require.Equal(t, cfg.ConnectTimeout, writer.ConnectTimeout)
+	require.Equal(t, cfg.NumConns, writer.NumConns)
+	require.Nil(t, writer.PoolConfig.HostSelectionPolicy)
+	require.Zero(t, writer.ProtoVersion)
+	require.Zero(t, writer.SocketKeepalive)
+
+	cfg.CreateSchema = false
+	writer, err = newSessionCluster(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Equal(t, cfg.Keyspace, writer.Keyspace)
+
+	cfg.ProtoVersion = 4
+	for _, newClusterFunc := range []func(context.Context, *Config) (*gocql.ClusterConfig, error){newCluster, newSessionCluster} {
+		cluster, err := newClusterFunc(context.Background(), cfg)
+		require.NoError(t, err)
+		require.Equal(t, 4, cluster.ProtoVersion)
+	}
+}
+
+func TestNewSessionClusterSocketKeepalive(t *testing.T) {
+	cluster, err := newSessionCluster(context.Background(), withDefaultConfig(func(config *Config) {
+		config.SocketKeepalive = 30 * time.Second
+	}))
+	require.NoError(t, err)
+	assert.Equal(t, 30*time.Second, cluster.SocketKeepalive)
+}
+
+func TestNewSessionClusterHostSelectionPolicy(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.LocalDC = "dc1"
+	})
+	writer, err := newSessionCluster(context.Background(), cfg)
+	require.NoError(t, err)
+	require.IsType(t, gocql.DCAwareRoundRobinPolicy(""), writer.PoolConfig.HostSelectionPolicy)
+
+	cfg.TokenAware = true
+	writer, err = newSessionCluster(context.Background(), cfg)
+	require.NoError(t, err)
+	require.IsType(t, gocql.TokenAwareHostPolicy(gocql.RoundRobinHostPolicy()), writer.PoolConfig.HostSelectionPolicy)
+
+	bootstrap, err := newCluster(context.Background(), cfg)
+	require.NoError(t, err)
+	require.Nil(t, bootstrap.PoolConfig.HostSelectionPolicy)
+}
+
+func TestPushLogsDataErrors(t *testing.T) {
+	testCases := map[string]struct {
+		fail             func(stmts []fakeStatement) error
+		expectedInserted int
+		expectedErr      string
+		permanent        bool
+	}{
+		"all_succeed": {
+			expectedInserted: 3,
+		},
+		"all_fail": {
+			fail: func([]fakeStatement) error {
+				return gocql.ErrNoConnections
+			},
+			expectedErr: "3 inserts failed",
+		},
+		"partial_fail": {
+			fail: func(stmts []fakeStatement) error {
+				if stmts[0].values[4] == "WARN" {
+					return errors.New("write timeout")
+				}
+				return nil
+			},
+			expectedInserted: 2,
+			expectedErr:      "1 inserts failed",
+		},
+		"permanent_fail": {
+			fail: func([]fakeStatement) error {
+				return fakeRequestError{code: gocql.ErrCodeInvalid}
 			},
+			expectedErr: "3 inserts failed",
+			permanent:   true,
 		},
 	}
 	for name, test := range testCases {
 		t.Run(name, func(t *testing.T) {
-			c, err := newCluster(test.cfg)
-			if err == nil {
-				require.Equal(t, test.expectedAuthenticator, c.Authenticator)
+			session := &fakeSession{fail: test.fail}
+			exp := newTestLogsExporter(t, session, func(config *Config) {
+				config.BatchSize = 1
+			})
+
+			err := exp.pushLogsData(context.Background(), simpleLogs("INFO", "WARN", "ERROR"))
+			assert.Len(t, session.statements(), test.expectedInserted)
+			if test.expectedErr == "" {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorContains(t, err, test.expectedErr)
+			assert.Equal(t, test.permanent, consumererror.IsPermanent(err))
+		})
+	}
+}
+
+func TestPushLogsDataServiceName(t *testing.T) {
+	logs := plog.NewLogs()
+	first := logs.ResourceLogs().AppendEmpty()
+	first.Resource().Attributes().PutStr("service.name", "checkout")
+	first.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("first")
+	second := logs.ResourceLogs().AppendEmpty()
+	second.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("second")
+
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session)
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 2)
+	assert.Equal(t, "checkout", stmts[0].values[6])
+	assert.Equal(t, "", stmts[1].values[6])
+}
+
+func TestPushLogsDataCoalescing(t *testing.T) {
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session, func(config *Config) {
+		config.BatchSize = 4
+		config.Coalescing = Coalescing{Enabled: true, FlushInterval: time.Hour}
+	})
+	exp.buffer = newStatementBuffer(exp.cfg.BatchSize, exp.cfg.Coalescing.FlushInterval, 0, exp.flushBuffered)
+
+	require.NoError(t, exp.pushLogsData(context.Background(), simpleLogs("INFO")))
+	require.NoError(t, exp.pushLogsData(context.Background(), simpleLogs("WARN", "ERROR")))
+	assert.Empty(t, session.statements())
+	require.NoError(t, exp.pushLogsData(context.Background(), simpleLogs("DEBUG")))
+	assert.Len(t, session.statements(), 4)
+	require.Len(t, session.batches, 1)
+
+	require.NoError(t, exp.pushLogsData(context.Background(), simpleLogs("INFO")))
+	require.NoError(t, exp.Shutdown(context.Background()))
+	assert.Len(t, session.statements(), 5)
+	assert.True(t, session.closed)
+}
+
+func TestPushLogsDataDeduplicateResources(t *testing.T) {
+	logs := plog.NewLogs()
+	for _, pod := range []string{"checkout-0", "checkout-0", "checkout-1"} {
+		rl := logs.ResourceLogs().AppendEmpty()
+		rl.Resource().Attributes().PutStr("service.name", "checkout")
+		rl.Resource().Attributes().PutStr("k8s.pod.name", pod)
+		records := rl.ScopeLogs().AppendEmpty().LogRecords()
+		records.AppendEmpty().Body().SetStr("first")
+		records.AppendEmpty().Body().SetStr("second")
+	}
+
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session, func(config *Config) {
+		config.DeduplicateResources = true
+	})
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	var resources, records []fakeStatement
+	for _, stmt := range session.statements() {
+		if strings.Contains(stmt.stmt, "otel_resources") {
+			resources = append(resources, stmt)
+		} else {
+			records = append(records, stmt)
+		}
+	}
+	require.Len(t, resources, 2)
+	require.Len(t, records, 6)
+	assert.Equal(t, map[string]string{"service.name": "checkout", "k8s.pod.name": "checkout-0"}, resources[0].values[1])
+	assert.Equal(t, map[string]string{"service.name": "checkout", "k8s.pod.name": "checkout-1"}, resources[1].values[1])
+	for i, record := range records {
+		assert.Nil(t, record.values[8])
+		assert.Equal(t, resources[i/4].values[0], record.values[18])
+	}
+}
+
+func TestPushLogsDataDateBucket(t *testing.T) {
+	timestamp := time.Date(2024, 9, 1, 13, 45, 10, 0, time.UTC)
+	logs := simpleLogs("INFO")
+	logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+
+	for partitionBy, expected := range map[string]time.Time{
+		partitionByHour: time.Date(2024, 9, 1, 13, 0, 0, 0, time.UTC),
+		partitionByDay:  time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC),
+	} {
+		t.Run(partitionBy, func(t *testing.T) {
+			session := &fakeSession{}
+			exp := newTestLogsExporter(t, session, func(config *Config) {
+				config.PartitionBy = partitionBy
+			})
+			require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+			stmts := session.statements()
+			require.Len(t, stmts, 1)
+			assert.Equal(t, expected, stmts[0].values[10])
+		})
+	}
+}
+
+func TestEncodeLogBody(t *testing.T) {
+	str := pcommon.NewValueStr("user logged in")
+	m := pcommon.NewValueMap()
+	m.Map().PutStr("user", "alice")
+	m.Map().PutInt("attempt", 2)
+	b := pcommon.NewValueBytes()
+	b.Bytes().FromRaw([]byte{0xca, 0xfe})
+
+	tests := map[string]struct {
+		body     pcommon.Value
+		expected map[string]any
+	}{
+		"string": {
+			body: str,
+			expected: map[string]any{
+				bodyEncodingJSON: `"user logged in"`,
+				bodyEncodingText: "user logged in",
+				bodyEncodingBlob: []byte("user logged in"),
+			},
+		},
+		"map": {
+			body: m,
+			expected: map[string]any{
+				bodyEncodingJSON: `{"attempt":2,"user":"alice"}`,
+				bodyEncodingText: `{"attempt":2,"user":"alice"}`,
+				bodyEncodingBlob: []byte(`{"attempt":2,"user":"alice"}`),
+			},
+		},
+		"bytes": {
+			body: b,
+			expected: map[string]any{
+				bodyEncodingJSON: `"yv4="`,
+				bodyEncodingText: "yv4=",
+				bodyEncodingBlob: []byte{0xca, 0xfe},
+			},
+		},
+	}
+	for name, test := range tests {
+		for encoding, expected := range test.expected {
+			t.Run(name+"_"+encoding, func(t *testing.T) {
+				got, err := encodeLogBody(test.body, encoding, newBodyEncoder(BodyJSON{}))
+				require.NoError(t, err)
+				assert.Equal(t, expected, got)
+			})
+		}
+	}
+}
+
+func TestEncodeLogBodyJSONOptions(t *testing.T) {
+	body := pcommon.NewValueMap()
+	body.Map().PutStr("html", "<b>a & b</b>")
+
+	got, err := encodeLogBody(body, bodyEncodingJSON, newBodyEncoder(BodyJSON{}))
+	require.NoError(t, err)
+	assert.Equal(t, `{"html":"<b>a & b</b>"}`, got)
+
+	got, err = encodeLogBody(body, bodyEncodingJSON, newBodyEncoder(BodyJSON{EscapeHTML: true}))
+	require.NoError(t, err)
+	assert.Equal(t, `{"html":"\u003cb\u003ea \u0026 b\u003c/b\u003e"}`, got)
+
+	got, err = encodeLogBody(body, bodyEncodingText, newBodyEncoder(BodyJSON{Indent: "  "}))
+	require.NoError(t, err)
+	assert.Equal(t, "{\n  \"html\": \"<b>a & b</b>\"\n}", got)
+}
+
+func TestPushLogsDataBodyEncoderReuse(t *testing.T) {
+	logs := simpleLogs("INFO", "WARN")
+	records := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	records.At(0).Body().SetEmptyMap().PutStr("msg", "first <")
+	records.At(1).Body().SetEmptySlice().AppendEmpty().SetStr("second >")
+
+	for _, encoding := range []string{bodyEncodingJSON, bodyEncodingBlob} {
+		session := &fakeSession{}
+		exp := newTestLogsExporter(t, session, func(config *Config) {
+			config.BodyEncoding = encoding
+		})
+		require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+		bodies := map[any]string{}
+		for _, stmt := range session.statements() {
+			switch body := stmt.values[7].(type) {
+			case string:
+				bodies[stmt.values[4]] = body
+			case []byte:
+				bodies[stmt.values[4]] = string(body)
+			}
+		}
+		assert.Equal(t, map[any]string{
+			"INFO": `{"msg":"first <"}`,
+			"WARN": `["second >"]`,
+		}, bodies, encoding)
+	}
+}
+
+func TestPushLogsDataAttributesFormat(t *testing.T) {
+	logs := simpleLogs("INFO")
+	logs.ResourceLogs().At(0).Resource().Attributes().PutStr("service.name", "checkout")
+	attrs := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords().At(0).Attributes()
+	attrs.PutInt("http.status_code", 200)
+	attrs.PutBool("error", true)
+	attrs.PutEmptyMap("user").PutStr("id", "42")
+
+	tests := map[string]struct {
+		resource any
+		record   any
+	}{
+		attributesFormatMap: {
+			resource: map[string]string{"service.name": "checkout"},
+			record:   map[string]string{"http.status_code": "200", "error": "true", "user.id": "42"},
+		},
+		attributesFormatJSON: {
+			resource: `{"service.name":"checkout"}`,
+			record:   `{"error":true,"http.status_code":200,"user":{"id":"42"}}`,
+		},
+	}
+	for format, expected := range tests {
+		t.Run(format, func(t *testing.T) {
+			session := &fakeSession{}
+			exp := newTestLogsExporter(t, session, func(config *Config) {
+				config.AttributesFormat = format
+			})
+			require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+			stmts := session.statements()
+			require.Len(t, stmts, 1)
+			assert.Equal(t, expected.resource, stmts[0].values[8])
+			assert.Equal(t, expected.record, stmts[0].values[9])
+		})
+	}
+}
+
+func TestParseCreateLogTableSQLAttributesFormat(t *testing.T) {
+	ddl := parseCreateLogTableSQL(withDefaultConfig(func(config *Config) {
+		config.AttributesFormat = attributesFormatJSON
+	}))
+	assert.Contains(t, ddl, "ResourceAttributes text, LogAttributes text,")
+	assert.NotContains(t, ddl, "map<")
+}
+
+func TestParseCreateLogTableSQLClusteringOrder(t *testing.T) {
+	assert.Contains(t, parseCreateLogTableSQL(withDefaultConfig()),
+		"PRIMARY KEY ((ServiceName, DateBucket), TimeStamp, SpanId, SeverityNumber, RecordId)) WITH CLUSTERING ORDER BY (TimeStamp ASC) AND COMPRESSION")
+	assert.Contains(t, parseCreateLogTableSQL(withDefaultConfig(func(config *Config) {
+		config.ClusteringOrder = clusteringOrderDesc
+	})), "WITH CLUSTERING ORDER BY (TimeStamp DESC) AND COMPRESSION")
+}
+
+func TestParseCreateLogTableSQLBodyEncoding(t *testing.T) {
+	assert.Contains(t, parseCreateLogTableSQL(withDefaultConfig()), ", Body text, ")
+	assert.Contains(t, parseCreateLogTableSQL(withDefaultConfig(func(config *Config) {
+		config.BodyEncoding = bodyEncodingBlob
+	})), ", Body blob, ")
+}
+
+func TestParseCreateLogTableSQLShardCount(t *testing.T) {
+	ddl := parseCreateLogTableSQL(withDefaultConfig(func(config *Config) {
+		config.ShardCount = 8
+	}))
+	assert.Contains(t, ddl, ", Sampled boolean, RecordId text, Shard int, PRIMARY KEY ((ServiceName, DateBucket, Shard), TimeStamp, SpanId, SeverityNumber, RecordId))")
+}
+
+func TestParseCreateLogTableSQLStoreRawOTLP(t *testing.T) {
+	ddl := parseCreateLogTableSQL(withDefaultConfig(func(config *Config) {
+		config.StoreRawOTLP = true
+	}))
+	assert.Contains(t, ddl, ", Sampled boolean, RecordId text, RawOtlp blob, PRIMARY KEY ((ServiceName, DateBucket), TimeStamp, SpanId, SeverityNumber, RecordId))")
+}
+
+func TestParseCreateLogTableSQLTimestampFormat(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.TimestampFormat = timestampFormatBigintNanos
+	})
+	assert.True(t, strings.HasPrefix(parseCreateLogTableSQL(cfg), "CREATE TABLE IF NOT EXISTS otel.otel_logs (TimeStamp bigint, TraceId text,"))
+	assert.True(t, strings.HasPrefix(parseCreateLogsByTraceTableSQL(cfg), "CREATE TABLE IF NOT EXISTS otel.otel_logs_by_trace (TraceId text, TimeStamp bigint, ServiceName text,"))
+}
+
+func TestPushLogsDataStoreAttributes(t *testing.T) {
+	testCases := map[string]struct {
+		storeResource bool
+		storeLog      bool
+	}{
+		"both":          {storeResource: true, storeLog: true},
+		"resource_only": {storeResource: true},
+		"log_only":      {storeLog: true},
+		"neither":       {},
+	}
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			logs := plog.NewLogs()
+			rl := logs.ResourceLogs().AppendEmpty()
+			rl.Resource().Attributes().PutStr("service.name", "checkout")
+			r := rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty()
+			r.Attributes().PutStr("order.id", "42")
+			r.Body().SetStr("order placed")
+
+			session := &fakeSession{}
+			exp := newTestLogsExporter(t, session, func(config *Config) {
+				config.StoreResourceAttributes = test.storeResource
+				config.StoreLogAttributes = test.storeLog
+			})
+			ddl := parseCreateLogTableSQL(exp.cfg)
+			require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+			stmts := session.statements()
+			require.Len(t, stmts, 1)
+			columns := strings.Split(stmts[0].stmt[strings.Index(stmts[0].stmt, "(")+1:strings.Index(stmts[0].stmt, ")")], ", ")
+			require.Len(t, stmts[0].values, len(columns))
+			assert.Equal(t, strings.Count(stmts[0].stmt, "?"), len(columns))
+			values := map[string]any{}
+			for i, column := range columns {
+				values[column] = stmts[0].values[i]
 			}
-			require.Equal(t, test.expectedErr, err)
+			assert.Equal(t, "checkout", values["servicename"])
+			assert.Equal(t, `"order placed"`, values["body"])
+			assert.Contains(t, values, "datebucket")
+
+			if test.storeResource {
+				assert.Contains(t, ddl, " ResourceAttributes map<text, text>, ")
+				assert.Equal(t, map[string]string{"service.name": "checkout"}, values["resourceattributes"])
+			} else {
+				assert.NotContains(t, ddl, "ResourceAttributes")
+				assert.NotContains(t, values, "resourceattributes")
+			}
+			if test.storeLog {
+				assert.Contains(t, ddl, " LogAttributes map<text, text>, ")
+				assert.Equal(t, map[string]string{"order.id": "42"}, values["logattributes"])
+			} else {
+				assert.NotContains(t, ddl, "LogAttributes")
+				assert.NotContains(t, values, "logattributes")
+			}
+		})
+	}
+}
+
+func TestPushLogsDataConsistencyFallbackChain(t *testing.T) {
+	var calls atomic.Int32
+	session := &fakeSession{fail: func([]fakeStatement) error {
+		switch calls.Add(1) {
+		case 1:
+			return fakeRequestError{code: gocql.ErrCodeUnavailable}
+		case 2:
+			return fakeRequestError{code: gocql.ErrCodeWriteTimeout}
+		}
+		return nil
+	}}
+	core, logs := observer.New(zapcore.WarnLevel)
+	set := componenttest.NewNopTelemetrySettings()
+	set.Logger = zap.New(core)
+	exp, err := newLogsExporter(set, withDefaultConfig(func(config *Config) {
+		config.Consistency = "LOCAL_QUORUM"
+		config.ConsistencyFallbackChain = []string{"QUORUM", "ONE"}
+	}))
+	require.NoError(t, err)
+	exp.client = session
+	require.NoError(t, exp.pushLogsData(context.Background(), simpleLogs("INFO")))
+
+	assert.EqualValues(t, 3, calls.Load())
+	assert.Len(t, session.statements(), 1)
+	// Only the successful batch is recorded, written at the last level.
+	assert.Equal(t, []string{"ONE"}, session.consistencies)
+	steps := logs.FilterMessage("retrying insert at the next consistency of the fallback chain").All()
+	require.Len(t, steps, 2)
+	assert.Equal(t, "LOCAL_QUORUM", steps[0].ContextMap()["consistency"])
+	assert.Equal(t, "QUORUM", steps[0].ContextMap()["fallback"])
+	assert.Equal(t, "QUORUM", steps[1].ContextMap()["consistency"])
+	assert.Equal(t, "ONE", steps[1].ContextMap()["fallback"])
+
+	// Other errors fail the insert without stepping down.
+	calls.Store(0)
+	session.fail = func([]fakeStatement) error {
+		calls.Add(1)
+		return fakeRequestError{code: gocql.ErrCodeInvalid}
+	}
+	require.Error(t, exp.pushLogsData(context.Background(), simpleLogs("INFO")))
+	assert.EqualValues(t, 1, calls.Load())
+}
+
+func TestPushLogsDataTimestampFormat(t *testing.T) {
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	ts := time.Date(2024, 9, 1, 12, 0, 0, 123456789, time.UTC)
+	r := records.AppendEmpty()
+	r.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+	r.SetTraceID([16]byte{1, 2, 3})
+
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session, func(config *Config) {
+		config.TimestampFormat = timestampFormatBigintNanos
+		config.IndexLogsByTrace = true
+	})
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 2)
+	for _, stmt := range stmts {
+		var nanos any
+		if strings.HasPrefix(stmt.stmt, "INSERT INTO otel.otel_logs_by_trace ") {
+			nanos = stmt.values[1]
+		} else {
+			nanos = stmt.values[0]
+		}
+		require.IsType(t, int64(0), nanos, stmt.stmt)
+		assert.Equal(t, pcommon.Timestamp(nanos.(int64)), r.Timestamp())
+		assert.Equal(t, ts, time.Unix(0, nanos.(int64)).UTC())
+	}
+}
+
+func TestPushLogsDataShardCount(t *testing.T) {
+	const shardCount = 4
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	start := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	for i := 0; i < 64; i++ {
+		r := records.AppendEmpty()
+		r.SetTimestamp(pcommon.NewTimestampFromTime(start.Add(time.Duration(i) * time.Millisecond)))
+		if i%2 == 0 {
+			r.SetTraceID([16]byte{byte(i), 1})
+		}
+	}
+
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session, func(config *Config) {
+		config.ShardCount = shardCount
+	})
+	assert.Contains(t, exp.insertSQL, ", sampled, recordid, shard) VALUES(")
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	shards := map[int32]int{}
+	for _, stmt := range session.statements() {
+		require.Len(t, stmt.values, 22)
+		shard := stmt.values[21].(int32)
+		assert.GreaterOrEqual(t, shard, int32(0))
+		assert.Less(t, shard, int32(shardCount))
+		shards[shard]++
+	}
+	assert.Len(t, shards, shardCount)
+
+	// A replayed record goes to the shard of its first write.
+	traceID := traceutil.TraceIDToHexOrEmptyString(records.At(0).TraceID())
+	assert.Equal(t, logShard(traceID, start, shardCount), logShard(traceID, start.Add(time.Hour), shardCount))
+	assert.Equal(t, logShard("", start, shardCount), logShard("", start, shardCount))
+}
+
+func TestPushLogsDataInstanceID(t *testing.T) {
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session, func(config *Config) {
+		config.InstanceID = "collector-1"
+		config.ShardCount = 2
+		config.UseEventTimestamp = true
+	})
+	assert.Contains(t, exp.insertSQL, ", sampled, recordid, shard, collectorid) VALUES(")
+	require.NoError(t, exp.pushLogsData(context.Background(), simpleLogs("INFO")))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 1)
+	require.Len(t, stmts[0].values, 24)
+	assert.Equal(t, "collector-1", stmts[0].values[22])
+	assert.IsType(t, int64(0), stmts[0].values[23])
+}
+
+func TestPushLogsDataStoreRawOTLP(t *testing.T) {
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	rl.SetSchemaUrl("https://opentelemetry.io/schemas/1.21.0")
+	rl.Resource().Attributes().PutStr("service.name", "checkout")
+	sl := rl.ScopeLogs().AppendEmpty()
+	sl.Scope().SetName("io.opentelemetry.slog")
+	r := sl.LogRecords().AppendEmpty()
+	r.SetTimestamp(pcommon.NewTimestampFromTime(time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)))
+	r.SetSeverityText("INFO")
+	r.Body().SetEmptyMap().PutStr("message", "order placed")
+	r.Attributes().PutInt("order.items", 3)
+
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session, func(config *Config) {
+		config.StoreRawOTLP = true
+		config.InstanceID = "collector-1"
+	})
+	assert.Contains(t, exp.insertSQL, ", sampled, recordid, collectorid, rawotlp) VALUES(")
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 1)
+	require.Len(t, stmts[0].values, 23)
+	raw, ok := stmts[0].values[22].([]byte)
+	require.True(t, ok)
+	req := plogotlp.NewExportRequest()
+	require.NoError(t, req.UnmarshalProto(raw))
+	assert.Equal(t, logs, req.Logs())
+}
+
+func TestPushLogsDataIndexLogsByTrace(t *testing.T) {
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	rl.Resource().Attributes().PutStr("service.name", "checkout")
+	records := rl.ScopeLogs().AppendEmpty().LogRecords()
+	ts := time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)
+	traced := records.AppendEmpty()
+	traced.SetTimestamp(pcommon.NewTimestampFromTime(ts))
+	traced.SetTraceID([16]byte{1, 2, 3})
+	traced.SetSpanID([8]byte{4, 5})
+	traced.SetSeverityNumber(plog.SeverityNumberError)
+	records.AppendEmpty().SetTimestamp(pcommon.NewTimestampFromTime(ts))
+
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session, func(config *Config) {
+		config.IndexLogsByTrace = true
+		config.ShardCount = 2
+	})
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	var byTrace []fakeStatement
+	var logRows int
+	for _, stmt := range session.statements() {
+		if strings.HasPrefix(stmt.stmt, "INSERT INTO otel.otel_logs_by_trace ") {
+			byTrace = append(byTrace, stmt)
+		} else {
+			logRows++
+		}
+	}
+	assert.Equal(t, 2, logRows)
+	require.Len(t, byTrace, 1)
+	traceID := traceutil.TraceIDToHexOrEmptyString(traced.TraceID())
+	assert.Equal(t, "INSERT INTO otel.otel_logs_by_trace (traceid, timestamp, servicename, spanid, severitynumber, recordid, datebucket, shard) VALUES (?, ?, ?, ?, ?, ?, ?, ?)", byTrace[0].stmt)
+	assert.Equal(t, []any{traceID, ts, "checkout", "0405000000000000", int32(plog.SeverityNumberError), logRecordID(traced), time.Date(2024, 9, 1, 0, 0, 0, 0, time.UTC), logShard(traceID, ts, 2)}, byTrace[0].values)
+}
+
+func TestParseCreateLogsByTraceTableSQL(t *testing.T) {
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS otel.otel_logs_by_trace (TraceId text, TimeStamp TimeStamp, ServiceName text, SpanId text, SeverityNumber int, RecordId text, DateBucket TimeStamp, PRIMARY KEY (TraceId, TimeStamp, ServiceName, SpanId, SeverityNumber, RecordId)) WITH COMPRESSION = {'class': 'LZ4Compressor'} AND compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': 1, 'compaction_window_unit': 'DAYS'} AND gc_grace_seconds = 864000",
+		parseCreateLogsByTraceTableSQL(withDefaultConfig()))
+}
+
+func TestLogDateBucket(t *testing.T) {
+	ts := time.Date(2024, 9, 1, 23, 30, 0, 0, time.FixedZone("UTC-2", -2*60*60))
+	assert.Equal(t, time.Date(2024, 9, 2, 1, 0, 0, 0, time.UTC), logDateBucket(ts, partitionByHour))
+	assert.Equal(t, time.Date(2024, 9, 2, 0, 0, 0, 0, time.UTC), logDateBucket(ts, partitionByDay))
+}
+
+func TestPushLogsDataScope(t *testing.T) {
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	rl.SetSchemaUrl("https://opentelemetry.io/schemas/1.21.0")
+	first := rl.ScopeLogs().AppendEmpty()
+	first.Scope().SetName("io.opentelemetry.slog")
+	first.Scope().SetVersion("0.4.0")
+	first.LogRecords().AppendEmpty().Body().SetStr("first")
+	rl.ScopeLogs().AppendEmpty().LogRecords().AppendEmpty().Body().SetStr("second")
+	logs.MarkReadOnly()
+
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session)
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	var scopes [][]any
+	for _, stmt := range session.statements() {
+		scopes = append(scopes, stmt.values[11:14])
+	}
+	assert.ElementsMatch(t, [][]any{
+		{"io.opentelemetry.slog", "0.4.0", "https://opentelemetry.io/schemas/1.21.0"},
+		{"", "", "https://opentelemetry.io/schemas/1.21.0"},
+	}, scopes)
+}
+
+func TestPushLogsDataRecordID(t *testing.T) {
+	// Records from a file parsed at second precision, without a span and at
+	// the same severity, only differ by their body.
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	ts := pcommon.NewTimestampFromTime(time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC))
+	for _, body := range []string{"order placed", "order shipped"} {
+		r := records.AppendEmpty()
+		r.SetTimestamp(ts)
+		r.SetObservedTimestamp(ts)
+		r.SetSeverityNumber(plog.SeverityNumberInfo)
+		r.Body().SetStr(body)
+	}
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session)
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	keys := map[[6]any]bool{}
+	stmts := session.statements()
+	require.Len(t, stmts, 2)
+	for _, stmt := range stmts {
+		// The primary key: ServiceName, DateBucket, TimeStamp, SpanId,
+		// SeverityNumber and RecordId.
+		keys[[6]any{stmt.values[6], stmt.values[10], stmt.values[0], stmt.values[2], stmt.values[5], stmt.values[20]}] = true
+	}
+	assert.Len(t, keys, 2)
+	assert.Equal(t, "", stmts[0].values[2])
+
+	// A replayed record binds the same key and overwrites its first write.
+	assert.Equal(t, logRecordID(records.At(0)), stmts[0].values[20])
+	assert.NotEqual(t, logRecordID(records.At(0)), logRecordID(records.At(1)))
+}
+
+func TestPushLogsDataSampled(t *testing.T) {
+	logs := simpleLogs("INFO", "ERROR")
+	records := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	records.At(0).SetFlags(plog.DefaultLogRecordFlags.WithIsSampled(true))
+
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session)
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	sampled := map[any][]any{}
+	for _, stmt := range session.statements() {
+		sampled[stmt.values[4]] = []any{stmt.values[3], stmt.values[19]}
+	}
+	assert.Equal(t, map[any][]any{
+		"INFO":  {uint32(1), true},
+		"ERROR": {uint32(0), false},
+	}, sampled)
+}
+
+func TestPushLogsDataUseEventTimestamp(t *testing.T) {
+	logs := simpleLogs("INFO", "ERROR")
+	records := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	eventTime := time.Date(2024, 3, 1, 12, 0, 0, 123456789, time.UTC)
+	records.At(0).SetTimestamp(pcommon.NewTimestampFromTime(eventTime))
+	observedTime := eventTime.Add(time.Minute)
+	records.At(1).SetObservedTimestamp(pcommon.NewTimestampFromTime(observedTime))
+
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session, func(config *Config) {
+		config.UseEventTimestamp = true
+	})
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	writeTimes := map[any]any{}
+	for _, stmt := range session.statements() {
+		assert.True(t, strings.HasSuffix(stmt.stmt, ") USING TIMESTAMP ?"), stmt.stmt)
+		require.Len(t, stmt.values, 22)
+		writeTimes[stmt.values[4]] = stmt.values[21]
+	}
+	assert.Equal(t, map[any]any{
+		"INFO":  eventTime.UnixMicro(),
+		"ERROR": observedTime.UnixMicro(),
+	}, writeTimes)
+
+	exp = newTestLogsExporter(t, &fakeSession{}, func(config *Config) {
+		config.UseEventTimestamp = true
+		config.TTL = time.Hour
+	})
+	assert.True(t, strings.HasSuffix(exp.insertSQL, ") USING TTL 3600 AND TIMESTAMP ?"), exp.insertSQL)
+}
+
+func TestPushLogsDataDroppedAttributesCount(t *testing.T) {
+	logs := plog.NewLogs()
+	rl := logs.ResourceLogs().AppendEmpty()
+	rl.Resource().SetDroppedAttributesCount(1)
+	sl := rl.ScopeLogs().AppendEmpty()
+	sl.Scope().SetDroppedAttributesCount(2)
+	sl.LogRecords().AppendEmpty().SetDroppedAttributesCount(5)
+
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session)
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 1)
+	assert.Equal(t, []any{uint32(5), uint32(2), uint32(1)}, stmts[0].values[14:17])
+}
+
+func TestPushLogsDataMaxBodySize(t *testing.T) {
+	logs := simpleLogs("INFO", "ERROR")
+	records := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	records.At(1).Body().SetStr(strings.Repeat("x", 1<<20))
+
+	tel := setupTestTelemetry()
+	exp, err := newLogsExporter(tel.NewSettings().TelemetrySettings, withDefaultConfig(func(config *Config) {
+		config.BodyEncoding = bodyEncodingText
+		config.MaxBodySize = 1024
+	}))
+	require.NoError(t, err)
+	session := &fakeSession{}
+	exp.client = session
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 2)
+	assert.Equal(t, "message", stmts[0].values[7])
+	assert.Equal(t, false, stmts[0].values[17])
+	assert.Equal(t, strings.Repeat("x", 1024), stmts[1].values[7])
+	assert.Equal(t, true, stmts[1].values[17])
+
+	var md metricdata.ResourceMetrics
+	require.NoError(t, tel.reader.Collect(context.Background(), &md))
+	metricdatatest.AssertEqual(t, metricdata.Metrics{
+		Name:        "otelcol_cassandra_exporter_truncated_body_bytes",
+		Description: "Number of bytes cut off log bodies larger than max_body_size.",
+		Unit:        "By",
+		Data: metricdata.Sum[int64]{
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+			DataPoints: []metricdata.DataPoint[int64]{{
+				Attributes: attribute.NewSet(attribute.String("signal", "logs")),
+				Value:      1<<20 - 1024,
+			}},
+		},
+	}, tel.getMetric("otelcol_cassandra_exporter_truncated_body_bytes", md), metricdatatest.IgnoreTimestamp())
+	require.NoError(t, tel.Shutdown(context.Background()))
+}
+
+func TestPushLogsDataMaxAttributes(t *testing.T) {
+	logs := simpleLogs("INFO", "ERROR")
+	records := logs.ResourceLogs().At(0).ScopeLogs().At(0).LogRecords()
+	for i := 0; i < 5; i++ {
+		records.At(1).Attributes().PutInt("attr."+strconv.Itoa(4-i), int64(i))
+	}
+	records.At(0).Attributes().PutStr("http.method", "GET")
+
+	tel := setupTestTelemetry()
+	exp, err := newLogsExporter(tel.NewSettings().TelemetrySettings, withDefaultConfig(func(config *Config) {
+		config.MaxAttributes = 3
+	}))
+	require.NoError(t, err)
+	assert.Contains(t, exp.insertSQL, ", sampled, recordid, attributestruncated) VALUES(")
+	assert.Contains(t, parseCreateLogTableSQL(exp.cfg), ", Sampled boolean, RecordId text, AttributesTruncated boolean, PRIMARY KEY ")
+	session := &fakeSession{}
+	exp.client = session
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 2)
+	require.Len(t, stmts[0].values, 22)
+	assert.Equal(t, map[string]string{"http.method": "GET"}, stmts[0].values[9])
+	assert.Equal(t, false, stmts[0].values[21])
+	assert.Equal(t, map[string]string{"attr.0": "4", "attr.1": "3", "attr.2": "2"}, stmts[1].values[9])
+	assert.Equal(t, true, stmts[1].values[21])
+
+	var md metricdata.ResourceMetrics
+	require.NoError(t, tel.reader.Collect(context.Background(), &md))
+	metricdatatest.AssertEqual(t, metricdata.Metrics{
+		Name:        "otelcol_cassandra_exporter_truncated_attributes",
+		Description: "Number of attributes left out of log records and spans with more than max_attributes.",
+		Unit:        "{attributes}",
+		Data: metricdata.Sum[int64]{
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+			DataPoints: []metricdata.DataPoint[int64]{{
+				Attributes: attribute.NewSet(attribute.String("signal", "logs")),
+				Value:      2,
+			}},
+		},
+	}, tel.getMetric("otelcol_cassandra_exporter_truncated_attributes", md), metricdatatest.IgnoreTimestamp())
+	require.NoError(t, tel.Shutdown(context.Background()))
+}
+
+func TestTruncateLogBody(t *testing.T) {
+	body, truncated := truncateLogBody("héllo", 2)
+	assert.Equal(t, "h", body)
+	assert.Equal(t, 5, truncated)
+	body, truncated = truncateLogBody("héllo", 3)
+	assert.Equal(t, "hé", body)
+	assert.Equal(t, 3, truncated)
+	body, truncated = truncateLogBody([]byte{1, 2, 3}, 2)
+	assert.Equal(t, []byte{1, 2}, body)
+	assert.Equal(t, 1, truncated)
+	body, truncated = truncateLogBody("short", 10)
+	assert.Equal(t, "short", body)
+	assert.Zero(t, truncated)
+}
+
+func TestLogSeverityText(t *testing.T) {
+	tests := []struct {
+		number   plog.SeverityNumber
+		text     string
+		expected string
+	}{
+		{number: plog.SeverityNumberUnspecified, expected: ""},
+		{number: plog.SeverityNumberTrace, expected: "TRACE"},
+		{number: plog.SeverityNumberTrace4, expected: "TRACE"},
+		{number: plog.SeverityNumberDebug, expected: "DEBUG"},
+		{number: plog.SeverityNumberDebug4, expected: "DEBUG"},
+		{number: plog.SeverityNumberInfo, expected: "INFO"},
+		{number: plog.SeverityNumberInfo4, expected: "INFO"},
+		{number: plog.SeverityNumberWarn, expected: "WARN"},
+		{number: plog.SeverityNumberWarn4, expected: "WARN"},
+		{number: plog.SeverityNumberError, expected: "ERROR"},
+		{number: plog.SeverityNumberError4, expected: "ERROR"},
+		{number: plog.SeverityNumberFatal, expected: "FATAL"},
+		{number: plog.SeverityNumberFatal4, expected: "FATAL"},
+		{number: plog.SeverityNumberError2, text: "E", expected: "E"},
+		{number: plog.SeverityNumberUnspecified, text: "notice", expected: "notice"},
+	}
+	for _, test := range tests {
+		t.Run(test.number.String()+"_"+test.text, func(t *testing.T) {
+			r := plog.NewLogRecord()
+			r.SetSeverityNumber(test.number)
+			r.SetSeverityText(test.text)
+			assert.Equal(t, test.expected, logSeverityText(r))
 		})
 	}
 }
 
+func TestLogTimestamp(t *testing.T) {
+	timestamp := time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)
+	observed := timestamp.Add(time.Second)
+
+	t.Run("timestamp", func(t *testing.T) {
+		r := plog.NewLogRecord()
+		r.SetTimestamp(pcommon.NewTimestampFromTime(timestamp))
+		r.SetObservedTimestamp(pcommon.NewTimestampFromTime(observed))
+		assert.True(t, timestamp.Equal(logTimestamp(r)))
+	})
+
+	t.Run("observed_timestamp", func(t *testing.T) {
+		r := plog.NewLogRecord()
+		r.SetObservedTimestamp(pcommon.NewTimestampFromTime(observed))
+		assert.True(t, observed.Equal(logTimestamp(r)))
+	})
+
+	t.Run("now", func(t *testing.T) {
+		before := time.Now()
+		got := logTimestamp(plog.NewLogRecord())
+		assert.False(t, got.Before(before))
+		assert.False(t, got.After(time.Now()))
+	})
+}
+
+func TestPushLogsDataTelemetry(t *testing.T) {
+	tel := setupTestTelemetry()
+	exp, err := newLogsExporter(tel.NewSettings().TelemetrySettings, withDefaultConfig(func(config *Config) {
+		config.BatchSize = 1
+	}))
+	require.NoError(t, err)
+	exp.client = &fakeSession{fail: func(stmts []fakeStatement) error {
+		if stmts[0].values[4] == "WARN" {
+			return errors.New("write timeout")
+		}
+		return nil
+	}}
+	require.Error(t, exp.pushLogsData(context.Background(), simpleLogs("INFO", "WARN", "ERROR")))
+
+	signal := attribute.NewSet(attribute.String("signal", "logs"))
+	var md metricdata.ResourceMetrics
+	require.NoError(t, tel.reader.Collect(context.Background(), &md))
+	metricdatatest.AssertEqual(t, metricdata.Metrics{
+		Name:        "otelcol_cassandra_exporter_sent_records",
+		Description: "Number of records successfully inserted into Cassandra.",
+		Unit:        "{records}",
+		Data: metricdata.Sum[int64]{
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+			DataPoints:  []metricdata.DataPoint[int64]{{Attributes: signal, Value: 2}},
+		},
+	}, tel.getMetric("otelcol_cassandra_exporter_sent_records", md), metricdatatest.IgnoreTimestamp())
+	metricdatatest.AssertEqual(t, metricdata.Metrics{
+		Name:        "otelcol_cassandra_exporter_failed_records",
+		Description: "Number of records that could not be inserted into Cassandra.",
+		Unit:        "{records}",
+		Data: metricdata.Sum[int64]{
+			Temporality: metricdata.CumulativeTemporality,
+			IsMonotonic: true,
+			DataPoints:  []metricdata.DataPoint[int64]{{Attributes: signal, Value: 1}},
+		},
+	}, tel.getMetric("otelcol_cassandra_exporter_failed_records", md), metricdatatest.IgnoreTimestamp())
+	latency := tel.getMetric("otelcol_cassandra_exporter_batch_latency", md).Data.(metricdata.Histogram[int64])
+	require.Len(t, latency.DataPoints, 1)
+	assert.Equal(t, signal, latency.DataPoints[0].Attributes)
+	assert.Equal(t, uint64(3), latency.DataPoints[0].Count)
+	require.NoError(t, tel.Shutdown(context.Background()))
+}
+
+func TestLogsExporterShutdownDrains(t *testing.T) {
+	release := make(chan struct{})
+	executing := make(chan struct{}, 3)
+	session := &fakeSession{fail: func([]fakeStatement) error {
+		executing <- struct{}{}
+		<-release
+		return nil
+	}}
+	exp := newTestLogsExporter(t, session, func(config *Config) {
+		config.BatchSize = 1
+		config.NumWorkers = 3
+	})
+
+	pushed := make(chan error, 1)
+	go func() {
+		pushed <- exp.pushLogsData(context.Background(), simpleLogs("INFO", "WARN", "ERROR"))
+	}()
+	<-executing
+
+	shutdown := make(chan error, 1)
+	go func() {
+		shutdown <- exp.Shutdown(context.Background())
+	}()
+	select {
+	case <-shutdown:
+		t.Fatal("shutdown returned while inserts were in flight")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	close(release)
+	require.NoError(t, <-pushed)
+	require.NoError(t, <-shutdown)
+	assert.Len(t, session.statements(), 3)
+	assert.True(t, session.closed)
+
+	err := exp.pushLogsData(context.Background(), simpleLogs("INFO"))
+	require.ErrorIs(t, err, errExporterShutdown)
+	assert.True(t, consumererror.IsPermanent(err))
+}
+
+func TestExportersShutdownTwice(t *testing.T) {
+	core, entries := observer.New(zapcore.InfoLevel)
+	set := componenttest.NewNopTelemetrySettings()
+	set.Logger = zap.New(core)
+	cfg := withDefaultConfig(func(config *Config) {
+		config.Coalescing = Coalescing{Enabled: true, FlushInterval: time.Hour}
+	})
+
+	logsSession := &fakeSession{}
+	logs, err := newLogsExporter(set, cfg)
+	require.NoError(t, err)
+	logs.client = logsSession
+	logs.buffer = newStatementBuffer(cfg.BatchSize, cfg.Coalescing.FlushInterval, 0, logs.flushBuffered)
+	require.NoError(t, logs.pushLogsData(context.Background(), simpleLogs("INFO", "WARN")))
+	tracesSession := &fakeSession{}
+	traces, err := newTracesExporter(set, cfg)
+	require.NoError(t, err)
+	traces.client = tracesSession
+	metricsSession := &fakeSession{}
+	metrics, err := newMetricsExporter(set, cfg)
+	require.NoError(t, err)
+	metrics.client = metricsSession
+
+	for _, shutdown := range []func(context.Context) error{logs.Shutdown, traces.Shutdown, metrics.Shutdown} {
+		require.NotPanics(t, func() {
+			require.NoError(t, shutdown(context.Background()))
+			require.NoError(t, shutdown(context.Background()))
+		})
+	}
+	assert.Len(t, logsSession.statements(), 2)
+	for _, session := range []*fakeSession{logsSession, tracesSession, metricsSession} {
+		assert.Equal(t, 1, session.closes)
+	}
+
+	shutdowns := entries.FilterMessage("cassandra exporter shut down").All()
+	require.Len(t, shutdowns, 3)
+	assert.Equal(t, map[string]any{"signal": signalLogs, "drained_pushes": int64(0), "flushed_records": int64(2)}, shutdowns[0].ContextMap())
+	assert.Equal(t, map[string]any{"signal": signalTraces, "drained_pushes": int64(0)}, shutdowns[1].ContextMap())
+}
+
+func TestLogsExporterShutdownTimeout(t *testing.T) {
+	release := make(chan struct{})
+	defer close(release)
+	executing := make(chan struct{}, 1)
+	session := &fakeSession{fail: func([]fakeStatement) error {
+		executing <- struct{}{}
+		<-release
+		return nil
+	}}
+	exp := newTestLogsExporter(t, session, func(config *Config) {
+		config.ShutdownTimeout = 10 * time.Millisecond
+	})
+
+	go func() {
+		_ = exp.pushLogsData(context.Background(), simpleLogs("INFO"))
+	}()
+	<-executing
+	require.ErrorIs(t, exp.Shutdown(context.Background()), errDrainTimeout)
+	assert.True(t, session.closed)
+}
+
+func TestPushLogsDataCanceled(t *testing.T) {
+	t.Run("before_push", func(t *testing.T) {
+		session := &fakeSession{}
+		exp := newTestLogsExporter(t, session)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+
+		err := exp.pushLogsData(ctx, simpleLogs("INFO", "WARN", "ERROR"))
+		require.ErrorIs(t, err, context.Canceled)
+		assert.Empty(t, session.statements())
+	})
+
+	t.Run("mid_flight", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+		session := &fakeSession{fail: func([]fakeStatement) error {
+			cancel()
+			return nil
+		}}
+		exp := newTestLogsExporter(t, session, func(config *Config) {
+			config.BatchSize = 1
+		})
+
+		err := exp.pushLogsData(ctx, simpleLogs("INFO", "WARN", "ERROR"))
+		require.ErrorIs(t, err, context.Canceled)
+		assert.False(t, consumererror.IsPermanent(err))
+		assert.Len(t, session.statements(), 1)
+	})
+}
+
+func newTestLogsExporter(tb testing.TB, session cqlSession, fns ...func(*Config)) *logsExporter {
+	exp, err := newLogsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig(fns...))
+	require.NoError(tb, err)
+	exp.client = session
+	return exp
+}
+
+// simpleLogs returns a single resource with one log record per severity text.
+func simpleLogs(severities ...string) plog.Logs {
+	logs := plog.NewLogs()
+	records := logs.ResourceLogs().AppendEmpty().ScopeLogs().AppendEmpty().LogRecords()
+	for _, severity := range severities {
+		r := records.AppendEmpty()
+		r.SetSeverityText(severity)
+		r.Body().SetStr("message")
+	}
+	return logs
+}
+
 func withDefaultConfig(fns ...func(*Config)) *Config {
 	cfg := createDefaultConfig().(*Config)
 	for _, fn := range fns {
@@ -64,3 +1520,91 @@ func withDefaultConfig(fns ...func(*Config)) *Config {
 	}
 	return cfg
 }
+
+func BenchmarkPushLogsData(b *testing.B) {
+	severities := make([]string, 1000)
+	for i := range severities {
+		severities[i] = "INFO"
+	}
+	logs := simpleLogs(severities...)
+	exp := newTestLogsExporter(b, &fakeSession{})
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		exp.client = &fakeSession{}
+		if err := exp.pushLogsData(context.Background(), logs); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkPushLogsDataWorkers compares the serial path with concurrent batch
+// flushes against a session that takes a fixed time per batch.
+func BenchmarkPushLogsDataWorkers(b *testing.B) {
+	severities := make([]string, 1000)
+	for i := range severities {
+		severities[i] = "INFO"
+	}
+	logs := simpleLogs(severities...)
+	slow := func([]fakeStatement) error {
+		time.Sleep(100 * time.Microsecond)
+		return nil
+	}
+
+	for _, workers := range []int{1, 4, 16} {
+		b.Run(strconv.Itoa(workers), func(b *testing.B) {
+			exp := newTestLogsExporter(b, &fakeSession{}, func(config *Config) {
+				config.NumWorkers = workers
+			})
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				exp.client = &fakeSession{fail: slow}
+				if err := exp.pushLogsData(context.Background(), logs); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+// BenchmarkPushLogsDataNumConns measures how the insert throughput scales with
+// the connections per host. It needs a live cluster, given by
+// CASSANDRA_BENCHMARK_ENDPOINT, since the fake session has no connections.
+func BenchmarkPushLogsDataNumConns(b *testing.B) {
+	endpoint := os.Getenv("CASSANDRA_BENCHMARK_ENDPOINT")
+	if endpoint == "" {
+		b.Skip("CASSANDRA_BENCHMARK_ENDPOINT is not set")
+	}
+	severities := make([]string, 1000)
+	for i := range severities {
+		severities[i] = "INFO"
+	}
+	logs := simpleLogs(severities...)
+
+	for _, conns := range []int{1, 2, 4, 8} {
+		b.Run(strconv.Itoa(conns), func(b *testing.B) {
+			exp, err := newLogsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig(func(config *Config) {
+				config.Endpoints = []string{endpoint}
+				config.Keyspace = "otel_benchmark"
+				config.NumConns = conns
+				config.NumWorkers = 16
+			}))
+			if err != nil {
+				b.Fatal(err)
+			}
+			if err := exp.Start(context.Background(), componenttest.NewNopHost()); err != nil {
+				b.Fatal(err)
+			}
+			defer func() { _ = exp.Shutdown(context.Background()) }()
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if err := exp.pushLogsData(context.Background(), logs); err != nil {
+					b.Fatal(err)
+				}
+			}
+			b.ReportMetric(float64(b.N*logs.LogRecordCount())/b.Elapsed().Seconds(), "records/s")
+		})
+	}
+}
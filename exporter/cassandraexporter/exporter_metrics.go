@@ -0,0 +1,444 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.uber.org/zap"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/coreinternal/traceutil"
+)
+
+const (
+	gaugeTableSuffix                = "_gauge"
+	sumTableSuffix                  = "_sum"
+	histogramTableSuffix            = "_histogram"
+	exponentialHistogramTableSuffix = "_exponential_histogram"
+	summaryTableSuffix              = "_summary"
+	exemplarTableSuffix             = "_exemplars"
+)
+
+type metricsExporter struct {
+	client                        cqlSession
+	newSession                    sessionFactory
+	borrowed                      cqlSession
+	insertGaugeSQL                string
+	insertSumSQL                  string
+	insertHistogramSQL            string
+	insertExponentialHistogramSQL string
+	insertSummarySQL              string
+	insertExemplarSQL             string
+
+	pushes    inflightPushes
+	closing   sync.Once
+	telemetry *insertTelemetry
+	logger    *zap.Logger
+	cfg       *Config
+}
+
+func newMetricsExporter(set component.TelemetrySettings, cfg *Config) (*metricsExporter, error) {
+	telemetry, err := newInsertTelemetry(set, signalMetrics)
+	if err != nil {
+		return nil, err
+	}
+	cfg = cfg.forSignal(cfg.MetricsKeyspace, cfg.MetricsConsistency)
+	return &metricsExporter{
+		insertGaugeSQL:                parseInsertSQL(cfg, withInstanceIDInsert(cfg, insertGaugeSQL), suffixTable(cfg.MetricsTable, gaugeTableSuffix)),
+		insertSumSQL:                  parseInsertSQL(cfg, withInstanceIDInsert(cfg, insertSumSQL), suffixTable(cfg.MetricsTable, sumTableSuffix)),
+		insertHistogramSQL:            parseInsertSQL(cfg, withInstanceIDInsert(cfg, insertHistogramSQL), suffixTable(cfg.MetricsTable, histogramTableSuffix)),
+		insertExponentialHistogramSQL: parseInsertSQL(cfg, withInstanceIDInsert(cfg, insertExponentialHistogramSQL), suffixTable(cfg.MetricsTable, exponentialHistogramTableSuffix)),
+		insertSummarySQL:              parseInsertSQL(cfg, withInstanceIDInsert(cfg, insertSummarySQL), suffixTable(cfg.MetricsTable, summaryTableSuffix)),
+		insertExemplarSQL:             parseInsertSQL(cfg, insertExemplarSQL, suffixTable(cfg.MetricsTable, exemplarTableSuffix)),
+		logger:                        set.Logger,
+		cfg:                           cfg,
+		newSession:                    createGocqlSession,
+		telemetry:                     telemetry,
+	}, nil
+}
+
+// initializeMetricKernel creates the keyspace and one table per metric type.
+func (e *metricsExporter) initializeMetricKernel(ctx context.Context, session cqlSession) error {
+	stmts := append(keyspaceSchema(e.cfg), parseCreateMetricTablesSQL(e.cfg)...)
+	return execSchema(ctx, session, stmts...)
+}
+
+func parseCreateMetricTablesSQL(cfg *Config) []string {
+	attrType := attributesColumnType(cfg)
+	return []string{
+		withInstanceIDColumn(cfg, fmt.Sprintf(createGaugeTableSQL, cfg.Keyspace, suffixTable(cfg.MetricsTable, gaugeTableSuffix), attrType, attrType, parseTableOptions(cfg))),
+		withInstanceIDColumn(cfg, fmt.Sprintf(createSumTableSQL, cfg.Keyspace, suffixTable(cfg.MetricsTable, sumTableSuffix), attrType, attrType, parseTableOptions(cfg))),
+		withInstanceIDColumn(cfg, fmt.Sprintf(createHistogramTableSQL, cfg.Keyspace, suffixTable(cfg.MetricsTable, histogramTableSuffix), attrType, attrType, parseTableOptions(cfg))),
+		withInstanceIDColumn(cfg, fmt.Sprintf(createExponentialHistogramTableSQL, cfg.Keyspace, suffixTable(cfg.MetricsTable, exponentialHistogramTableSuffix), attrType, attrType, parseTableOptions(cfg))),
+		withInstanceIDColumn(cfg, fmt.Sprintf(createSummaryTableSQL, cfg.Keyspace, suffixTable(cfg.MetricsTable, summaryTableSuffix), attrType, attrType, parseTableOptions(cfg))),
+		fmt.Sprintf(createExemplarTableSQL, cfg.Keyspace, suffixTable(cfg.MetricsTable, exemplarTableSuffix), attrType, parseTableOptions(cfg)),
+	}
+}
+
+func (e *metricsExporter) Start(ctx context.Context, _ component.Host) error {
+	session, err := startSession(ctx, e.cfg, e.logger, e.telemetry, e.newSession, e.borrowed, e.initializeMetricKernel)
+	if err != nil {
+		return err
+	}
+	e.client = session
+	return nil
+}
+
+func (e *metricsExporter) Shutdown(ctx context.Context) error {
+	// Let the running pushes write their batches before the session goes
+	// away, new pushes are rejected from now on.
+	drained, err := e.pushes.drain(ctx, e.cfg.ShutdownTimeout)
+	e.closing.Do(func() {
+		if e.client != nil {
+			e.client.Close()
+		}
+		e.logger.Info("cassandra exporter shut down", zap.String("signal", signalMetrics),
+			zap.Int("drained_pushes", drained))
+	})
+
+	return err
+}
+
+// metricRow carries the columns shared by every metric table.
+type metricRow struct {
+	// resAttr identifies the series while resColumn is what the resource
+	// attributes are stored as.
+	resAttr      map[string]string
+	resColumn    any
+	service      string
+	cfg          *Config
+	schemaURL    string
+	scopeName    string
+	scopeVersion string
+	name         string
+	description  string
+	unit         string
+}
+
+// args returns the partition key, the series id and the leading bind values
+// of a data point.
+func (m metricRow) args(attributes pcommon.Map, startTime, ts pcommon.Timestamp) (string, string, []any) {
+	attrs := attributesToMap(attributes)
+	series := seriesID(m.resAttr, attrs)
+	var attrsColumn any = attrs
+	if m.cfg.AttributesFormat != attributesFormatMap || m.cfg.AttributesUDT != "" {
+		attrsColumn = encodeAttributes(attributes, m.cfg)
+	}
+	return m.name + "/" + series, series, []any{
+		m.resColumn,
+		m.scopeName,
+		m.scopeVersion,
+		m.name,
+		m.description,
+		m.unit,
+		series,
+		attrsColumn,
+		startTime.AsTime(),
+		ts.AsTime(),
+	}
+}
+
+// seriesID identifies a time series by hashing its resource and data point attributes.
+func seriesID(resAttr, attrs map[string]string) string {
+	h := fnv.New64a()
+	hashAttributes(h, resAttr)
+	hashAttributes(h, attrs)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (e *metricsExporter) pushMetricsData(ctx context.Context, md pmetric.Metrics) error {
+	if err := e.pushes.start(); err != nil {
+		return err
+	}
+	defer e.pushes.done()
+	if e.client == nil {
+		return errSessionNotOpen
+	}
+
+	start := time.Now()
+
+	var errs insertErrors
+	for i := 0; i < md.ResourceMetrics().Len(); i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+		metrics := md.ResourceMetrics().At(i)
+		resAttr := attributesToMap(metrics.Resource().Attributes())
+		resColumn := encodeAttributes(metrics.Resource().Attributes(), e.cfg)
+		serviceName := resourceServiceName(metrics.Resource())
+
+		var stmts []statement
+		for j := 0; j < metrics.ScopeMetrics().Len(); j++ {
+			scopeMetrics := metrics.ScopeMetrics().At(j)
+			scope := scopeMetrics.Scope()
+			rs := scopeMetrics.Metrics()
+			for k := 0; k < rs.Len(); k++ {
+				r := rs.At(k)
+				row := metricRow{
+					resAttr:      resAttr,
+					resColumn:    resColumn,
+					service:      serviceName,
+					cfg:          e.cfg,
+					schemaURL:    metrics.SchemaUrl(),
+					scopeName:    scope.Name(),
+					scopeVersion: scope.Version(),
+					name:         r.Name(),
+					description:  r.Description(),
+					unit:         r.Unit(),
+				}
+
+				switch r.Type() {
+				case pmetric.MetricTypeGauge:
+					stmts = e.appendGauge(stmts, row, r.Gauge())
+				case pmetric.MetricTypeSum:
+					stmts = e.appendSum(stmts, row, r.Sum())
+				case pmetric.MetricTypeHistogram:
+					stmts = e.appendHistogram(stmts, row, r.Histogram())
+				case pmetric.MetricTypeExponentialHistogram:
+					stmts = e.appendExponentialHistogram(stmts, row, r.ExponentialHistogram())
+				case pmetric.MetricTypeSummary:
+					stmts = e.appendSummary(stmts, row, r.Summary())
+				default:
+					e.logger.Debug("unsupported metric type", zap.String("metric", r.Name()),
+						zap.String("type", r.Type().String()))
+				}
+			}
+		}
+
+		executeBatches(ctx, e.client, e.cfg, stmts, &errs, e.telemetry, e.logger)
+	}
+
+	duration := time.Since(start)
+	e.logger.Debug("insert metrics", zap.Int("records", md.DataPointCount()),
+		zap.String("cost", duration.String()))
+	if err := errs.err(); err != nil {
+		return fmt.Errorf("insert metrics: %w", err)
+	}
+	return nil
+}
+
+func (e *metricsExporter) appendGauge(stmts []statement, row metricRow, gauge pmetric.Gauge) []statement {
+	dps := gauge.DataPoints()
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		partitionKey, series, args := row.args(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+		stmts = append(stmts, statement{
+			partitionKey: partitionKey,
+			query:        e.insertGaugeSQL,
+			args:         appendInstanceID(e.cfg, append(args, numberValue(dp), uint32(dp.Flags()), row.schemaURL)),
+			timestamp:    dp.Timestamp().AsTime(),
+			service:      row.service,
+		})
+		stmts = e.appendExemplars(stmts, row, partitionKey, series, dp.Timestamp(), dp.Exemplars())
+	}
+	return stmts
+}
+
+func (e *metricsExporter) appendSum(stmts []statement, row metricRow, sum pmetric.Sum) []statement {
+	dps := sum.DataPoints()
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		partitionKey, series, args := row.args(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+		stmts = append(stmts, statement{
+			partitionKey: partitionKey,
+			query:        e.insertSumSQL,
+			args: appendInstanceID(e.cfg, append(args,
+				numberValue(dp),
+				uint32(dp.Flags()),
+				int32(sum.AggregationTemporality()),
+				sum.IsMonotonic(),
+				row.schemaURL,
+			)),
+			timestamp: dp.Timestamp().AsTime(),
+			service:   row.service,
+		})
+		stmts = e.appendExemplars(stmts, row, partitionKey, series, dp.Timestamp(), dp.Exemplars())
+	}
+	return stmts
+}
+
+func (e *metricsExporter) appendHistogram(stmts []statement, row metricRow, histogram pmetric.Histogram) []statement {
+	dps := histogram.DataPoints()
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		partitionKey, series, args := row.args(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+		var sum, minimum, maximum any
+		if dp.HasSum() {
+			sum = dp.Sum()
+		}
+		if dp.HasMin() {
+			minimum = dp.Min()
+		}
+		if dp.HasMax() {
+			maximum = dp.Max()
+		}
+		stmts = append(stmts, statement{
+			partitionKey: partitionKey,
+			query:        e.insertHistogramSQL,
+			args: appendInstanceID(e.cfg, append(args,
+				int64(dp.Count()),
+				sum,
+				bucketCounts(dp.BucketCounts()),
+				dp.ExplicitBounds().AsRaw(),
+				minimum,
+				maximum,
+				uint32(dp.Flags()),
+				int32(histogram.AggregationTemporality()),
+				row.schemaURL,
+			)),
+			timestamp: dp.Timestamp().AsTime(),
+			service:   row.service,
+		})
+		stmts = e.appendExemplars(stmts, row, partitionKey, series, dp.Timestamp(), dp.Exemplars())
+	}
+	return stmts
+}
+
+// appendExponentialHistogram adds a row per data point keeping the scale, the
+// zero bucket and the offset and counts of the positive and negative buckets,
+// from which the bucket boundaries are reconstructed.
+func (e *metricsExporter) appendExponentialHistogram(stmts []statement, row metricRow, histogram pmetric.ExponentialHistogram) []statement {
+	dps := histogram.DataPoints()
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		partitionKey, series, args := row.args(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+		var sum, minimum, maximum any
+		if dp.HasSum() {
+			sum = dp.Sum()
+		}
+		if dp.HasMin() {
+			minimum = dp.Min()
+		}
+		if dp.HasMax() {
+			maximum = dp.Max()
+		}
+		stmts = append(stmts, statement{
+			partitionKey: partitionKey,
+			query:        e.insertExponentialHistogramSQL,
+			args: appendInstanceID(e.cfg, append(args,
+				int64(dp.Count()),
+				sum,
+				dp.Scale(),
+				int64(dp.ZeroCount()),
+				dp.ZeroThreshold(),
+				dp.Positive().Offset(),
+				bucketCounts(dp.Positive().BucketCounts()),
+				dp.Negative().Offset(),
+				bucketCounts(dp.Negative().BucketCounts()),
+				minimum,
+				maximum,
+				uint32(dp.Flags()),
+				int32(histogram.AggregationTemporality()),
+				row.schemaURL,
+			)),
+			timestamp: dp.Timestamp().AsTime(),
+			service:   row.service,
+		})
+		stmts = e.appendExemplars(stmts, row, partitionKey, series, dp.Timestamp(), dp.Exemplars())
+	}
+	return stmts
+}
+
+// appendSummary adds a row per data point keeping its quantiles and their
+// values as two lists of the same length, in the order of the data point.
+func (e *metricsExporter) appendSummary(stmts []statement, row metricRow, summary pmetric.Summary) []statement {
+	dps := summary.DataPoints()
+	for i := 0; i < dps.Len(); i++ {
+		dp := dps.At(i)
+		partitionKey, _, args := row.args(dp.Attributes(), dp.StartTimestamp(), dp.Timestamp())
+		qvs := dp.QuantileValues()
+		quantiles := make([]float64, qvs.Len())
+		values := make([]float64, qvs.Len())
+		for j := 0; j < qvs.Len(); j++ {
+			quantiles[j] = qvs.At(j).Quantile()
+			values[j] = qvs.At(j).Value()
+		}
+		stmts = append(stmts, statement{
+			partitionKey: partitionKey,
+			query:        e.insertSummarySQL,
+			args: appendInstanceID(e.cfg, append(args,
+				int64(dp.Count()),
+				dp.Sum(),
+				quantiles,
+				values,
+				uint32(dp.Flags()),
+				row.schemaURL,
+			)),
+			timestamp: dp.Timestamp().AsTime(),
+			service:   row.service,
+		})
+	}
+	return stmts
+}
+
+// appendExemplars adds a row per exemplar of a data point, in the partition of
+// its series and keyed by the data point timestamp, linking the measurement to
+// the trace and span it was recorded in.
+func (e *metricsExporter) appendExemplars(stmts []statement, row metricRow, partitionKey, series string, ts pcommon.Timestamp, exemplars pmetric.ExemplarSlice) []statement {
+	for i := 0; i < exemplars.Len(); i++ {
+		exemplar := exemplars.At(i)
+		stmts = append(stmts, statement{
+			partitionKey: partitionKey,
+			query:        e.insertExemplarSQL,
+			args: []any{
+				row.name,
+				series,
+				ts.AsTime(),
+				int32(i),
+				exemplar.Timestamp().AsTime(),
+				exemplarValue(exemplar),
+				traceutil.TraceIDToHexOrEmptyString(exemplar.TraceID()),
+				traceutil.SpanIDToHexOrEmptyString(exemplar.SpanID()),
+				encodeAttributes(exemplar.FilteredAttributes(), e.cfg),
+			},
+			timestamp: exemplar.Timestamp().AsTime(),
+			service:   row.service,
+		})
+	}
+	return stmts
+}
+
+// exemplarValue returns the value of the exemplar as a double, or nil when it
+// has none.
+func exemplarValue(exemplar pmetric.Exemplar) any {
+	switch exemplar.ValueType() {
+	case pmetric.ExemplarValueTypeInt:
+		return float64(exemplar.IntValue())
+	case pmetric.ExemplarValueTypeDouble:
+		return exemplar.DoubleValue()
+	default:
+		return nil
+	}
+}
+
+// numberValue returns the value of the data point as a double, or nil when it has none.
+func numberValue(dp pmetric.NumberDataPoint) any {
+	switch dp.ValueType() {
+	case pmetric.NumberDataPointValueTypeInt:
+		return float64(dp.IntValue())
+	case pmetric.NumberDataPointValueTypeDouble:
+		return dp.DoubleValue()
+	default:
+		return nil
+	}
+}
+
+// bucketCounts converts the counts so they can be bound to a list<bigint> column.
+func bucketCounts(counts pcommon.UInt64Slice) []int64 {
+	res := make([]int64, counts.Len())
+	for i := 0; i < counts.Len(); i++ {
+		res[i] = int64(counts.At(i))
+	}
+	return res
+}
@@ -0,0 +1,283 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+)
+
+func TestPushMetricsData(t *testing.T) {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.SetSchemaUrl("https://opentelemetry.io/schemas/1.21.0")
+	rm.Resource().Attributes().PutStr("service.name", "checkout")
+	sm := rm.ScopeMetrics().AppendEmpty()
+	sm.Scope().SetName("io.opentelemetry.test")
+	sm.Scope().SetVersion("1.0.0")
+
+	gauge := sm.Metrics().AppendEmpty()
+	gauge.SetName("queue.size")
+	gauge.SetUnit("1")
+	dp := gauge.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetIntValue(42)
+	dp.Attributes().PutStr("queue", "orders")
+
+	sum := sm.Metrics().AppendEmpty()
+	sum.SetName("requests")
+	sum.SetEmptySum().SetIsMonotonic(true)
+	sum.Sum().SetAggregationTemporality(pmetric.AggregationTemporalityCumulative)
+	sum.Sum().DataPoints().AppendEmpty().SetDoubleValue(1.5)
+
+	histogram := sm.Metrics().AppendEmpty()
+	histogram.SetName("latency")
+	hdp := histogram.SetEmptyHistogram().DataPoints().AppendEmpty()
+	hdp.SetCount(3)
+	hdp.SetSum(12)
+	hdp.SetMin(1)
+	hdp.BucketCounts().FromRaw([]uint64{1, 2})
+	hdp.ExplicitBounds().FromRaw([]float64{5})
+
+	sm.Metrics().AppendEmpty().SetName("empty")
+
+	session := &fakeSession{}
+	exp, err := newMetricsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig())
+	require.NoError(t, err)
+	exp.client = session
+	// Exporters report MutatesData false, pushing read-only data panics on any write.
+	md.MarkReadOnly()
+	require.NoError(t, exp.pushMetricsData(context.Background(), md))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 3)
+	byTable := map[string]fakeStatement{}
+	for _, stmt := range stmts {
+		for _, suffix := range []string{gaugeTableSuffix, sumTableSuffix, histogramTableSuffix} {
+			if strings.Contains(stmt.stmt, "otel.otel_metrics"+suffix+" ") {
+				byTable[suffix] = stmt
+			}
+		}
+	}
+	require.Len(t, byTable, 3)
+
+	g := byTable[gaugeTableSuffix].values
+	assert.Equal(t, map[string]string{"service.name": "checkout"}, g[0])
+	assert.Equal(t, "io.opentelemetry.test", g[1])
+	assert.Equal(t, "1.0.0", g[2])
+	assert.Equal(t, "queue.size", g[3])
+	assert.Equal(t, "1", g[5])
+	assert.Equal(t, float64(42), g[10])
+	assert.Equal(t, "https://opentelemetry.io/schemas/1.21.0", g[12])
+
+	s := byTable[sumTableSuffix].values
+	assert.Equal(t, 1.5, s[10])
+	assert.Equal(t, int32(pmetric.AggregationTemporalityCumulative), s[12])
+	assert.Equal(t, true, s[13])
+	assert.Equal(t, "https://opentelemetry.io/schemas/1.21.0", s[14])
+
+	h := byTable[histogramTableSuffix].values
+	assert.Equal(t, int64(3), h[10])
+	assert.Equal(t, float64(12), h[11])
+	assert.Equal(t, []int64{1, 2}, h[12])
+	assert.Equal(t, []float64{5}, h[13])
+	assert.Equal(t, float64(1), h[14])
+	assert.Nil(t, h[15])
+	assert.Equal(t, "https://opentelemetry.io/schemas/1.21.0", h[18])
+}
+
+func TestPushMetricsDataSummary(t *testing.T) {
+	md := pmetric.NewMetrics()
+	summary := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	summary.SetName("rpc.duration")
+	dp := summary.SetEmptySummary().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)))
+	dp.SetCount(10)
+	dp.SetSum(42.5)
+	median := dp.QuantileValues().AppendEmpty()
+	median.SetQuantile(0.5)
+	median.SetValue(3)
+	p99 := dp.QuantileValues().AppendEmpty()
+	p99.SetQuantile(0.99)
+	p99.SetValue(12.25)
+
+	session := &fakeSession{}
+	exp, err := newMetricsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig())
+	require.NoError(t, err)
+	exp.client = session
+	md.MarkReadOnly()
+	require.NoError(t, exp.pushMetricsData(context.Background(), md))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 1)
+	assert.True(t, strings.HasPrefix(stmts[0].stmt, "INSERT INTO otel.otel_metrics_summary ("), stmts[0].stmt)
+	values := stmts[0].values
+	require.Len(t, values, 16)
+	assert.Equal(t, "rpc.duration", values[3])
+	assert.Equal(t, time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC), values[9].(time.Time).UTC())
+	assert.Equal(t, int64(10), values[10])
+	assert.Equal(t, 42.5, values[11])
+	assert.Equal(t, []float64{0.5, 0.99}, values[12])
+	assert.Equal(t, []float64{3, 12.25}, values[13])
+	assert.Equal(t, uint32(0), values[14])
+}
+
+func TestPushMetricsDataExponentialHistogram(t *testing.T) {
+	md := pmetric.NewMetrics()
+	metric := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	metric.SetName("latency")
+	histogram := metric.SetEmptyExponentialHistogram()
+	histogram.SetAggregationTemporality(pmetric.AggregationTemporalityDelta)
+	dp := histogram.DataPoints().AppendEmpty()
+	dp.SetCount(10)
+	dp.SetSum(42.5)
+	dp.SetScale(-2)
+	dp.SetZeroCount(1)
+	dp.SetZeroThreshold(0.001)
+	dp.Positive().SetOffset(3)
+	dp.Positive().BucketCounts().FromRaw([]uint64{4, 2})
+	dp.Negative().SetOffset(-1)
+	dp.Negative().BucketCounts().FromRaw([]uint64{3})
+	dp.SetMax(20)
+
+	session := &fakeSession{}
+	exp, err := newMetricsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig())
+	require.NoError(t, err)
+	exp.client = session
+	md.MarkReadOnly()
+	require.NoError(t, exp.pushMetricsData(context.Background(), md))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 1)
+	assert.True(t, strings.HasPrefix(stmts[0].stmt, "INSERT INTO otel.otel_metrics_exponential_histogram ("), stmts[0].stmt)
+	values := stmts[0].values
+	assert.Equal(t, "latency", values[3])
+	assert.Equal(t, []any{
+		int64(10),
+		42.5,
+		int32(-2),
+		int64(1),
+		0.001,
+		int32(3),
+		[]int64{4, 2},
+		int32(-1),
+		[]int64{3},
+		nil,
+		float64(20),
+		uint32(0),
+		int32(pmetric.AggregationTemporalityDelta),
+		"",
+	}, values[10:])
+}
+
+func TestPushMetricsDataInstanceID(t *testing.T) {
+	md := pmetric.NewMetrics()
+	metric := md.ResourceMetrics().AppendEmpty().ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	metric.SetName("queue.size")
+	dp := metric.SetEmptyGauge().DataPoints().AppendEmpty()
+	dp.SetIntValue(42)
+	dp.Exemplars().AppendEmpty().SetIntValue(1)
+
+	session := &fakeSession{}
+	exp, err := newMetricsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig(func(config *Config) {
+		config.InstanceID = "collector-1"
+	}))
+	require.NoError(t, err)
+	exp.client = session
+	md.MarkReadOnly()
+	require.NoError(t, exp.pushMetricsData(context.Background(), md))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 2)
+	assert.Contains(t, stmts[0].stmt, ", resourceschemaurl, collectorid) VALUES (")
+	require.Len(t, stmts[0].values, 14)
+	assert.Equal(t, "collector-1", stmts[0].values[13])
+	// Exemplars belong to a data point, which carries the instance id.
+	assert.NotContains(t, stmts[1].stmt, "collectorid")
+	assert.Len(t, stmts[1].values, 9)
+}
+
+func TestParseCreateMetricTablesSQL(t *testing.T) {
+	stmts := parseCreateMetricTablesSQL(withDefaultConfig())
+	require.Len(t, stmts, 6)
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS otel.otel_metrics_exponential_histogram (ResourceAttributes map<text, text>, ScopeName text, ScopeVersion text, MetricName text, MetricDescription text, MetricUnit text, SeriesId text, Attributes map<text, text>, StartTimeUnix TimeStamp, TimeUnix TimeStamp, Count bigint, Sum double, Scale int, ZeroCount bigint, ZeroThreshold double, PositiveOffset int, PositiveBucketCounts list<bigint>, NegativeOffset int, NegativeBucketCounts list<bigint>, Min double, Max double, Flags int, AggregationTemporality int, ResourceSchemaUrl text, PRIMARY KEY ((MetricName, SeriesId), TimeUnix)) WITH COMPRESSION = {'class': 'LZ4Compressor'} AND compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': 1, 'compaction_window_unit': 'DAYS'} AND gc_grace_seconds = 864000",
+		stmts[3])
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS otel.otel_metrics_summary (ResourceAttributes map<text, text>, ScopeName text, ScopeVersion text, MetricName text, MetricDescription text, MetricUnit text, SeriesId text, Attributes map<text, text>, StartTimeUnix TimeStamp, TimeUnix TimeStamp, Count bigint, Sum double, Quantiles list<double>, QuantileValues list<double>, Flags int, ResourceSchemaUrl text, PRIMARY KEY ((MetricName, SeriesId), TimeUnix)) WITH COMPRESSION = {'class': 'LZ4Compressor'} AND compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': 1, 'compaction_window_unit': 'DAYS'} AND gc_grace_seconds = 864000",
+		stmts[4])
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS otel.otel_metrics_exemplars (MetricName text, SeriesId text, TimeUnix TimeStamp, ExemplarIndex int, ExemplarTimeUnix TimeStamp, Value double, TraceId text, SpanId text, FilteredAttributes map<text, text>, PRIMARY KEY ((MetricName, SeriesId), TimeUnix, ExemplarIndex)) WITH COMPRESSION = {'class': 'LZ4Compressor'} AND compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': 1, 'compaction_window_unit': 'DAYS'} AND gc_grace_seconds = 864000",
+		stmts[5])
+}
+
+func TestPushMetricsDataExemplars(t *testing.T) {
+	md := pmetric.NewMetrics()
+	rm := md.ResourceMetrics().AppendEmpty()
+	rm.Resource().Attributes().PutStr("service.name", "checkout")
+	metric := rm.ScopeMetrics().AppendEmpty().Metrics().AppendEmpty()
+	metric.SetName("requests")
+	dp := metric.SetEmptySum().DataPoints().AppendEmpty()
+	dp.SetTimestamp(pcommon.NewTimestampFromTime(time.Unix(1700000000, 0)))
+	dp.SetIntValue(7)
+
+	first := dp.Exemplars().AppendEmpty()
+	first.SetTimestamp(pcommon.NewTimestampFromTime(time.Unix(1699999990, 0)))
+	first.SetIntValue(3)
+	first.SetTraceID([16]byte{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	first.SetSpanID([8]byte{1, 2, 3, 4, 5, 6, 7, 8})
+	first.FilteredAttributes().PutStr("http.route", "/checkout")
+	second := dp.Exemplars().AppendEmpty()
+	second.SetTimestamp(pcommon.NewTimestampFromTime(time.Unix(1699999995, 0)))
+	second.SetDoubleValue(4.5)
+
+	session := &fakeSession{}
+	exp, err := newMetricsExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig())
+	require.NoError(t, err)
+	exp.client = session
+	md.MarkReadOnly()
+	require.NoError(t, exp.pushMetricsData(context.Background(), md))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 3)
+	assert.True(t, strings.HasPrefix(stmts[0].stmt, "INSERT INTO otel.otel_metrics_sum ("), stmts[0].stmt)
+	series := stmts[0].values[6]
+	for _, stmt := range stmts[1:] {
+		assert.True(t, strings.HasPrefix(stmt.stmt, "INSERT INTO otel.otel_metrics_exemplars ("), stmt.stmt)
+	}
+	assert.Equal(t, []any{
+		"requests",
+		series,
+		time.Unix(1700000000, 0).UTC(),
+		int32(0),
+		time.Unix(1699999990, 0).UTC(),
+		float64(3),
+		"0102030405060708090a0b0c0d0e0f10",
+		"0102030405060708",
+		map[string]string{"http.route": "/checkout"},
+	}, stmts[1].values)
+	assert.Equal(t, []any{
+		"requests",
+		series,
+		time.Unix(1700000000, 0).UTC(),
+		int32(1),
+		time.Unix(1699999995, 0).UTC(),
+		4.5,
+		"",
+		"",
+		map[string]string{},
+	}, stmts[2].values)
+}
+
+func TestSeriesID(t *testing.T) {
+	resAttr := map[string]string{"service.name": "checkout"}
+	a := seriesID(resAttr, map[string]string{"a": "1", "b": "2"})
+	assert.Equal(t, a, seriesID(resAttr, map[string]string{"b": "2", "a": "1"}))
+	assert.NotEqual(t, a, seriesID(resAttr, map[string]string{"a": "1"}))
+	assert.NotEqual(t, a, seriesID(map[string]string{"a": "1", "b": "2"}, nil))
+}
@@ -6,9 +6,11 @@ package cassandraexporter // import "github.com/open-telemetry/opentelemetry-col
 import (
 	"context"
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
 	"time"
 
-	"github.com/gocql/gocql"
 	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/pdata/ptrace"
 	"go.uber.org/zap"
@@ -16,55 +18,82 @@ import (
 	"github.com/open-telemetry/opentelemetry-collector-contrib/internal/coreinternal/traceutil"
 )
 
+// The events and links of the spans are stored in tables named after the
+// span table.
+const (
+	eventsTableSuffix = "_events"
+	linksTableSuffix  = "_links"
+)
+
+// w3cSampledFlag is the sampled bit of the W3C trace flags.
+const w3cSampledFlag = 0x01
+
+// zeroSpanID is the SpanId stored for the spans whose span id is all zeros.
+// SpanId is the partition key of the spans table and Cassandra rejects empty
+// partition keys, which would fail the whole batch of such a span.
+const zeroSpanID = "0000000000000000"
+
 type tracesExporter struct {
-	client *gocql.Session
-	logger *zap.Logger
-	cfg    *Config
-}
+	client         cqlSession
+	newSession     sessionFactory
+	borrowed       cqlSession
+	insertSQL      string
+	insertEventSQL string
+	insertLinkSQL  string
+	// insertResourceSQL writes the deduplicated resources.
+	insertResourceSQL string
 
-func newTracesExporter(logger *zap.Logger, cfg *Config) *tracesExporter {
-	return &tracesExporter{logger: logger, cfg: cfg}
+	pushes    inflightPushes
+	closing   sync.Once
+	telemetry *insertTelemetry
+	logger    *zap.Logger
+	cfg       *Config
 }
 
-func initializeTraceKernel(cfg *Config) error {
-	ctx := context.Background()
-	cluster, err := newCluster(cfg)
+func newTracesExporter(set component.TelemetrySettings, cfg *Config) (*tracesExporter, error) {
+	telemetry, err := newInsertTelemetry(set, signalTraces)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	cluster.Consistency = gocql.Quorum
-	cluster.Port = cfg.Port
-	cluster.Timeout = cfg.Timeout
+	cfg = cfg.forSignal(cfg.TracesKeyspace, cfg.TracesConsistency)
+	return &tracesExporter{
+		insertSQL:         parseInsertSQL(cfg, withAttributesTruncatedInsert(cfg, withInstanceIDInsert(cfg, insertSpanSQL)), cfg.TraceTable),
+		insertEventSQL:    parseInsertSQL(cfg, insertSpanEventSQL, suffixTable(cfg.TraceTable, eventsTableSuffix)),
+		insertLinkSQL:     parseInsertSQL(cfg, insertSpanLinkSQL, suffixTable(cfg.TraceTable, linksTableSuffix)),
+		insertResourceSQL: parseInsertSQL(cfg, insertResourceSQL, cfg.ResourcesTable),
+		logger:            set.Logger,
+		cfg:               cfg,
+		newSession:        createGocqlSession,
+		telemetry:         telemetry,
+	}, nil
+}
 
-	session, err := cluster.CreateSession()
-	if err != nil {
-		return err
+// initializeTraceKernel creates the keyspace, the span types and tables.
+func (e *tracesExporter) initializeTraceKernel(ctx context.Context, session cqlSession) error {
+	stmts := append(keyspaceSchema(e.cfg),
+		parseCreateLinksTypeSQL(e.cfg),
+		parseCreateEventsTypeSQL(e.cfg),
+		parseCreateSpanTableSQL(e.cfg),
+		parseCreateSpanEventsTableSQL(e.cfg),
+		parseCreateSpanLinksTableSQL(e.cfg),
+	)
+	if e.cfg.DeduplicateResources {
+		stmts = append(stmts, parseCreateResourceTableSQL(e.cfg))
 	}
+	return execSchema(ctx, session, stmts...)
+}
 
-	defer session.Close()
-
-	createDatabaseError := session.Query(parseCreateDatabaseSQL(cfg)).WithContext(ctx).Exec()
-	if createDatabaseError != nil {
-		return createDatabaseError
-	}
-	createLinksTypeError := session.Query(parseCreateLinksTypeSQL(cfg)).WithContext(ctx).Exec()
-	if createLinksTypeError != nil {
-		return createLinksTypeError
-	}
-	createEventsTypeError := session.Query(parseCreateEventsTypeSQL(cfg)).WithContext(ctx).Exec()
-	if createEventsTypeError != nil {
-		return createEventsTypeError
-	}
-	createSpanTableError := session.Query(parseCreateSpanTableSQL(cfg)).WithContext(ctx).Exec()
-	if createSpanTableError != nil {
-		return createSpanTableError
-	}
+func parseCreateSpanTableSQL(cfg *Config) string {
+	attrType := attributesColumnType(cfg)
+	return withAttributesTruncatedColumn(cfg, withInstanceIDColumn(cfg, fmt.Sprintf(createSpanTableSQL, cfg.Keyspace, cfg.TraceTable, attrType, attrType, parseTableOptions(cfg))))
+}
 
-	return nil
+func parseCreateSpanEventsTableSQL(cfg *Config) string {
+	return fmt.Sprintf(createSpanEventsTableSQL, cfg.Keyspace, suffixTable(cfg.TraceTable, eventsTableSuffix), attributesColumnType(cfg), parseTableOptions(cfg))
 }
 
-func parseCreateSpanTableSQL(cfg *Config) string {
-	return fmt.Sprintf(createSpanTableSQL, cfg.Keyspace, cfg.TraceTable, cfg.Compression.Algorithm)
+func parseCreateSpanLinksTableSQL(cfg *Config) string {
+	return fmt.Sprintf(createSpanLinksTableSQL, cfg.Keyspace, suffixTable(cfg.TraceTable, linksTableSuffix), attributesColumnType(cfg), parseTableOptions(cfg))
 }
 
 func parseCreateEventsTypeSQL(cfg *Config) string {
@@ -76,74 +105,264 @@ func parseCreateLinksTypeSQL(cfg *Config) string {
 }
 
 func parseCreateDatabaseSQL(cfg *Config) string {
-	return fmt.Sprintf(createDatabaseSQL, cfg.Keyspace, cfg.Replication.Class, cfg.Replication.ReplicationFactor)
+	var durableWrites string
+	if cfg.DurableWrites != nil {
+		durableWrites = fmt.Sprintf(" AND durable_writes = %t", *cfg.DurableWrites)
+	}
+	return fmt.Sprintf(createDatabaseSQL, cfg.Keyspace, parseReplicationOptions(cfg.Replication), durableWrites)
 }
 
-func (e *tracesExporter) Start(_ context.Context, _ component.Host) error {
-	cluster, err := newCluster(e.cfg)
-	if err != nil {
-		return err
+// parseReplicationOptions renders the replication map of the keyspace. The
+// NetworkTopologyStrategy takes a factor per datacenter, every other strategy
+// a single replication_factor.
+func parseReplicationOptions(replication Replication) string {
+	options := []string{fmt.Sprintf("'class' : '%s'", replication.Class)}
+	if replication.Class != networkTopologyStrategy {
+		options = append(options, fmt.Sprintf("'replication_factor' : %d", replication.ReplicationFactor))
+		return strings.Join(options, ", ")
+	}
+	dataCenters := make([]string, 0, len(replication.DataCenters))
+	for dc := range replication.DataCenters {
+		dataCenters = append(dataCenters, dc)
+	}
+	sort.Strings(dataCenters)
+	for _, dc := range dataCenters {
+		options = append(options, fmt.Sprintf("'%s' : %d", dc, replication.DataCenters[dc]))
+	}
+	return strings.Join(options, ", ")
+}
+
+// parseTableOptions renders the WITH clause shared by every table the exporter
+// creates.
+func parseTableOptions(cfg *Config) string {
+	options := fmt.Sprintf("COMPRESSION = {%s} AND compaction = {%s} AND gc_grace_seconds = %d",
+		parseCompressionOptions(cfg.Compression), parseCompactionOptions(cfg.Compaction), cfg.GCGraceSeconds)
+	if seconds := int64(cfg.DefaultTTL / time.Second); seconds > 0 {
+		options += fmt.Sprintf(" AND default_time_to_live = %d", seconds)
+	}
+	if cfg.SpeculativeRetry != "" {
+		options += fmt.Sprintf(" AND speculative_retry = '%s'", cfg.SpeculativeRetry)
+	}
+	return options
+}
+
+// parseCompactionOptions renders the compaction map of a table, the window
+// only applies to the TimeWindowCompactionStrategy.
+func parseCompactionOptions(compaction Compaction) string {
+	options := fmt.Sprintf("'class': '%s'", compaction.Strategy)
+	if compaction.Strategy == timeWindowCompactionStrategy {
+		options += fmt.Sprintf(", 'compaction_window_size': %d, 'compaction_window_unit': '%s'",
+			compaction.CompactionWindowSize, compaction.CompactionWindowUnit)
+	}
+	return options
+}
+
+// parseCompressionOptions renders the compression map of a table, an empty
+// algorithm disables compression.
+func parseCompressionOptions(compression Compression) string {
+	if compression.Algorithm == "" {
+		return "'enabled': 'false'"
+	}
+	options := fmt.Sprintf("'class': '%s'", compression.Algorithm)
+	if compression.ChunkLength > 0 {
+		options += fmt.Sprintf(", 'chunk_length_in_kb': %d", compression.ChunkLength)
+	}
+	return options
+}
+
+// parseInsertSQL renders an insert template for the given table, appending the
+// USING TTL clause when rows are configured to expire.
+func parseInsertSQL(cfg *Config, insertSQL string, table string) string {
+	query := fmt.Sprintf(insertSQL, cfg.Keyspace, table)
+	if seconds := int64(cfg.TTL / time.Second); seconds > 0 {
+		query += fmt.Sprintf(" USING TTL %d", seconds)
+	}
+	if cfg.QueryComment != "" {
+		// A line comment ends at the line break, which Validate keeps out
+		// of the comment itself.
+		query = "-- " + cfg.QueryComment + "\n" + query
+	}
+	return query
+}
+
+// withWriteTimestamp adds a bind marker for the write timestamp, in
+// microseconds, to an insert rendered by parseInsertSQL. The timestamp is
+// bound after the values of the row.
+func withWriteTimestamp(cfg *Config, query string) string {
+	if int64(cfg.TTL/time.Second) > 0 {
+		return query + " AND TIMESTAMP ?"
 	}
-	cluster.Keyspace = e.cfg.Keyspace
-	cluster.Consistency = gocql.Quorum
-	cluster.Port = e.cfg.Port
-	cluster.Timeout = e.cfg.Timeout
+	return query + " USING TIMESTAMP ?"
+}
 
-	session, err := cluster.CreateSession()
+func (e *tracesExporter) Start(ctx context.Context, _ component.Host) error {
+	session, err := startSession(ctx, e.cfg, e.logger, e.telemetry, e.newSession, e.borrowed, e.initializeTraceKernel)
 	if err != nil {
 		return err
 	}
 	e.client = session
-	initializeErr := initializeTraceKernel(e.cfg)
-	return initializeErr
+	return nil
 }
 
-func (e *tracesExporter) Shutdown(_ context.Context) error {
-	if e.client != nil {
-		e.client.Close()
-	}
+func (e *tracesExporter) Shutdown(ctx context.Context) error {
+	// Let the running pushes write their batches before the session goes
+	// away, new pushes are rejected from now on.
+	drained, err := e.pushes.drain(ctx, e.cfg.ShutdownTimeout)
+	e.closing.Do(func() {
+		if e.client != nil {
+			e.client.Close()
+		}
+		e.logger.Info("cassandra exporter shut down", zap.String("signal", signalTraces),
+			zap.Int("drained_pushes", drained))
+	})
 
-	return nil
+	return err
 }
 
 func (e *tracesExporter) pushTraceData(ctx context.Context, td ptrace.Traces) error {
+	if err := e.pushes.start(); err != nil {
+		return err
+	}
+	defer e.pushes.done()
+	if e.client == nil {
+		return errSessionNotOpen
+	}
+
 	start := time.Now()
 
+	var errs insertErrors
+	var invalid int
+	resources := newResourceRows(e.cfg, e.insertResourceSQL)
 	for i := 0; i < td.ResourceSpans().Len(); i++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
 		spans := td.ResourceSpans().At(i)
 		res := spans.Resource()
-		resAttr := attributesToMap(res.Attributes().AsRaw())
+		stmts, resID, resAttr := resources.columns(nil, res.Attributes())
+		serviceName := resourceServiceName(res)
 
 		for j := 0; j < spans.ScopeSpans().Len(); j++ {
+			scope := spans.ScopeSpans().At(j).Scope()
 			rs := spans.ScopeSpans().At(j).Spans()
 			for k := 0; k < rs.Len(); k++ {
 				r := rs.At(k)
-				spanAttr := attributesToMap(r.Attributes().AsRaw())
+				if r.TraceID().IsEmpty() || r.SpanID().IsEmpty() {
+					invalid++
+					if e.cfg.InvalidSpanIDs == invalidSpanIDsDrop {
+						continue
+					}
+				}
+				attributes, droppedAttributes := limitAttributes(r.Attributes(), e.cfg.MaxAttributes)
+				if droppedAttributes > 0 {
+					e.telemetry.recordTruncatedAttributes(ctx, droppedAttributes)
+				}
+				spanAttr := encodeAttributes(attributes, e.cfg)
 				status := r.Status()
 
-				insertSpanError := e.client.Query(fmt.Sprintf(insertSpanSQL, e.cfg.Keyspace, e.cfg.TraceTable), r.StartTimestamp().AsTime(),
-					traceutil.TraceIDToHexOrEmptyString(r.TraceID()),
-					traceutil.SpanIDToHexOrEmptyString(r.SpanID()),
-					traceutil.SpanIDToHexOrEmptyString(r.ParentSpanID()),
-					r.TraceState().AsRaw(),
-					r.Name(),
-					traceutil.SpanKindStr(r.Kind()),
-					resAttr,
-					spanAttr,
-					r.EndTimestamp().AsTime().Sub(r.StartTimestamp().AsTime()).Nanoseconds(),
-					traceutil.StatusCodeStr(status.Code()),
-					status.Message(),
-				).WithContext(ctx).Exec()
-
-				if insertSpanError != nil {
-					e.logger.Error("insert span error", zap.Error(insertSpanError))
+				traceID := traceutil.TraceIDToHexOrEmptyString(r.TraceID())
+				spanID := traceutil.SpanIDToHexOrEmptyString(r.SpanID())
+				if spanID == "" {
+					spanID = zeroSpanID
 				}
+				stmts = append(stmts, statement{
+					partitionKey: spanID,
+					query:        e.insertSQL,
+					args: appendAttributesTruncated(e.cfg, appendInstanceID(e.cfg, []any{
+						r.StartTimestamp().AsTime(),
+						traceID,
+						spanID,
+						traceutil.SpanIDToHexOrEmptyString(r.ParentSpanID()),
+						r.TraceState().AsRaw(),
+						r.Name(),
+						traceutil.SpanKindStr(r.Kind()),
+						resAttr,
+						spanAttr,
+						r.EndTimestamp().AsTime().Sub(r.StartTimestamp().AsTime()).Nanoseconds(),
+						traceutil.StatusCodeStr(status.Code()),
+						status.Message(),
+						scope.Name(),
+						scope.Version(),
+						spans.SchemaUrl(),
+						r.DroppedAttributesCount(),
+						scope.DroppedAttributesCount(),
+						res.DroppedAttributesCount(),
+						resID,
+						r.Flags(),
+						spanSampled(r.Flags()),
+					}), droppedAttributes),
+					timestamp: r.StartTimestamp().AsTime(),
+					service:   serviceName,
+				})
+				stmts = e.appendEventsAndLinks(stmts, r, traceID, spanID, serviceName)
 			}
 		}
+
+		executeBatches(ctx, e.client, e.cfg, stmts, &errs, e.telemetry, e.logger)
+	}
+
+	if invalid > 0 {
+		// Missing ids usually come from broken instrumentation.
+		e.telemetry.recordInvalidSpans(ctx, invalid)
+		e.logger.Warn("spans with an all-zero trace or span id", zap.Int("spans", invalid),
+			zap.Bool("dropped", e.cfg.InvalidSpanIDs == invalidSpanIDsDrop))
 	}
 
 	duration := time.Since(start)
 	e.logger.Debug("insert traces", zap.Int("records", td.SpanCount()),
 		zap.String("cost", duration.String()))
+	if err := errs.err(); err != nil {
+		return fmt.Errorf("insert spans: %w", err)
+	}
 	return nil
 }
+
+// spanSampled reports whether the sampled bit of the W3C trace flags, held in
+// the lowest byte of the span flags, is set.
+func spanSampled(flags uint32) bool {
+	return flags&w3cSampledFlag != 0
+}
+
+// appendEventsAndLinks adds a row per event and link of the span, keyed by the
+// trace and span id so that the events and links of a span share a partition.
+func (e *tracesExporter) appendEventsAndLinks(stmts []statement, span ptrace.Span, traceID, spanID, serviceName string) []statement {
+	partitionKey := traceID + "/" + spanID
+	for i := 0; i < span.Events().Len(); i++ {
+		event := span.Events().At(i)
+		stmts = append(stmts, statement{
+			partitionKey: partitionKey,
+			query:        e.insertEventSQL,
+			args: []any{
+				traceID,
+				spanID,
+				int32(i),
+				event.Timestamp().AsTime(),
+				event.Name(),
+				encodeAttributes(event.Attributes(), e.cfg),
+			},
+			timestamp: event.Timestamp().AsTime(),
+			service:   serviceName,
+		})
+	}
+	for i := 0; i < span.Links().Len(); i++ {
+		link := span.Links().At(i)
+		stmts = append(stmts, statement{
+			partitionKey: partitionKey,
+			query:        e.insertLinkSQL,
+			args: []any{
+				traceID,
+				spanID,
+				int32(i),
+				traceutil.TraceIDToHexOrEmptyString(link.TraceID()),
+				traceutil.SpanIDToHexOrEmptyString(link.SpanID()),
+				link.TraceState().AsRaw(),
+				encodeAttributes(link.Attributes(), e.cfg),
+			},
+			timestamp: span.StartTimestamp().AsTime(),
+			service:   serviceName,
+		})
+	}
+	return stmts
+}
@@ -0,0 +1,430 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata/metricdatatest"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestPushTraceData(t *testing.T) {
+	start := time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)
+	td := ptrace.NewTraces()
+	rs := td.ResourceSpans().AppendEmpty()
+	rs.SetSchemaUrl("https://opentelemetry.io/schemas/1.21.0")
+	rs.Resource().SetDroppedAttributesCount(1)
+	ss := rs.ScopeSpans().AppendEmpty()
+	ss.Scope().SetName("io.opentelemetry.http")
+	ss.Scope().SetVersion("1.2.0")
+	span := ss.Spans().AppendEmpty()
+	span.SetTraceID(pcommon.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	span.SetSpanID(pcommon.SpanID{1, 2, 3, 4, 5, 6, 7, 8})
+	span.SetName("GET /checkout")
+	span.SetDroppedAttributesCount(3)
+	span.SetKind(ptrace.SpanKindServer)
+	span.SetStartTimestamp(pcommon.NewTimestampFromTime(start))
+	span.SetEndTimestamp(pcommon.NewTimestampFromTime(start.Add(250 * time.Millisecond)))
+	span.Status().SetCode(ptrace.StatusCodeError)
+	span.Status().SetMessage("upstream unavailable")
+
+	session := &fakeSession{}
+	exp, err := newTracesExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig())
+	require.NoError(t, err)
+	exp.client = session
+	td.MarkReadOnly()
+	require.NoError(t, exp.pushTraceData(context.Background(), td))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 1)
+	values := stmts[0].values
+	assert.Equal(t, "0102030405060708090a0b0c0d0e0f10", values[1])
+	assert.Equal(t, "0102030405060708", values[2])
+	assert.Equal(t, "GET /checkout", values[5])
+	assert.Equal(t, "SPAN_KIND_SERVER", values[6])
+	assert.Equal(t, (250 * time.Millisecond).Nanoseconds(), values[9])
+	assert.Equal(t, "STATUS_CODE_ERROR", values[10])
+	assert.Equal(t, "upstream unavailable", values[11])
+	assert.Equal(t, "io.opentelemetry.http", values[12])
+	assert.Equal(t, "1.2.0", values[13])
+	assert.Equal(t, "https://opentelemetry.io/schemas/1.21.0", values[14])
+	assert.Equal(t, uint32(3), values[15])
+	assert.Equal(t, uint32(0), values[16])
+	assert.Equal(t, uint32(1), values[17])
+
+	// The duration of a span longer than about 2.1s overflows an int column.
+	assert.Contains(t, parseCreateSpanTableSQL(withDefaultConfig()), ", Duration bigint, ")
+	_, err = gocql.Marshal(gocql.NewNativeType(4, gocql.TypeBigInt, ""), (3 * time.Second).Nanoseconds())
+	require.NoError(t, err)
+	_, err = gocql.Marshal(gocql.NewNativeType(4, gocql.TypeInt, ""), (3 * time.Second).Nanoseconds())
+	require.Error(t, err)
+}
+
+func TestPushTraceDataSampled(t *testing.T) {
+	td := ptrace.NewTraces()
+	spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+	sampled := spans.AppendEmpty()
+	sampled.SetSpanID(pcommon.SpanID{1})
+	sampled.SetFlags(0x0301)
+	unsampled := spans.AppendEmpty()
+	unsampled.SetSpanID(pcommon.SpanID{2})
+	unsampled.SetFlags(0x0300)
+
+	session := &fakeSession{}
+	exp, err := newTracesExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig())
+	require.NoError(t, err)
+	exp.client = session
+	td.MarkReadOnly()
+	require.NoError(t, exp.pushTraceData(context.Background(), td))
+
+	flags := map[any][]any{}
+	for _, stmt := range session.statements() {
+		flags[stmt.values[2]] = stmt.values[19:21]
+	}
+	assert.Equal(t, map[any][]any{
+		"0100000000000000": {uint32(0x0301), true},
+		"0200000000000000": {uint32(0x0300), false},
+	}, flags)
+}
+
+func TestPushTraceDataInvalidSpanIDs(t *testing.T) {
+	for _, mode := range []string{invalidSpanIDsStore, invalidSpanIDsDrop} {
+		t.Run(mode, func(t *testing.T) {
+			td := ptrace.NewTraces()
+			spans := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans()
+			valid := spans.AppendEmpty()
+			valid.SetTraceID(pcommon.TraceID{1})
+			valid.SetSpanID(pcommon.SpanID{1})
+			spans.AppendEmpty().SetSpanID(pcommon.SpanID{2})
+			spans.AppendEmpty().SetTraceID(pcommon.TraceID{3})
+			td.MarkReadOnly()
+
+			tel := setupTestTelemetry()
+			core, logs := observer.New(zapcore.WarnLevel)
+			set := tel.NewSettings().TelemetrySettings
+			set.Logger = zap.New(core)
+			exp, err := newTracesExporter(set, withDefaultConfig(func(config *Config) {
+				config.InvalidSpanIDs = mode
+			}))
+			require.NoError(t, err)
+			session := &fakeSession{}
+			exp.client = session
+			require.NoError(t, exp.pushTraceData(context.Background(), td))
+
+			if mode == invalidSpanIDsDrop {
+				stmts := session.statements()
+				require.Len(t, stmts, 1)
+				assert.Equal(t, "01000000000000000000000000000000", stmts[0].values[1])
+			} else {
+				traceIDs := map[any]any{}
+				for _, stmt := range session.statements() {
+					traceIDs[stmt.values[2]] = stmt.values[1]
+				}
+				// Cassandra rejects the batch of a span with an empty
+				// partition key.
+				assert.Equal(t, map[any]any{
+					"0100000000000000": "01000000000000000000000000000000",
+					"0200000000000000": "",
+					zeroSpanID:         "03000000000000000000000000000000",
+				}, traceIDs)
+			}
+			warnings := logs.FilterMessage("spans with an all-zero trace or span id").All()
+			require.Len(t, warnings, 1)
+			assert.Equal(t, map[string]any{"spans": int64(2), "dropped": mode == invalidSpanIDsDrop}, warnings[0].ContextMap())
+
+			var md metricdata.ResourceMetrics
+			require.NoError(t, tel.reader.Collect(context.Background(), &md))
+			metricdatatest.AssertEqual(t, metricdata.Metrics{
+				Name:        "otelcol_cassandra_exporter_invalid_spans",
+				Description: "Number of spans with an all-zero trace or span id, stored or dropped as configured by invalid_span_ids.",
+				Unit:        "{spans}",
+				Data: metricdata.Sum[int64]{
+					Temporality: metricdata.CumulativeTemporality,
+					IsMonotonic: true,
+					DataPoints: []metricdata.DataPoint[int64]{{
+						Attributes: attribute.NewSet(attribute.String("signal", "traces")),
+						Value:      2,
+					}},
+				},
+			}, tel.getMetric("otelcol_cassandra_exporter_invalid_spans", md), metricdatatest.IgnoreTimestamp())
+			require.NoError(t, tel.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestPushTraceDataMaxAttributes(t *testing.T) {
+	td := ptrace.NewTraces()
+	span := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetTraceID(pcommon.TraceID{1})
+	span.SetSpanID(pcommon.SpanID{1})
+	span.Attributes().PutStr("b", "2")
+	span.Attributes().PutStr("a", "1")
+
+	session := &fakeSession{}
+	exp, err := newTracesExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig(func(config *Config) {
+		config.MaxAttributes = 1
+		config.InstanceID = "collector-1"
+	}))
+	require.NoError(t, err)
+	assert.Contains(t, exp.insertSQL, ", sampled, collectorid, attributestruncated) VALUES (")
+	exp.client = session
+	require.NoError(t, exp.pushTraceData(context.Background(), td))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 1)
+	require.Len(t, stmts[0].values, 23)
+	assert.Equal(t, map[string]string{"a": "1"}, stmts[0].values[8])
+	assert.Equal(t, "collector-1", stmts[0].values[21])
+	assert.Equal(t, true, stmts[0].values[22])
+}
+
+func TestPushTraceDataInstanceID(t *testing.T) {
+	td := ptrace.NewTraces()
+	span := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetSpanID(pcommon.SpanID{1})
+	span.Events().AppendEmpty().SetName("retry")
+
+	session := &fakeSession{}
+	exp, err := newTracesExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig(func(config *Config) {
+		config.InstanceID = "collector-1"
+	}))
+	require.NoError(t, err)
+	exp.client = session
+	td.MarkReadOnly()
+	require.NoError(t, exp.pushTraceData(context.Background(), td))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 2)
+	for _, stmt := range stmts {
+		if !strings.HasPrefix(stmt.stmt, "INSERT INTO otel.otel_spans (") {
+			assert.NotContains(t, stmt.stmt, "collectorid")
+			continue
+		}
+		assert.Contains(t, stmt.stmt, ", sampled, collectorid) VALUES (")
+		require.Len(t, stmt.values, 22)
+		assert.Equal(t, "collector-1", stmt.values[21])
+	}
+}
+
+func TestPushTraceDataEventsAndLinks(t *testing.T) {
+	start := time.Date(2024, 9, 1, 12, 0, 0, 0, time.UTC)
+	td := ptrace.NewTraces()
+	span := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetTraceID(pcommon.TraceID{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16})
+	span.SetSpanID(pcommon.SpanID{1, 2, 3, 4, 5, 6, 7, 8})
+	retry := span.Events().AppendEmpty()
+	retry.SetName("retry")
+	retry.SetTimestamp(pcommon.NewTimestampFromTime(start))
+	retry.Attributes().PutInt("attempt", 2)
+	exception := span.Events().AppendEmpty()
+	exception.SetName("exception")
+	exception.SetTimestamp(pcommon.NewTimestampFromTime(start.Add(time.Second)))
+	link := span.Links().AppendEmpty()
+	link.SetTraceID(pcommon.TraceID{16, 15, 14, 13, 12, 11, 10, 9, 8, 7, 6, 5, 4, 3, 2, 1})
+	link.SetSpanID(pcommon.SpanID{8, 7, 6, 5, 4, 3, 2, 1})
+	link.TraceState().FromRaw("vendor=1")
+	link.Attributes().PutStr("link.kind", "follows_from")
+
+	session := &fakeSession{}
+	exp, err := newTracesExporter(componenttest.NewNopTelemetrySettings(), withDefaultConfig())
+	require.NoError(t, err)
+	exp.client = session
+	require.NoError(t, exp.pushTraceData(context.Background(), td))
+
+	var events, links [][]any
+	for _, stmt := range session.statements() {
+		switch {
+		case strings.HasPrefix(stmt.stmt, "INSERT INTO otel.otel_spans_events "):
+			events = append(events, stmt.values)
+		case strings.HasPrefix(stmt.stmt, "INSERT INTO otel.otel_spans_links "):
+			links = append(links, stmt.values)
+		}
+	}
+	traceID, spanID := "0102030405060708090a0b0c0d0e0f10", "0102030405060708"
+	assert.Equal(t, [][]any{
+		{traceID, spanID, int32(0), start, "retry", map[string]string{"attempt": "2"}},
+		{traceID, spanID, int32(1), start.Add(time.Second), "exception", map[string]string{}},
+	}, events)
+	assert.Equal(t, [][]any{
+		{traceID, spanID, int32(0), "100f0e0d0c0b0a090807060504030201", "0807060504030201", "vendor=1", map[string]string{"link.kind": "follows_from"}},
+	}, links)
+}
+
+func TestParseCreateSpanEventsAndLinksTableSQL(t *testing.T) {
+	cfg := withDefaultConfig()
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS otel.otel_spans_events (TraceId text, SpanId text, EventIndex int, Timestamp TimeStamp, Name text, Attributes map<text, text>, PRIMARY KEY ((TraceId, SpanId), EventIndex)) WITH COMPRESSION = {'class': 'LZ4Compressor'} AND compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': 1, 'compaction_window_unit': 'DAYS'} AND gc_grace_seconds = 864000",
+		parseCreateSpanEventsTableSQL(cfg))
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS otel.otel_spans_links (TraceId text, SpanId text, LinkIndex int, LinkedTraceId text, LinkedSpanId text, TraceState text, Attributes map<text, text>, PRIMARY KEY ((TraceId, SpanId), LinkIndex)) WITH COMPRESSION = {'class': 'LZ4Compressor'} AND compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': 1, 'compaction_window_unit': 'DAYS'} AND gc_grace_seconds = 864000",
+		parseCreateSpanLinksTableSQL(cfg))
+}
+
+func TestParseCreateSpanTablesSQLAttributesFormat(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.AttributesFormat = attributesFormatJSON
+	})
+	assert.Contains(t, parseCreateSpanTableSQL(cfg), "ResourceAttributes text, SpanAttributes text,")
+	assert.Contains(t, parseCreateSpanEventsTableSQL(cfg), "Attributes text,")
+	assert.Contains(t, parseCreateSpanLinksTableSQL(cfg), "Attributes text,")
+	stmts := parseCreateMetricTablesSQL(cfg)
+	for _, ddl := range stmts[:len(stmts)-1] {
+		assert.Contains(t, ddl, "(ResourceAttributes text,")
+		assert.NotContains(t, ddl, "map<")
+	}
+	assert.Contains(t, stmts[len(stmts)-1], "FilteredAttributes text,")
+}
+
+func TestParseCreateDatabaseSQL(t *testing.T) {
+	cfg := withDefaultConfig()
+	assert.Equal(t, "CREATE KEYSPACE IF NOT EXISTS otel WITH REPLICATION = { 'class' : 'SimpleStrategy', 'replication_factor' : 1 };",
+		parseCreateDatabaseSQL(cfg))
+
+	cfg.Replication.Class = "NetworkTopologyStrategy"
+	cfg.Replication.DataCenters = map[string]int{"eu-west": 3, "us-east": 2}
+	assert.Equal(t, "CREATE KEYSPACE IF NOT EXISTS otel WITH REPLICATION = { 'class' : 'NetworkTopologyStrategy', 'eu-west' : 3, 'us-east' : 2 };",
+		parseCreateDatabaseSQL(cfg))
+}
+
+func TestParseCreateDatabaseSQLDurableWrites(t *testing.T) {
+	for _, durableWrites := range []bool{false, true} {
+		cfg := withDefaultConfig(func(config *Config) {
+			config.DurableWrites = &durableWrites
+		})
+		assert.Equal(t, fmt.Sprintf("CREATE KEYSPACE IF NOT EXISTS otel WITH REPLICATION = { 'class' : 'SimpleStrategy', 'replication_factor' : 1 } AND durable_writes = %t;", durableWrites),
+			parseCreateDatabaseSQL(cfg))
+	}
+}
+
+func TestParseCreateTableSQLCompression(t *testing.T) {
+	cfg := withDefaultConfig()
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS otel.otel_logs (TimeStamp TimeStamp, TraceId text, SpanId text, TraceFlags int, SeverityText text, SeverityNumber int, ServiceName text, Body text, ResourceAttributes map<text, text>, LogAttributes map<text, text>, DateBucket TimeStamp, ScopeName text, ScopeVersion text, ResourceSchemaUrl text, DroppedAttributesCount int, ScopeDroppedAttributesCount int, ResourceDroppedAttributesCount int, BodyTruncated boolean, ResourceId text, Sampled boolean, RecordId text, PRIMARY KEY ((ServiceName, DateBucket), TimeStamp, SpanId, SeverityNumber, RecordId)) WITH CLUSTERING ORDER BY (TimeStamp ASC) AND COMPRESSION = {'class': 'LZ4Compressor'} AND compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': 1, 'compaction_window_unit': 'DAYS'} AND gc_grace_seconds = 864000",
+		parseCreateLogTableSQL(cfg))
+
+	cfg.Compression = Compression{Algorithm: "ZstdCompressor", ChunkLength: 64}
+	assert.Equal(t, "'class': 'ZstdCompressor', 'chunk_length_in_kb': 64", parseCompressionOptions(cfg.Compression))
+	assert.Contains(t, parseCreateSpanTableSQL(cfg), "WITH COMPRESSION = {'class': 'ZstdCompressor', 'chunk_length_in_kb': 64}")
+	assert.Contains(t, parseCreateLogTableSQL(cfg), "AND COMPRESSION = {'class': 'ZstdCompressor', 'chunk_length_in_kb': 64}")
+
+	cfg.Compression = Compression{}
+	for _, createTableSQL := range parseCreateMetricTablesSQL(cfg) {
+		assert.Contains(t, createTableSQL, "WITH COMPRESSION = {'enabled': 'false'} AND ")
+	}
+}
+
+func TestParseTableOptions(t *testing.T) {
+	cfg := withDefaultConfig()
+	cfg.Compaction = Compaction{Strategy: "LeveledCompactionStrategy", CompactionWindowSize: 1, CompactionWindowUnit: "DAYS"}
+	cfg.GCGraceSeconds = 3600
+	assert.Equal(t, "COMPRESSION = {'class': 'LZ4Compressor'} AND compaction = {'class': 'LeveledCompactionStrategy'} AND gc_grace_seconds = 3600",
+		parseTableOptions(cfg))
+
+	cfg.Compaction = Compaction{Strategy: "TimeWindowCompactionStrategy", CompactionWindowSize: 6, CompactionWindowUnit: "HOURS"}
+	assert.Contains(t, parseCreateLogTableSQL(cfg),
+		"AND compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': 6, 'compaction_window_unit': 'HOURS'} AND gc_grace_seconds = 3600")
+}
+
+func TestParseTableOptionsSpeculativeRetry(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.SpeculativeRetry = "99PERCENTILE"
+	})
+	assert.True(t, strings.HasSuffix(parseTableOptions(cfg), " AND gc_grace_seconds = 864000 AND speculative_retry = '99PERCENTILE'"))
+	assert.True(t, strings.HasSuffix(parseCreateLogTableSQL(cfg), " AND speculative_retry = '99PERCENTILE'"))
+	for _, createTableSQL := range parseCreateMetricTablesSQL(cfg) {
+		assert.True(t, strings.HasSuffix(createTableSQL, " AND speculative_retry = '99PERCENTILE'"), createTableSQL)
+	}
+
+	for _, value := range []string{"NONE", "always", "50ms", "99.9PERCENTILE", "99p", "MIN(99p,50ms)"} {
+		assert.True(t, speculativeRetryPattern.MatchString(value), value)
+	}
+	for _, value := range []string{"99", "PERCENTILE", "50 ms", "MIN(99p)"} {
+		assert.False(t, speculativeRetryPattern.MatchString(value), value)
+	}
+}
+
+func TestParseTableOptionsDefaultTTL(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.DefaultTTL = 72*time.Hour + 500*time.Millisecond
+	})
+	assert.True(t, strings.HasSuffix(parseTableOptions(cfg), " AND gc_grace_seconds = 864000 AND default_time_to_live = 259200"))
+	assert.True(t, strings.HasSuffix(parseCreateLogTableSQL(cfg), "AND default_time_to_live = 259200"))
+	assert.True(t, strings.HasSuffix(parseCreateSpanTableSQL(cfg), "AND default_time_to_live = 259200"))
+	for _, createTableSQL := range parseCreateMetricTablesSQL(cfg) {
+		assert.True(t, strings.HasSuffix(createTableSQL, "AND default_time_to_live = 259200"), createTableSQL)
+	}
+}
+
+func TestMixedCaseIdentifiers(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.LogsTable = "MyLogs"
+		config.TraceTable = "Spans"
+		config.MetricsTable = "Metrics"
+	})
+	set := componenttest.NewNopTelemetrySettings()
+
+	logs, err := newLogsExporter(set, cfg)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(logs.insertSQL, `INSERT INTO otel."MyLogs" (`), logs.insertSQL)
+	assert.True(t, strings.HasPrefix(parseCreateLogTableSQL(logs.cfg), `CREATE TABLE IF NOT EXISTS otel."MyLogs" (`))
+
+	traces, err := newTracesExporter(set, withDefaultConfig(func(config *Config) {
+		config.Keyspace = "Telemetry"
+		config.TraceTable = "Spans"
+	}))
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(traces.insertSQL, `INSERT INTO "Telemetry"."Spans" (`), traces.insertSQL)
+	assert.True(t, strings.HasPrefix(traces.insertEventSQL, `INSERT INTO "Telemetry"."Spans_events" (`), traces.insertEventSQL)
+	assert.True(t, strings.HasPrefix(parseCreateDatabaseSQL(traces.cfg), `CREATE KEYSPACE IF NOT EXISTS "Telemetry" `))
+	assert.True(t, strings.HasPrefix(parseCreateSpanTableSQL(traces.cfg), `CREATE TABLE IF NOT EXISTS "Telemetry"."Spans" (`))
+
+	metrics, err := newMetricsExporter(set, cfg)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(metrics.insertGaugeSQL, `INSERT INTO otel."Metrics_gauge" (`), metrics.insertGaugeSQL)
+}
+
+func TestParseInsertSQL(t *testing.T) {
+	cfg := withDefaultConfig()
+	assert.Equal(t, "INSERT INTO otel.otel_spans (timestamp, traceid, spanid, parentspanid, tracestate, spanname, spankind, resourceattributes, spanattributes, duration, statuscode, statusmessage, scopename, scopeversion, resourceschemaurl, droppedattributescount, scopedroppedattributescount, resourcedroppedattributescount, resourceid, spanflags, sampled) VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)",
+		parseInsertSQL(cfg, insertSpanSQL, cfg.TraceTable))
+
+	cfg.TTL = 72 * time.Hour
+	assert.Equal(t, "INSERT INTO otel.otel_logs (timestamp, traceid, spanid, traceflags, severitytext, severitynumber, servicename, body, resourceattributes, logattributes, datebucket, scopename, scopeversion, resourceschemaurl, droppedattributescount, scopedroppedattributescount, resourcedroppedattributescount, bodytruncated, resourceid, sampled, recordid) VALUES(?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?) USING TTL 259200",
+		parseInsertSQL(cfg, insertLogTableSQL, cfg.LogsTable))
+}
+
+func TestParseInsertSQLQueryComment(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.QueryComment = "tenant=acme"
+		config.TTL = time.Hour
+	})
+	assert.Equal(t, "-- tenant=acme\nINSERT INTO otel.otel_spans_events (traceid, spanid, eventindex, timestamp, name, attributes) VALUES (?, ?, ?, ?, ?, ?) USING TTL 3600",
+		parseInsertSQL(cfg, insertSpanEventSQL, cfg.TraceTable+"_events"))
+
+	session := &fakeSession{}
+	exp, err := newTracesExporter(componenttest.NewNopTelemetrySettings(), cfg)
+	require.NoError(t, err)
+	exp.client = session
+	td := ptrace.NewTraces()
+	span := td.ResourceSpans().AppendEmpty().ScopeSpans().AppendEmpty().Spans().AppendEmpty()
+	span.SetTraceID([16]byte{1})
+	span.SetSpanID([8]byte{1})
+	td.MarkReadOnly()
+	require.NoError(t, exp.pushTraceData(context.Background(), td))
+	stmts := session.statements()
+	require.Len(t, stmts, 1)
+	assert.True(t, strings.HasPrefix(stmts[0].stmt, "-- tenant=acme\nINSERT INTO otel.otel_spans ("), stmts[0].stmt)
+}
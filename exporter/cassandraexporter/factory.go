@@ -7,7 +7,11 @@ import (
 	"context"
 	"time"
 
+	"github.com/gocql/gocql"
 	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/collector/config/configretry"
+	"go.opentelemetry.io/collector/config/configtls"
+	"go.opentelemetry.io/collector/consumer"
 	"go.opentelemetry.io/collector/exporter"
 	"go.opentelemetry.io/collector/exporter/exporterhelper"
 
@@ -15,21 +19,43 @@ import (
 )
 
 func NewFactory() exporter.Factory {
+	return newFactory(createGocqlSession, nil)
+}
+
+// NewFactoryWithSession returns a factory of exporters writing through
+// session instead of opening their own, for tools embedding the exporter
+// that already hold a session. The session stays owned by the caller: the
+// exporters never close it, and the connection options of the configuration
+// do not apply to it: no secret is read, the cluster is neither probed nor
+// health checked, and only create_schema still runs through it.
+func NewFactoryWithSession(session *gocql.Session) exporter.Factory {
+	return newFactory(nil, borrowedSession{newGocqlSession(session, nil)})
+}
+
+func newFactory(newSession sessionFactory, borrowed cqlSession) exporter.Factory {
 	return exporter.NewFactory(metadata.Type,
 		createDefaultConfig,
-		exporter.WithTraces(createTracesExporter, metadata.TracesStability),
-		exporter.WithLogs(createLogsExporter, metadata.LogsStability),
+		exporter.WithTraces(createTracesExporter(newSession, borrowed), metadata.TracesStability),
+		exporter.WithLogs(createLogsExporter(newSession, borrowed), metadata.LogsStability),
+		exporter.WithMetrics(createMetricsExporter(newSession, borrowed), metadata.MetricsStability),
 	)
 }
 
 func createDefaultConfig() component.Config {
 	return &Config{
-		DSN:        "127.0.0.1",
-		Port:       9042,
-		Timeout:    10 * time.Second,
-		Keyspace:   "otel",
-		TraceTable: "otel_spans",
-		LogsTable:  "otel_logs",
+		TimeoutSettings: exporterhelper.TimeoutSettings{
+			Timeout: 10 * time.Second,
+		},
+		BackOffConfig:    configretry.NewDefaultBackOffConfig(),
+		QueueSettings:    exporterhelper.NewDefaultQueueSettings(),
+		DSN:              "127.0.0.1",
+		Port:             9042,
+		Keyspace:         "otel",
+		TraceTable:       "otel_spans",
+		LogsTable:        "otel_logs",
+		ResourcesTable:   "otel_resources",
+		LogsByTraceTable: "otel_logs_by_trace",
+		MetricsTable:     "otel_metrics",
 		Replication: Replication{
 			Class:             "SimpleStrategy",
 			ReplicationFactor: 1,
@@ -37,19 +63,122 @@ func createDefaultConfig() component.Config {
 		Compression: Compression{
 			Algorithm: "LZ4Compressor",
 		},
+		Compaction: Compaction{
+			Strategy:             timeWindowCompactionStrategy,
+			CompactionWindowSize: 1,
+			CompactionWindowUnit: "DAYS",
+		},
+		GCGraceSeconds: 864000,
+		TLS: configtls.ClientConfig{
+			Insecure: true,
+		},
+		Consistency:   "QUORUM",
+		BatchSize:     100,
+		MaxBatchBytes: 50 * 1024,
+		BatchMode:     batchModeUnlogged,
+		Coalescing: Coalescing{
+			FlushInterval: time.Second,
+		},
+		NumWorkers:     1,
+		NumConns:       2,
+		ConnectTimeout: 5 * time.Second,
+		Reconnection: Reconnection{
+			InitialInterval: time.Second,
+			MaxInterval:     30 * time.Second,
+			MaxRetries:      5,
+		},
+		HealthCheck: HealthCheck{
+			FailureThreshold: 3,
+		},
+		InsertRetry: InsertRetry{
+			MaxAttempts: 1,
+			Backoff:     100 * time.Millisecond,
+		},
+		CreateSchema:            true,
+		CreateKeyspace:          true,
+		CheckVersion:            true,
+		PartitionBy:             partitionByDay,
+		ClusteringOrder:         clusteringOrderAsc,
+		BodyEncoding:            bodyEncodingJSON,
+		AttributesFormat:        attributesFormatMap,
+		StoreResourceAttributes: true,
+		StoreLogAttributes:      true,
+		InvalidSpanIDs:          invalidSpanIDsStore,
+		TimestampFormat:         timestampFormatTimestamp,
+	}
+}
+
+func createTracesExporter(newSession sessionFactory, borrowed cqlSession) exporter.CreateTracesFunc {
+	return func(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Traces, error) {
+		c := cfg.(*Config)
+		exp, err := newTracesExporter(set.TelemetrySettings, c)
+		if err != nil {
+			return nil, err
+		}
+		exp.newSession = newSession
+		exp.borrowed = borrowed
+
+		return exporterhelper.NewTracesExporter(
+			ctx,
+			set,
+			cfg,
+			exp.pushTraceData,
+			exporterhelper.WithShutdown(exp.Shutdown),
+			exporterhelper.WithStart(exp.Start),
+			exporterhelper.WithTimeout(c.TimeoutSettings),
+			exporterhelper.WithQueue(c.QueueSettings),
+			exporterhelper.WithRetry(c.BackOffConfig),
+			exporterhelper.WithCapabilities(consumer.Capabilities{MutatesData: false}),
+		)
 	}
 }
 
-func createTracesExporter(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Traces, error) {
-	c := cfg.(*Config)
-	exp := newTracesExporter(set.Logger, c)
+func createLogsExporter(newSession sessionFactory, borrowed cqlSession) exporter.CreateLogsFunc {
+	return func(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Logs, error) {
+		c := cfg.(*Config)
+		exp, err := newLogsExporter(set.TelemetrySettings, c)
+		if err != nil {
+			return nil, err
+		}
+		exp.newSession = newSession
+		exp.borrowed = borrowed
 
-	return exporterhelper.NewTracesExporter(ctx, set, cfg, exp.pushTraceData, exporterhelper.WithShutdown(exp.Shutdown), exporterhelper.WithStart(exp.Start))
+		return exporterhelper.NewLogsExporter(
+			ctx,
+			set,
+			cfg,
+			exp.pushLogsData,
+			exporterhelper.WithShutdown(exp.Shutdown),
+			exporterhelper.WithStart(exp.Start),
+			exporterhelper.WithTimeout(c.TimeoutSettings),
+			exporterhelper.WithQueue(c.QueueSettings),
+			exporterhelper.WithRetry(c.BackOffConfig),
+			exporterhelper.WithCapabilities(consumer.Capabilities{MutatesData: false}),
+		)
+	}
 }
 
-func createLogsExporter(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Logs, error) {
-	c := cfg.(*Config)
-	exp := newLogsExporter(set.Logger, c)
+func createMetricsExporter(newSession sessionFactory, borrowed cqlSession) exporter.CreateMetricsFunc {
+	return func(ctx context.Context, set exporter.Settings, cfg component.Config) (exporter.Metrics, error) {
+		c := cfg.(*Config)
+		exp, err := newMetricsExporter(set.TelemetrySettings, c)
+		if err != nil {
+			return nil, err
+		}
+		exp.newSession = newSession
+		exp.borrowed = borrowed
 
-	return exporterhelper.NewLogsExporter(ctx, set, cfg, exp.pushLogsData, exporterhelper.WithShutdown(exp.Shutdown), exporterhelper.WithStart(exp.Start))
+		return exporterhelper.NewMetricsExporter(
+			ctx,
+			set,
+			cfg,
+			exp.pushMetricsData,
+			exporterhelper.WithShutdown(exp.Shutdown),
+			exporterhelper.WithStart(exp.Start),
+			exporterhelper.WithTimeout(c.TimeoutSettings),
+			exporterhelper.WithQueue(c.QueueSettings),
+			exporterhelper.WithRetry(c.BackOffConfig),
+			exporterhelper.WithCapabilities(consumer.Capabilities{MutatesData: false}),
+		)
+	}
 }
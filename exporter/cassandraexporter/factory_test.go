@@ -4,10 +4,19 @@
 package cassandraexporter
 
 import (
+	"context"
+	"fmt"
+	"path/filepath"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component"
 	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/consumer"
+	"go.opentelemetry.io/collector/exporter"
+	"go.opentelemetry.io/collector/exporter/exportertest"
 )
 
 func TestCreateDefaultConfig(t *testing.T) {
@@ -16,3 +25,82 @@ func TestCreateDefaultConfig(t *testing.T) {
 	assert.NotNil(t, cfg, "failed to create default config")
 	assert.NoError(t, componenttest.CheckConfigStruct(cfg))
 }
+
+func TestCreateExporters(t *testing.T) {
+	factory := NewFactory()
+	tests := map[string]func(context.Context, exporter.Settings, component.Config) (component.Component, error){
+		"traces": func(ctx context.Context, set exporter.Settings, cfg component.Config) (component.Component, error) {
+			return factory.CreateTracesExporter(ctx, set, cfg)
+		},
+		"logs": func(ctx context.Context, set exporter.Settings, cfg component.Config) (component.Component, error) {
+			return factory.CreateLogsExporter(ctx, set, cfg)
+		},
+		"metrics": func(ctx context.Context, set exporter.Settings, cfg component.Config) (component.Component, error) {
+			return factory.CreateMetricsExporter(ctx, set, cfg)
+		},
+	}
+	for name, create := range tests {
+		t.Run(name, func(t *testing.T) {
+			exp, err := create(context.Background(), exportertest.NewNopSettings(), factory.CreateDefaultConfig())
+			require.NoError(t, err)
+			require.NotNil(t, exp)
+			assert.NoError(t, exp.Shutdown(context.Background()))
+		})
+	}
+}
+
+func TestFactoryWithSession(t *testing.T) {
+	session := &fakeSession{}
+	factory := newFactory(nil, borrowedSession{session})
+	cfg := withDefaultConfig(func(config *Config) {
+		config.CreateSchema = false
+		config.QueueSettings.Enabled = false
+	})
+	exp, err := factory.CreateLogsExporter(context.Background(), exportertest.NewNopSettings(), cfg)
+	require.NoError(t, err)
+	require.NoError(t, exp.Start(context.Background(), componenttest.NewNopHost()))
+	require.NoError(t, exp.ConsumeLogs(context.Background(), simpleLogs("WARN")))
+	require.NoError(t, exp.Shutdown(context.Background()))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 1)
+	assert.Equal(t, fmt.Sprintf(insertLogTableSQL, "otel", "otel_logs"), stmts[0].stmt)
+	require.Len(t, stmts[0].values, 21)
+	assert.Equal(t, "WARN", stmts[0].values[4])
+	assert.Equal(t, `"message"`, stmts[0].values[7])
+	assert.False(t, session.closed, "the session belongs to the caller")
+}
+
+func TestFactoryWithSessionSkipsConnectionOptions(t *testing.T) {
+	session := &fakeSession{}
+	factory := newFactory(nil, borrowedSession{session})
+	cfg := withDefaultConfig(func(config *Config) {
+		config.CreateSchema = false
+		config.QueueSettings.Enabled = false
+		config.Auth.UserName = "cassandra"
+		config.Auth.PasswordFile = filepath.Join(t.TempDir(), "missing")
+		config.HealthCheck.Interval = time.Hour
+	})
+	exp, err := factory.CreateLogsExporter(context.Background(), exportertest.NewNopSettings(), cfg)
+	require.NoError(t, err)
+	require.NoError(t, exp.Start(context.Background(), componenttest.NewNopHost()))
+	assert.Empty(t, session.statements(), "the borrowed session is not probed")
+	require.NoError(t, exp.Shutdown(context.Background()))
+	assert.False(t, session.closed)
+}
+
+func TestCapabilities(t *testing.T) {
+	factory := NewFactory()
+	cfg := factory.CreateDefaultConfig()
+	set := exportertest.NewNopSettings()
+
+	traces, err := factory.CreateTracesExporter(context.Background(), set, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, consumer.Capabilities{MutatesData: false}, traces.Capabilities())
+	logs, err := factory.CreateLogsExporter(context.Background(), set, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, consumer.Capabilities{MutatesData: false}, logs.Capabilities())
+	metrics, err := factory.CreateMetricsExporter(context.Background(), set, cfg)
+	require.NoError(t, err)
+	assert.Equal(t, consumer.Capabilities{MutatesData: false}, metrics.Capabilities())
+}
@@ -0,0 +1,161 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// monitoredSession is the session of an exporter with health_check enabled.
+// A session can stay open while it only reaches coordinators that are down,
+// so the cluster is probed every interval and the session is replaced by a
+// new one once failure_threshold probes in a row failed.
+type monitoredSession struct {
+	cfg HealthCheck
+	// drain is how long a replaced session stays open for the writes
+	// started on it before the swap, the timeout of the exporter.
+	drain   time.Duration
+	probe   func(ctx context.Context, session cqlSession) error
+	rebuild func(ctx context.Context) (cqlSession, error)
+	logger  *zap.Logger
+
+	mu      sync.RWMutex
+	session cqlSession
+
+	cancel  context.CancelFunc
+	stopped chan struct{}
+}
+
+// monitorSession wraps the session opened by openSession, rebuilding it with
+// the same options but the schema creation.
+func monitorSession(session cqlSession, cfg *Config, logger *zap.Logger, telemetry *insertTelemetry, newSession sessionFactory) *monitoredSession {
+	rebuildCfg := *cfg
+	rebuildCfg.CreateSchema = false
+	rebuildCfg.HealthCheck.Interval = 0
+	probe := func(ctx context.Context, session cqlSession) error {
+		_, err := readReleaseVersion(ctx, session, cfg)
+		return err
+	}
+	rebuild := func(ctx context.Context) (cqlSession, error) {
+		return openSession(ctx, &rebuildCfg, logger, telemetry, newSession, nil)
+	}
+	return newMonitoredSession(session, cfg.HealthCheck, cfg.TimeoutSettings.Timeout, probe, rebuild, logger)
+}
+
+func newMonitoredSession(session cqlSession, cfg HealthCheck, drain time.Duration, probe func(context.Context, cqlSession) error, rebuild func(context.Context) (cqlSession, error), logger *zap.Logger) *monitoredSession {
+	ctx, cancel := context.WithCancel(context.Background())
+	s := &monitoredSession{
+		cfg:     cfg,
+		drain:   drain,
+		probe:   probe,
+		rebuild: rebuild,
+		logger:  logger,
+		session: session,
+		cancel:  cancel,
+		stopped: make(chan struct{}),
+	}
+	go s.run(ctx)
+	return s
+}
+
+func (s *monitoredSession) current() cqlSession {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.session
+}
+
+func (s *monitoredSession) Query(stmt string, values ...any) queryExecutor {
+	return s.current().Query(stmt, values...)
+}
+
+func (s *monitoredSession) NewBatch(typ gocql.BatchType) batchExecutor {
+	return s.current().NewBatch(typ)
+}
+
+// Close stops the probes, waiting for a rebuild in progress to give up, and
+// closes the current session along with the replaced ones still draining.
+func (s *monitoredSession) Close() {
+	s.cancel()
+	<-s.stopped
+	s.current().Close()
+}
+
+func (s *monitoredSession) run(ctx context.Context) {
+	defer close(s.stopped)
+	timer := time.NewTimer(jitter(s.cfg.Interval))
+	defer timer.Stop()
+	var retired []retiredSession
+	defer func() {
+		for _, r := range retired {
+			r.session.Close()
+		}
+	}()
+	var failures int
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-timer.C:
+		}
+		retired = closeDrained(retired, time.Now())
+		if err := s.probe(ctx, s.current()); err != nil {
+			if ctx.Err() != nil {
+				return
+			}
+			failures++
+			s.logger.Warn("cassandra health check failed", zap.Error(err), zap.Int("failures", failures))
+			if failures >= s.cfg.FailureThreshold {
+				if failing := s.replace(ctx, failures); failing != nil {
+					retired = append(retired, retiredSession{session: failing, closeAt: time.Now().Add(s.drain)})
+					failures = 0
+				}
+			}
+		} else {
+			failures = 0
+		}
+		timer.Reset(jitter(s.cfg.Interval))
+	}
+}
+
+// replace opens a new session and puts it in use, returning the failing one,
+// which the writes started before the swap may still be using. The failing
+// session is kept, and nil returned, when no new one can be opened.
+func (s *monitoredSession) replace(ctx context.Context, failures int) cqlSession {
+	s.logger.Warn("rebuilding the cassandra session", zap.Int("failures", failures))
+	session, err := s.rebuild(ctx)
+	if err != nil {
+		s.logger.Error("failed to rebuild the cassandra session", zap.Error(err))
+		return nil
+	}
+	s.mu.Lock()
+	failing := s.session
+	s.session = session
+	s.mu.Unlock()
+	s.logger.Info("rebuilt the cassandra session")
+	return failing
+}
+
+// retiredSession is a replaced session left open until closeAt.
+type retiredSession struct {
+	session cqlSession
+	closeAt time.Time
+}
+
+// closeDrained closes the retired sessions due by now and returns the others.
+func closeDrained(retired []retiredSession, now time.Time) []retiredSession {
+	draining := retired[:0]
+	for _, r := range retired {
+		if now.Before(r.closeAt) {
+			draining = append(draining, r)
+			continue
+		}
+		r.session.Close()
+	}
+	return draining
+}
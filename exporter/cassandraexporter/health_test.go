@@ -0,0 +1,150 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func (s *fakeSession) isClosed() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.closed
+}
+
+func TestMonitoredSessionRebuilds(t *testing.T) {
+	core, logs := observer.New(zapcore.WarnLevel)
+	failing, healthy := &fakeSession{}, &fakeSession{}
+	var probes, rebuilds atomic.Int32
+	probe := func(_ context.Context, session cqlSession) error {
+		probes.Add(1)
+		if session == failing {
+			return errors.New("no response from coordinator")
+		}
+		return nil
+	}
+	rebuild := func(context.Context) (cqlSession, error) {
+		if rebuilds.Add(1) == 1 {
+			return nil, errors.New("no hosts available in the pool")
+		}
+		return healthy, nil
+	}
+	s := newMonitoredSession(failing, HealthCheck{Interval: time.Millisecond, FailureThreshold: 2}, 0, probe, rebuild, zap.New(core))
+
+	require.Eventually(t, func() bool {
+		return s.current() == cqlSession(healthy)
+	}, 5*time.Second, time.Millisecond)
+	require.Eventually(t, failing.isClosed, 5*time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), rebuilds.Load())
+
+	// The healthy session passes its probes and is not rebuilt again.
+	before := probes.Load()
+	require.Eventually(t, func() bool {
+		return probes.Load() > before+3
+	}, 5*time.Second, time.Millisecond)
+	assert.Equal(t, int32(2), rebuilds.Load())
+
+	s.Close()
+	assert.True(t, healthy.isClosed())
+	assert.Len(t, logs.FilterMessage("rebuilding the cassandra session").All(), 2)
+	assert.Len(t, logs.FilterMessage("failed to rebuild the cassandra session").All(), 1)
+}
+
+func TestMonitoredSessionDrainsReplacedSession(t *testing.T) {
+	failing, healthy := &fakeSession{}, &fakeSession{}
+	failing.fail = func([]fakeStatement) error {
+		if failing.isClosed() {
+			return errSessionNotOpen
+		}
+		return nil
+	}
+	started := make(chan struct{})
+	probe := func(ctx context.Context, session cqlSession) error {
+		select {
+		case <-started:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if session == failing {
+			return errors.New("no response from coordinator")
+		}
+		return nil
+	}
+	rebuild := func(context.Context) (cqlSession, error) {
+		return healthy, nil
+	}
+	s := newMonitoredSession(failing, HealthCheck{Interval: time.Millisecond, FailureThreshold: 1}, time.Hour, probe, rebuild, zap.NewNop())
+
+	// A batch started before the swap runs against the replaced session.
+	batch := s.NewBatch(gocql.UnloggedBatch)
+	batch.Query("INSERT INTO otel.otel_logs (id) VALUES (?)", 1)
+	close(started)
+	require.Eventually(t, func() bool {
+		return s.current() == cqlSession(healthy)
+	}, 5*time.Second, time.Millisecond)
+	require.NoError(t, batch.Exec())
+	assert.Len(t, failing.statements(), 1)
+	assert.False(t, failing.isClosed())
+
+	s.Close()
+	assert.True(t, failing.isClosed())
+	assert.True(t, healthy.isClosed())
+}
+
+func TestMonitoredSessionCloseStopsProbes(t *testing.T) {
+	session := &fakeSession{}
+	var probes atomic.Int32
+	probe := func(context.Context, cqlSession) error {
+		probes.Add(1)
+		return errors.New("no response from coordinator")
+	}
+	rebuild := func(ctx context.Context) (cqlSession, error) {
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+	s := newMonitoredSession(session, HealthCheck{Interval: time.Millisecond, FailureThreshold: 1}, 0, probe, rebuild, zap.NewNop())
+	require.Eventually(t, func() bool {
+		return probes.Load() > 0
+	}, 5*time.Second, time.Millisecond)
+
+	// The rebuild in progress gives up when the session is closed.
+	s.Close()
+	assert.True(t, session.isClosed())
+	stopped := probes.Load()
+	time.Sleep(10 * time.Millisecond)
+	assert.Equal(t, stopped, probes.Load())
+}
+
+func TestOpenSessionHealthCheck(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.HealthCheck.Interval = time.Hour
+		config.CreateSchema = false
+	})
+	sessions := &fakeSessionFactory{}
+	session, err := openSession(context.Background(), cfg, zap.NewNop(), newTestInsertTelemetry(t), sessions.newSession, nil)
+	require.NoError(t, err)
+	monitored, ok := session.(*monitoredSession)
+	require.True(t, ok)
+	require.Len(t, sessions.sessions, 1)
+	assert.Equal(t, cqlSession(sessions.sessions[0]), monitored.current())
+	monitored.Close()
+	assert.True(t, sessions.sessions[0].closed)
+
+	// The session given to NewFactoryWithSession is not the exporter's to
+	// rebuild.
+	session, err = startSession(context.Background(), cfg, zap.NewNop(), newTestInsertTelemetry(t), nil, borrowedSession{&fakeSession{}}, nil)
+	require.NoError(t, err)
+	assert.IsType(t, borrowedSession{}, session)
+}
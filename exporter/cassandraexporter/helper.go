@@ -3,15 +3,232 @@
 
 package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
 
-import "encoding/json"
-
-func attributesToMap(attributes map[string]any) map[string]string {
-	newAttrMap := make(map[string]string)
-	for k, v := range attributes {
-		jsonV, err := json.Marshal(v)
-		if err == nil {
-			newAttrMap[k] = string(jsonV)
+import (
+	"encoding/json"
+	"hash"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+	conventions "go.opentelemetry.io/collector/semconv/v1.18.0"
+)
+
+// resourceServiceName returns the service.name attribute of the resource, or
+// an empty string when it has none.
+func resourceServiceName(res pcommon.Resource) string {
+	if v, ok := res.Attributes().Get(conventions.AttributeServiceName); ok {
+		return v.Str()
+	}
+	return ""
+}
+
+// withInstanceIDColumn adds the CollectorId column to the DDL of the main
+// table of a signal when instance_id is configured.
+func withInstanceIDColumn(cfg *Config, createTableSQL string) string {
+	if cfg.InstanceID == "" {
+		return createTableSQL
+	}
+	return withColumn(createTableSQL, "CollectorId text")
+}
+
+// withColumn adds a column definition to the DDL of a table, before its
+// primary key.
+func withColumn(createTableSQL, column string) string {
+	return strings.Replace(createTableSQL, ", PRIMARY KEY ", ", "+column+", PRIMARY KEY ", 1)
+}
+
+// withoutColumn removes a column definition, which must not be the first or
+// the last one, from the DDL of a table.
+func withoutColumn(createTableSQL, column string) string {
+	return strings.Replace(createTableSQL, ", "+column+", ", ", ", 1)
+}
+
+// withInstanceIDInsert adds the collectorid column, bound after the other
+// values of the row, to an insert template when instance_id is configured.
+func withInstanceIDInsert(cfg *Config, insertSQL string) string {
+	if cfg.InstanceID == "" {
+		return insertSQL
+	}
+	return withInsertColumn(insertSQL, "collectorid")
+}
+
+// withInsertColumn adds column, bound after the other values of the row, to
+// an insert template.
+func withInsertColumn(insertSQL, column string) string {
+	columns := strings.Index(insertSQL, ") VALUES")
+	return insertSQL[:columns] + ", " + column + strings.TrimSuffix(insertSQL[columns:], ")") + ", ?)"
+}
+
+// withoutInsertColumn removes a column, which must not be the first or the
+// last one, and its bind marker from an insert template.
+func withoutInsertColumn(insertSQL, column string) string {
+	insertSQL = strings.Replace(insertSQL, ", "+column+", ", ", ", 1)
+	return strings.Replace(insertSQL, "?, ", "", 1)
+}
+
+// appendInstanceID binds the instance_id to the collectorid column added by
+// withInstanceIDInsert.
+func appendInstanceID(cfg *Config, args []any) []any {
+	if cfg.InstanceID == "" {
+		return args
+	}
+	return append(args, cfg.InstanceID)
+}
+
+// withAttributesTruncatedColumn adds the AttributesTruncated column to the
+// DDL of the logs and spans tables when max_attributes is set.
+func withAttributesTruncatedColumn(cfg *Config, createTableSQL string) string {
+	if cfg.MaxAttributes == 0 {
+		return createTableSQL
+	}
+	return withColumn(createTableSQL, "AttributesTruncated boolean")
+}
+
+// withAttributesTruncatedInsert adds the attributestruncated column to an
+// insert template when max_attributes is set.
+func withAttributesTruncatedInsert(cfg *Config, insertSQL string) string {
+	if cfg.MaxAttributes == 0 {
+		return insertSQL
+	}
+	return withInsertColumn(insertSQL, "attributestruncated")
+}
+
+// appendAttributesTruncated binds whether attributes were left out of the
+// record to the attributestruncated column added by
+// withAttributesTruncatedInsert.
+func appendAttributesTruncated(cfg *Config, args []any, dropped int) []any {
+	if cfg.MaxAttributes == 0 {
+		return args
+	}
+	return append(args, dropped > 0)
+}
+
+// limitAttributes returns the first limit attributes in key order, so the
+// same ones are kept for every record carrying the same keys, along with the
+// number of attributes left out. Attributes within the limit, or any with a
+// zero limit, are returned as they are.
+func limitAttributes(attributes pcommon.Map, limit int) (pcommon.Map, int) {
+	if limit == 0 || attributes.Len() <= limit {
+		return attributes, 0
+	}
+	keys := make([]string, 0, attributes.Len())
+	attributes.Range(func(k string, _ pcommon.Value) bool {
+		keys = append(keys, k)
+		return true
+	})
+	sort.Strings(keys)
+	limited := pcommon.NewMap()
+	limited.EnsureCapacity(limit)
+	for _, k := range keys[:limit] {
+		v, _ := attributes.Get(k)
+		v.CopyTo(limited.PutEmpty(k))
+	}
+	return limited, len(keys) - limit
+}
+
+// attributesColumnType returns the CQL type of the attribute columns, the
+// attributes_udt when set and otherwise the type of the attributes_format.
+func attributesColumnType(cfg *Config) string {
+	switch {
+	case cfg.AttributesUDT != "":
+		return "frozen<" + cfg.AttributesUDT + ">"
+	case cfg.AttributesFormat == attributesFormatJSON:
+		return "text"
+	default:
+		return "map<text, text>"
+	}
+}
+
+// encodeAttributes converts attributes into the value bound to an attribute
+// column: a map<text, text>, a JSON object keeping the types and nesting of
+// the values with the json format, or the fields of the attributes_udt.
+func encodeAttributes(attributes pcommon.Map, cfg *Config) any {
+	switch {
+	case cfg.AttributesUDT != "":
+		return attributesToUDT(attributes)
+	case cfg.AttributesFormat == attributesFormatJSON:
+		return attributesToJSON(attributes)
+	default:
+		return attributesToMap(attributes)
+	}
+}
+
+// attributesToMap converts attributes into the map<text, text> stored in
+// Cassandra. Nested maps are flattened into dot separated keys, for example
+// k8s.pod.labels.app, while slices are stored JSON encoded. Scalar values are
+// stored as their string representation and bytes base64 encoded.
+func attributesToMap(attributes pcommon.Map) map[string]string {
+	m := make(map[string]string, attributes.Len())
+	flattenAttributes(m, "", attributes)
+	return m
+}
+
+func flattenAttributes(m map[string]string, prefix string, attributes pcommon.Map) {
+	rangeFlattened(prefix, attributes, func(key string, v pcommon.Value) {
+		m[key] = v.AsString()
+	})
+}
+
+// rangeFlattened calls fn with every attribute, nested maps being flattened
+// into dot separated keys.
+func rangeFlattened(prefix string, attributes pcommon.Map, fn func(key string, v pcommon.Value)) {
+	attributes.Range(func(k string, v pcommon.Value) bool {
+		key := prefix + k
+		if v.Type() == pcommon.ValueTypeMap && v.Map().Len() > 0 {
+			rangeFlattened(key+".", v.Map(), fn)
+			return true
+		}
+		fn(key, v)
+		return true
+	})
+}
+
+// attributesToJSON encodes attributes as a JSON object. JSON has no
+// representation for NaN and infinities, so those doubles are stored as
+// strings.
+func attributesToJSON(attributes pcommon.Map) string {
+	b, err := json.Marshal(jsonSafeValue(attributes.AsRaw()))
+	if err != nil {
+		// Unreachable, every value AsRaw returns is encodable once the
+		// non-finite doubles are replaced.
+		return "{}"
+	}
+	return string(b)
+}
+
+func jsonSafeValue(v any) any {
+	switch v := v.(type) {
+	case float64:
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return strconv.FormatFloat(v, 'g', -1, 64)
+		}
+	case map[string]any:
+		for k, e := range v {
+			v[k] = jsonSafeValue(e)
 		}
+	case []any:
+		for i, e := range v {
+			v[i] = jsonSafeValue(e)
+		}
+	}
+	return v
+}
+
+// hashAttributes writes the flattened attributes to h in key order, so equal
+// attribute sets hash the same whatever their insertion order.
+func hashAttributes(h hash.Hash, m map[string]string) {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		_, _ = h.Write([]byte(k))
+		_, _ = h.Write([]byte{'='})
+		_, _ = h.Write([]byte(m[k]))
+		_, _ = h.Write([]byte{0})
 	}
-	return newAttrMap
+	_, _ = h.Write([]byte{0})
 }
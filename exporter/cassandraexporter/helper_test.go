@@ -0,0 +1,176 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestAttributesToMap(t *testing.T) {
+	testCases := map[string]struct {
+		attributes func(m pcommon.Map)
+		expected   map[string]string
+	}{
+		"string": {
+			attributes: func(m pcommon.Map) {
+				m.PutStr("service.name", "checkout")
+			},
+			expected: map[string]string{"service.name": "checkout"},
+		},
+		"int": {
+			attributes: func(m pcommon.Map) {
+				m.PutInt("http.status_code", 200)
+			},
+			expected: map[string]string{"http.status_code": "200"},
+		},
+		"bool": {
+			attributes: func(m pcommon.Map) {
+				m.PutBool("error", true)
+			},
+			expected: map[string]string{"error": "true"},
+		},
+		"double": {
+			attributes: func(m pcommon.Map) {
+				m.PutDouble("ratio", 0.5)
+			},
+			expected: map[string]string{"ratio": "0.5"},
+		},
+		"bytes": {
+			attributes: func(m pcommon.Map) {
+				m.PutEmptyBytes("payload").FromRaw([]byte("otel"))
+			},
+			expected: map[string]string{"payload": "b3RlbA=="},
+		},
+		"empty": {
+			attributes: func(m pcommon.Map) {
+				m.PutEmpty("nothing")
+			},
+			expected: map[string]string{"nothing": ""},
+		},
+		"nested_map": {
+			attributes: func(m pcommon.Map) {
+				labels := m.PutEmptyMap("k8s").PutEmptyMap("pod").PutEmptyMap("labels")
+				labels.PutStr("app", "checkout")
+				labels.PutInt("replicas", 3)
+			},
+			expected: map[string]string{
+				"k8s.pod.labels.app":      "checkout",
+				"k8s.pod.labels.replicas": "3",
+			},
+		},
+		"empty_map": {
+			attributes: func(m pcommon.Map) {
+				m.PutEmptyMap("labels")
+			},
+			expected: map[string]string{"labels": "{}"},
+		},
+		"slice": {
+			attributes: func(m pcommon.Map) {
+				s := m.PutEmptySlice("process.command_args")
+				s.AppendEmpty().SetStr("otelcol")
+				s.AppendEmpty().SetInt(1)
+				s.AppendEmpty().SetEmptyMap().PutStr("config", "config.yaml")
+			},
+			expected: map[string]string{"process.command_args": `["otelcol",1,{"config":"config.yaml"}]`},
+		},
+	}
+	for name, test := range testCases {
+		t.Run(name, func(t *testing.T) {
+			attributes := pcommon.NewMap()
+			test.attributes(attributes)
+			assert.Equal(t, test.expected, attributesToMap(attributes))
+		})
+	}
+}
+
+func TestEncodeAttributes(t *testing.T) {
+	attributes := pcommon.NewMap()
+	attributes.PutStr("service.name", "checkout")
+	attributes.PutInt("http.status_code", 200)
+	attributes.PutBool("error", true)
+	attributes.PutDouble("ratio", 0.5)
+	attributes.PutEmptyMap("k8s").PutEmptyMap("pod").PutStr("name", "checkout-0")
+	attributes.PutEmptySlice("ports").FromRaw([]any{80, 443})
+
+	assert.Equal(t, map[string]string{
+		"service.name":     "checkout",
+		"http.status_code": "200",
+		"error":            "true",
+		"ratio":            "0.5",
+		"k8s.pod.name":     "checkout-0",
+		"ports":            "[80,443]",
+	}, encodeAttributes(attributes, withDefaultConfig()))
+
+	encoded := encodeAttributes(attributes, withDefaultConfig(func(config *Config) {
+		config.AttributesFormat = attributesFormatJSON
+	}))
+	require.IsType(t, "", encoded)
+	assert.JSONEq(t, `{
+		"service.name": "checkout",
+		"http.status_code": 200,
+		"error": true,
+		"ratio": 0.5,
+		"k8s": {"pod": {"name": "checkout-0"}},
+		"ports": [80, 443]
+	}`, encoded.(string))
+
+	nonFinite := pcommon.NewMap()
+	nonFinite.PutDouble("score", math.Inf(1))
+	nonFinite.PutEmptySlice("samples").FromRaw([]any{1.5, math.NaN()})
+	assert.JSONEq(t, `{"score": "+Inf", "samples": [1.5, "NaN"]}`, attributesToJSON(nonFinite))
+}
+
+func TestAttributesColumnType(t *testing.T) {
+	assert.Equal(t, "map<text, text>", attributesColumnType(withDefaultConfig()))
+	assert.Equal(t, "text", attributesColumnType(withDefaultConfig(func(config *Config) {
+		config.AttributesFormat = attributesFormatJSON
+	})))
+	assert.Equal(t, "frozen<otel_attributes>", attributesColumnType(withDefaultConfig(func(config *Config) {
+		config.AttributesUDT = "otel_attributes"
+	})))
+}
+
+func TestLimitAttributes(t *testing.T) {
+	attributes := pcommon.NewMap()
+	attributes.PutStr("c", "3")
+	attributes.PutStr("a", "1")
+	attributes.PutEmptyMap("b").PutStr("nested", "2")
+	attributes.PutStr("d", "4")
+
+	limited, dropped := limitAttributes(attributes, 2)
+	assert.Equal(t, 2, dropped)
+	assert.Equal(t, map[string]any{"a": "1", "b": map[string]any{"nested": "2"}}, limited.AsRaw())
+	assert.Equal(t, 4, attributes.Len())
+
+	for _, limit := range []int{0, 4, 5} {
+		limited, dropped = limitAttributes(attributes, limit)
+		assert.Zero(t, dropped)
+		assert.Equal(t, attributes, limited)
+	}
+}
+
+func TestWithInstanceID(t *testing.T) {
+	cfg := withDefaultConfig()
+	assert.Equal(t, insertSpanLinkSQL, withInstanceIDInsert(cfg, insertSpanLinkSQL))
+	assert.Equal(t, createSpanLinksTableSQL, withInstanceIDColumn(cfg, createSpanLinksTableSQL))
+	assert.Equal(t, []any{1}, appendInstanceID(cfg, []any{1}))
+
+	cfg.InstanceID = "collector-1"
+	assert.Equal(t, "INSERT INTO %s.%s (traceid, spanid, linkindex, linkedtraceid, linkedspanid, tracestate, attributes, collectorid) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		withInstanceIDInsert(cfg, insertSpanLinkSQL))
+	assert.Contains(t, withInstanceIDInsert(cfg, insertLogTableSQL), ", sampled, recordid, collectorid) VALUES(?, ")
+	assert.Contains(t, parseCreateSpanTableSQL(cfg), ", SpanFlags int, Sampled boolean, CollectorId text, PRIMARY KEY (")
+	assert.Contains(t, parseCreateLogTableSQL(cfg), ", Sampled boolean, RecordId text, CollectorId text, PRIMARY KEY ((ServiceName, DateBucket), ")
+	stmts := parseCreateMetricTablesSQL(cfg)
+	for _, ddl := range stmts[:len(stmts)-1] {
+		assert.Contains(t, ddl, ", ResourceSchemaUrl text, CollectorId text, PRIMARY KEY (")
+	}
+	assert.NotContains(t, stmts[len(stmts)-1], "CollectorId")
+	assert.Equal(t, []any{1, "collector-1"}, appendInstanceID(cfg, []any{1}))
+}
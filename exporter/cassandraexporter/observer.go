@@ -0,0 +1,60 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"context"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// queryObserver logs every query and batch attempt sent to Cassandra at debug
+// level and records its latency per host, which points at a misbehaving
+// coordinator.
+type queryObserver struct {
+	logger    *zap.Logger
+	telemetry *insertTelemetry
+}
+
+// observeQueries registers a queryObserver on the cluster when
+// enable_query_observer is set.
+func observeQueries(cluster *gocql.ClusterConfig, cfg *Config, logger *zap.Logger, telemetry *insertTelemetry) {
+	if !cfg.EnableQueryObserver {
+		return
+	}
+	observer := queryObserver{logger: logger, telemetry: telemetry}
+	cluster.QueryObserver = observer
+	cluster.BatchObserver = observer
+}
+
+func (o queryObserver) ObserveQuery(ctx context.Context, q gocql.ObservedQuery) {
+	o.observe(ctx, q.Host, q.End.Sub(q.Start), q.Attempt, q.Err, zap.String("statement", q.Statement))
+}
+
+func (o queryObserver) ObserveBatch(ctx context.Context, b gocql.ObservedBatch) {
+	fields := []zap.Field{zap.Int("statements", len(b.Statements))}
+	if len(b.Statements) > 0 {
+		// The rows of a batch share their insert statement.
+		fields = append(fields, zap.String("statement", b.Statements[0]))
+	}
+	o.observe(ctx, b.Host, b.End.Sub(b.Start), b.Attempt, b.Err, fields...)
+}
+
+func (o queryObserver) observe(ctx context.Context, host *gocql.HostInfo, latency time.Duration, attempt int, err error, fields ...zap.Field) {
+	address := ""
+	if host != nil {
+		address = host.ConnectAddressAndPort()
+	}
+	o.telemetry.recordQuery(ctx, address, latency)
+	if ce := o.logger.Check(zap.DebugLevel, "cassandra query"); ce != nil {
+		ce.Write(append(fields,
+			zap.String("host", address),
+			zap.Duration("latency", latency),
+			zap.Int("attempt", attempt),
+			zap.Error(err),
+		)...)
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestObserveQueries(t *testing.T) {
+	cluster := gocql.NewCluster("127.0.0.1")
+	observeQueries(cluster, withDefaultConfig(), zap.NewNop(), nil)
+	assert.Nil(t, cluster.QueryObserver)
+	assert.Nil(t, cluster.BatchObserver)
+
+	observeQueries(cluster, withDefaultConfig(func(config *Config) {
+		config.EnableQueryObserver = true
+	}), zap.NewNop(), nil)
+	assert.IsType(t, queryObserver{}, cluster.QueryObserver)
+	assert.IsType(t, queryObserver{}, cluster.BatchObserver)
+}
+
+func TestQueryObserver(t *testing.T) {
+	tel := setupTestTelemetry()
+	telemetry, err := newInsertTelemetry(tel.NewSettings().TelemetrySettings, signalLogs)
+	require.NoError(t, err)
+	core, logs := observer.New(zap.DebugLevel)
+	o := queryObserver{logger: zap.New(core), telemetry: telemetry}
+
+	host := (&gocql.HostInfo{}).SetConnectAddress(net.ParseIP("10.0.0.1"))
+	start := time.Now()
+	o.ObserveBatch(context.Background(), gocql.ObservedBatch{
+		Statements: []string{insertLogTableSQL, insertLogTableSQL},
+		Start:      start,
+		End:        start.Add(30 * time.Millisecond),
+		Host:       host,
+		Attempt:    1,
+		Err:        errors.New("write timeout"),
+	})
+	o.ObserveQuery(context.Background(), gocql.ObservedQuery{
+		Statement: releaseVersionSQL,
+		Start:     start,
+		End:       start.Add(2 * time.Millisecond),
+		Host:      host,
+	})
+
+	require.Equal(t, 2, logs.Len())
+	batch := logs.All()[0].ContextMap()
+	assert.Equal(t, insertLogTableSQL, batch["statement"])
+	assert.EqualValues(t, 2, batch["statements"])
+	assert.Equal(t, "10.0.0.1:0", batch["host"])
+	assert.Equal(t, 30*time.Millisecond, batch["latency"])
+	assert.EqualValues(t, 1, batch["attempt"])
+	assert.Equal(t, "write timeout", batch["error"])
+	assert.Equal(t, releaseVersionSQL, logs.All()[1].ContextMap()["statement"])
+
+	var md metricdata.ResourceMetrics
+	require.NoError(t, tel.reader.Collect(context.Background(), &md))
+	latency := tel.getMetric("otelcol_cassandra_exporter_query_latency", md).Data.(metricdata.Histogram[int64])
+	require.Len(t, latency.DataPoints, 1)
+	assert.Equal(t, attribute.NewSet(attribute.String("signal", "logs"), attribute.String("host", "10.0.0.1:0")), latency.DataPoints[0].Attributes)
+	assert.Equal(t, uint64(2), latency.DataPoints[0].Count)
+	assert.Equal(t, int64(32), latency.DataPoints[0].Sum)
+	require.NoError(t, tel.Shutdown(context.Background()))
+}
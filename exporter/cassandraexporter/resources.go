@@ -0,0 +1,74 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"encoding/hex"
+	"fmt"
+	"hash/fnv"
+
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// resourceRows fills the resource columns of the rows of a single push. With
+// deduplicate_resources the attributes of each resource are written once to
+// the resources table and the rows only carry the id of the resource.
+type resourceRows struct {
+	deduplicate bool
+	insertSQL   string
+	cfg         *Config
+	// encoded holds the attribute column of every resource seen in the
+	// push by its id, so resources repeated across the push are only
+	// encoded once.
+	encoded map[string]any
+}
+
+func newResourceRows(cfg *Config, insertSQL string) *resourceRows {
+	return &resourceRows{
+		deduplicate: cfg.DeduplicateResources,
+		insertSQL:   insertSQL,
+		cfg:         cfg,
+		encoded:     map[string]any{},
+	}
+}
+
+// columns returns the resource id and attributes bound on the rows of a
+// resource, and adds the row of the resource to stmts the first time it is
+// seen. The attributes are nil when deduplicating, leaving them to the
+// resources table.
+func (r *resourceRows) columns(stmts []statement, attributes pcommon.Map) ([]statement, string, any) {
+	// The flattened attributes both identify the resource and fill the
+	// map column, so they are only computed once.
+	flattened := attributesToMap(attributes)
+	id := hashResource(flattened)
+	encoded, ok := r.encoded[id]
+	if !ok {
+		encoded = flattened
+		if r.cfg.AttributesFormat != attributesFormatMap || r.cfg.AttributesUDT != "" {
+			encoded = encodeAttributes(attributes, r.cfg)
+		}
+		r.encoded[id] = encoded
+		if r.deduplicate {
+			stmts = append(stmts, statement{
+				partitionKey: id,
+				query:        r.insertSQL,
+				args:         []any{id, encoded},
+			})
+		}
+	}
+	if r.deduplicate {
+		return stmts, id, nil
+	}
+	return stmts, id, encoded
+}
+
+func hashResource(flattened map[string]string) string {
+	h := fnv.New64a()
+	hashAttributes(h, flattened)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func parseCreateResourceTableSQL(cfg *Config) string {
+	return fmt.Sprintf(createResourceTableSQL, cfg.Keyspace, cfg.ResourcesTable, attributesColumnType(cfg), parseTableOptions(cfg))
+}
@@ -0,0 +1,92 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+func TestHashResource(t *testing.T) {
+	first := pcommon.NewMap()
+	first.PutStr("service.name", "checkout")
+	first.PutStr("host.name", "node-1")
+	second := pcommon.NewMap()
+	second.PutStr("host.name", "node-1")
+	second.PutStr("service.name", "checkout")
+	other := pcommon.NewMap()
+	other.PutStr("service.name", "cart")
+
+	id := hashResource(attributesToMap(first))
+	assert.Equal(t, id, hashResource(attributesToMap(second)))
+	assert.NotEqual(t, id, hashResource(attributesToMap(other)))
+	assert.Len(t, id, 16)
+}
+
+func TestResourceRows(t *testing.T) {
+	attributes := pcommon.NewMap()
+	attributes.PutStr("service.name", "checkout")
+
+	rows := newResourceRows(withDefaultConfig(), "INSERT")
+	stmts, id, value := rows.columns(nil, attributes)
+	assert.Empty(t, stmts)
+	assert.Equal(t, hashResource(map[string]string{"service.name": "checkout"}), id)
+	assert.Equal(t, map[string]string{"service.name": "checkout"}, value)
+
+	rows = newResourceRows(withDefaultConfig(func(config *Config) {
+		config.DeduplicateResources = true
+		config.AttributesFormat = attributesFormatJSON
+	}), "INSERT")
+	stmts, id, value = rows.columns(nil, attributes)
+	assert.Nil(t, value)
+	require.Len(t, stmts, 1)
+	assert.Equal(t, statement{partitionKey: id, query: "INSERT", args: []any{id, `{"service.name":"checkout"}`}}, stmts[0])
+
+	stmts, _, _ = rows.columns(stmts, attributes)
+	assert.Len(t, stmts, 1)
+
+	rows = newResourceRows(withDefaultConfig(func(config *Config) {
+		config.AttributesFormat = attributesFormatJSON
+	}), "INSERT")
+	_, first, value := rows.columns(nil, attributes)
+	assert.Equal(t, `{"service.name":"checkout"}`, value)
+	_, second, value := rows.columns(nil, attributes)
+	assert.Equal(t, first, second)
+	assert.Equal(t, `{"service.name":"checkout"}`, value)
+}
+
+func TestParseCreateResourceTableSQL(t *testing.T) {
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS otel.otel_resources (ResourceId text, ResourceAttributes map<text, text>, PRIMARY KEY (ResourceId)) WITH COMPRESSION = {'class': 'LZ4Compressor'} AND compaction = {'class': 'TimeWindowCompactionStrategy', 'compaction_window_size': 1, 'compaction_window_unit': 'DAYS'} AND gc_grace_seconds = 864000",
+		parseCreateResourceTableSQL(withDefaultConfig()))
+}
+
+func BenchmarkResourceRows(b *testing.B) {
+	attributes := pcommon.NewMap()
+	attributes.PutStr("service.name", "checkout")
+	attributes.PutStr("service.version", "1.4.2")
+	attributes.PutStr("host.name", "node-1")
+	attributes.PutStr("k8s.namespace.name", "shop")
+	attributes.PutStr("k8s.pod.name", "checkout-7d9c8b6f5-x2x4q")
+	labels := attributes.PutEmptyMap("k8s.pod.labels")
+	labels.PutStr("app", "checkout")
+	labels.PutStr("tier", "backend")
+
+	for _, format := range []string{attributesFormatMap, attributesFormatJSON} {
+		b.Run(format, func(b *testing.B) {
+			cfg := withDefaultConfig(func(config *Config) {
+				config.AttributesFormat = format
+			})
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				rows := newResourceRows(cfg, "INSERT")
+				for j := 0; j < 100; j++ {
+					rows.columns(nil, attributes)
+				}
+			}
+		})
+	}
+}
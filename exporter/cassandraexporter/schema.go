@@ -0,0 +1,60 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/collector/component"
+)
+
+// CreateSchema runs the DDL of the traces, logs and metrics exporters
+// configured by cfg and returns, without starting them. It lets the keyspaces,
+// types and tables be provisioned in a deploy step ahead of the collector;
+// the sessions it opens are closed before it returns. The schema is created
+// whatever the value of create_schema.
+func CreateSchema(ctx context.Context, cfg *Config, set component.TelemetrySettings) error {
+	return createSchema(ctx, cfg, set, createGocqlSession)
+}
+
+func createSchema(ctx context.Context, cfg *Config, set component.TelemetrySettings, newSession sessionFactory) error {
+	if err := cfg.Validate(); err != nil {
+		return err
+	}
+	schemaCfg := *cfg
+	schemaCfg.CreateSchema = true
+
+	traces, err := newTracesExporter(set, &schemaCfg)
+	if err != nil {
+		return err
+	}
+	logs, err := newLogsExporter(set, &schemaCfg)
+	if err != nil {
+		return err
+	}
+	metrics, err := newMetricsExporter(set, &schemaCfg)
+	if err != nil {
+		return err
+	}
+
+	signals := []struct {
+		signal           string
+		cfg              *Config
+		telemetry        *insertTelemetry
+		initializeKernel func(context.Context, cqlSession) error
+	}{
+		{signal: signalTraces, cfg: traces.cfg, telemetry: traces.telemetry, initializeKernel: traces.initializeTraceKernel},
+		{signal: signalLogs, cfg: logs.cfg, telemetry: logs.telemetry, initializeKernel: logs.initializeLogKernel},
+		{signal: signalMetrics, cfg: metrics.cfg, telemetry: metrics.telemetry, initializeKernel: metrics.initializeMetricKernel},
+	}
+	for _, s := range signals {
+		session, err := openSession(ctx, s.cfg, set.Logger, s.telemetry, newSession, s.initializeKernel)
+		if err != nil {
+			return fmt.Errorf("create %s schema: %w", s.signal, err)
+		}
+		session.Close()
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/template"
+)
+
+// Sentinels rendered into a schema template to check that it places both the
+// keyspace and the table.
+const (
+	schemaTemplateKeyspaceSentinel = "\x00keyspace\x00"
+	schemaTemplateTableSentinel    = "\x00table\x00"
+)
+
+var errConfigSchemaTemplatePlaceholders = errors.New("schema_template must reference both {{.Keyspace}} and {{.Table}}")
+
+// schemaTemplateData is what a schema template is rendered with.
+type schemaTemplateData struct {
+	Keyspace string
+	Table    string
+}
+
+// loadSchemaTemplate parses the schema template given as inline CQL or, when
+// source has no whitespace, as the path of a file holding it.
+func loadSchemaTemplate(source string) (*template.Template, error) {
+	text := source
+	if !strings.ContainsAny(source, " \t\r\n") {
+		content, err := os.ReadFile(source)
+		if err != nil {
+			return nil, fmt.Errorf("read schema_template: %w", err)
+		}
+		text = string(content)
+	}
+	tmpl, err := template.New("schema_template").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("parse schema_template: %w", err)
+	}
+
+	var sb strings.Builder
+	data := schemaTemplateData{Keyspace: schemaTemplateKeyspaceSentinel, Table: schemaTemplateTableSentinel}
+	if err := tmpl.Execute(&sb, data); err != nil {
+		return nil, fmt.Errorf("render schema_template: %w", err)
+	}
+	if !strings.Contains(sb.String(), schemaTemplateKeyspaceSentinel) || !strings.Contains(sb.String(), schemaTemplateTableSentinel) {
+		return nil, errConfigSchemaTemplatePlaceholders
+	}
+	return tmpl, nil
+}
+
+// renderSchemaTemplate returns the DDL of the schema template for the given
+// keyspace and table.
+func renderSchemaTemplate(source, keyspace, table string) (string, error) {
+	tmpl, err := loadSchemaTemplate(source)
+	if err != nil {
+		return "", err
+	}
+	var sb strings.Builder
+	if err := tmpl.Execute(&sb, schemaTemplateData{Keyspace: keyspace, Table: table}); err != nil {
+		return "", fmt.Errorf("render schema_template: %w", err)
+	}
+	return sb.String(), nil
+}
@@ -0,0 +1,54 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testSchemaTemplate = `CREATE TABLE IF NOT EXISTS {{.Keyspace}}.{{.Table}} (TimeStamp timestamp, ServiceName text, PRIMARY KEY (ServiceName, TimeStamp))`
+
+func TestRenderSchemaTemplate(t *testing.T) {
+	ddl, err := renderSchemaTemplate(testSchemaTemplate, "otel", "otel_logs")
+	require.NoError(t, err)
+	assert.Equal(t, "CREATE TABLE IF NOT EXISTS otel.otel_logs (TimeStamp timestamp, ServiceName text, PRIMARY KEY (ServiceName, TimeStamp))", ddl)
+
+	path := filepath.Join(t.TempDir(), "logs.cql")
+	require.NoError(t, os.WriteFile(path, []byte(testSchemaTemplate), 0o600))
+	fromFile, err := renderSchemaTemplate(path, "otel", "otel_logs")
+	require.NoError(t, err)
+	assert.Equal(t, ddl, fromFile)
+}
+
+func TestLoadSchemaTemplateInvalid(t *testing.T) {
+	_, err := loadSchemaTemplate(filepath.Join(t.TempDir(), "missing.cql"))
+	require.ErrorContains(t, err, "read schema_template")
+
+	_, err = loadSchemaTemplate("CREATE TABLE {{.Keyspace}.{{.Table}} (id int PRIMARY KEY)")
+	require.ErrorContains(t, err, "parse schema_template")
+
+	_, err = loadSchemaTemplate("CREATE TABLE {{.Keyspace}}.{{.Tabel}} (id int PRIMARY KEY)")
+	require.ErrorContains(t, err, "render schema_template")
+
+	_, err = loadSchemaTemplate("CREATE TABLE otel.{{.Table}} (id int PRIMARY KEY)")
+	require.ErrorIs(t, err, errConfigSchemaTemplatePlaceholders)
+}
+
+func TestParseCreateLogTableStatement(t *testing.T) {
+	ddl, err := parseCreateLogTableStatement(withDefaultConfig())
+	require.NoError(t, err)
+	assert.Equal(t, parseCreateLogTableSQL(withDefaultConfig()), ddl)
+
+	ddl, err = parseCreateLogTableStatement(withDefaultConfig(func(config *Config) {
+		config.Keyspace = "telemetry"
+		config.SchemaTemplate = testSchemaTemplate
+	}))
+	require.NoError(t, err)
+	assert.Contains(t, ddl, "telemetry.otel_logs (")
+}
@@ -0,0 +1,94 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+)
+
+func TestCreateSchema(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.CreateSchema = false
+		config.MetricsKeyspace = "metrics"
+	})
+	sessions := &fakeSessionFactory{}
+	require.NoError(t, createSchema(context.Background(), cfg, componenttest.NewNopTelemetrySettings(), sessions.newSession))
+
+	require.Len(t, sessions.sessions, 3)
+	var created []string
+	for _, session := range sessions.sessions {
+		assert.True(t, session.closed)
+		assert.Empty(t, session.batches)
+		for _, query := range session.queries {
+			if strings.HasPrefix(query.stmt, "CREATE TABLE IF NOT EXISTS ") {
+				created = append(created, strings.Fields(query.stmt)[5])
+			}
+		}
+	}
+	assert.Equal(t, []string{
+		"otel.otel_spans",
+		"otel.otel_spans_events",
+		"otel.otel_spans_links",
+		"otel.otel_logs",
+		"metrics.otel_metrics_gauge",
+		"metrics.otel_metrics_sum",
+		"metrics.otel_metrics_histogram",
+		"metrics.otel_metrics_exponential_histogram",
+		"metrics.otel_metrics_summary",
+		"metrics.otel_metrics_exemplars",
+	}, created)
+	assert.False(t, cfg.CreateSchema)
+}
+
+// TestCreateSchemaIfNotExists guards the schema against collectors starting
+// together, each one creating the keyspace, types and tables the others may
+// already have created.
+func TestCreateSchemaIfNotExists(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.DeduplicateResources = true
+		config.IndexLogsByTrace = true
+	})
+	sessions := &fakeSessionFactory{}
+	require.NoError(t, createSchema(context.Background(), cfg, componenttest.NewNopTelemetrySettings(), sessions.newSession))
+
+	var ddl int
+	for _, session := range sessions.sessions {
+		for _, query := range session.queries {
+			if !strings.HasPrefix(query.stmt, "CREATE ") {
+				continue
+			}
+			ddl++
+			fields := strings.Fields(query.stmt)
+			require.GreaterOrEqual(t, len(fields), 5, query.stmt)
+			assert.Equal(t, []string{"IF", "NOT", "EXISTS"}, fields[2:5], query.stmt)
+		}
+	}
+	// A keyspace per signal, the span types and every table, the resources
+	// one being shared by spans and logs.
+	assert.Equal(t, 18, ddl)
+}
+
+func TestCreateSchemaFailure(t *testing.T) {
+	sessions := &fakeSessionFactory{scan: func(string, ...any) error {
+		return errors.New("unavailable")
+	}}
+	err := createSchema(context.Background(), withDefaultConfig(), componenttest.NewNopTelemetrySettings(), sessions.newSession)
+	require.ErrorContains(t, err, "create traces schema: cassandra health check failed: unavailable")
+	require.Len(t, sessions.sessions, 1)
+	assert.True(t, sessions.sessions[0].closed)
+	assert.Empty(t, sessions.sessions[0].queries)
+
+	cfg := withDefaultConfig(func(config *Config) {
+		config.Keyspace = ""
+	})
+	require.Error(t, createSchema(context.Background(), cfg, componenttest.NewNopTelemetrySettings(), sessions.newSession))
+	assert.Len(t, sessions.sessions, 1)
+}
@@ -0,0 +1,55 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.uber.org/zap"
+)
+
+// withSecrets returns a copy of cfg holding the password and the Astra token
+// read from their files, which takes precedence over the inline values. The
+// files are read at every start, so a rotated secret is picked up by the next
+// one.
+func (cfg *Config) withSecrets(logger *zap.Logger) (*Config, error) {
+	secretCfg := *cfg
+	if cfg.Auth.PasswordFile != "" {
+		password, err := readSecret(logger, "auth.password", cfg.Auth.Password, cfg.Auth.PasswordFile)
+		if err != nil {
+			return nil, err
+		}
+		secretCfg.Auth.Password = password
+	}
+	if cfg.Astra != nil && cfg.Astra.TokenFile != "" {
+		token, err := readSecret(logger, "astra.token", cfg.Astra.Token, cfg.Astra.TokenFile)
+		if err != nil {
+			return nil, err
+		}
+		astra := *cfg.Astra
+		astra.Token = token
+		secretCfg.Astra = &astra
+	}
+	return &secretCfg, nil
+}
+
+// readSecret returns the content of path, trimmed of the trailing whitespace
+// and newlines secret mounts usually end with.
+func readSecret(logger *zap.Logger, option string, inline configopaque.String, path string) (configopaque.String, error) {
+	if inline != "" {
+		logger.Warn("both "+option+" and "+option+"_file are set, using "+option+"_file", zap.String("path", path))
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", fmt.Errorf("read %s_file: %w", option, err)
+	}
+	secret := strings.TrimRight(string(content), " \t\r\n")
+	if secret == "" {
+		return "", fmt.Errorf("%s_file %q is empty", option, path)
+	}
+	return configopaque.String(secret), nil
+}
@@ -0,0 +1,104 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/config/configopaque"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func writeSecret(t *testing.T, content string) string {
+	path := filepath.Join(t.TempDir(), "secret")
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestWithSecrets(t *testing.T) {
+	t.Run("inline", func(t *testing.T) {
+		cfg := withDefaultConfig(func(config *Config) {
+			config.Auth.UserName = "user"
+			config.Auth.Password = "inline"
+		})
+		secretCfg, err := cfg.withSecrets(zap.NewNop())
+		require.NoError(t, err)
+		assert.Equal(t, configopaque.String("inline"), secretCfg.Auth.Password)
+	})
+
+	t.Run("password_file", func(t *testing.T) {
+		cfg := withDefaultConfig(func(config *Config) {
+			config.Auth.UserName = "user"
+			config.Auth.PasswordFile = writeSecret(t, "s3cret \n")
+		})
+		secretCfg, err := cfg.withSecrets(zap.NewNop())
+		require.NoError(t, err)
+		assert.Equal(t, configopaque.String("s3cret"), secretCfg.Auth.Password)
+		assert.Empty(t, cfg.Auth.Password)
+	})
+
+	t.Run("inline_and_file", func(t *testing.T) {
+		core, logs := observer.New(zapcore.WarnLevel)
+		cfg := withDefaultConfig(func(config *Config) {
+			config.Auth.UserName = "user"
+			config.Auth.Password = "inline"
+			config.Auth.PasswordFile = writeSecret(t, "from-file\n")
+		})
+		secretCfg, err := cfg.withSecrets(zap.New(core))
+		require.NoError(t, err)
+		assert.Equal(t, configopaque.String("from-file"), secretCfg.Auth.Password)
+		require.Equal(t, 1, logs.Len())
+		assert.Equal(t, "both auth.password and auth.password_file are set, using auth.password_file", logs.All()[0].Message)
+	})
+
+	t.Run("astra_token_file", func(t *testing.T) {
+		cfg := withDefaultConfig(func(config *Config) {
+			config.Astra = &Astra{SecureConnectBundle: "secure-connect.zip", TokenFile: writeSecret(t, "AstraCS:secret\r\n")}
+		})
+		secretCfg, err := cfg.withSecrets(zap.NewNop())
+		require.NoError(t, err)
+		assert.Equal(t, configopaque.String("AstraCS:secret"), secretCfg.Astra.Token)
+		assert.Empty(t, cfg.Astra.Token)
+	})
+
+	t.Run("missing_file", func(t *testing.T) {
+		cfg := withDefaultConfig(func(config *Config) {
+			config.Auth.UserName = "user"
+			config.Auth.PasswordFile = filepath.Join(t.TempDir(), "missing")
+		})
+		_, err := cfg.withSecrets(zap.NewNop())
+		require.ErrorIs(t, err, os.ErrNotExist)
+		assert.ErrorContains(t, err, "read auth.password_file: ")
+	})
+
+	t.Run("empty_file", func(t *testing.T) {
+		path := writeSecret(t, "\n")
+		cfg := withDefaultConfig(func(config *Config) {
+			config.Auth.UserName = "user"
+			config.Auth.PasswordFile = path
+		})
+		_, err := cfg.withSecrets(zap.NewNop())
+		require.EqualError(t, err, "auth.password_file \""+path+"\" is empty")
+	})
+}
+
+func TestOpenSessionPasswordFile(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.Auth.UserName = "user"
+		config.Auth.PasswordFile = writeSecret(t, "s3cret\n")
+	})
+	sessions := &fakeSessionFactory{}
+	_, err := openSession(context.Background(), cfg, zap.NewNop(), nil, sessions.newSession, func(context.Context, cqlSession) error { return nil })
+	require.NoError(t, err)
+	require.Len(t, sessions.clusters, 1)
+	assert.Equal(t, gocql.PasswordAuthenticator{Username: "user", Password: "s3cret"}, sessions.clusters[0].Authenticator)
+}
@@ -0,0 +1,386 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/gocql/gocql"
+	"go.uber.org/zap"
+)
+
+// cqlSession is the part of *gocql.Session the exporters depend on, so that
+// they can be exercised against a fake session in tests.
+type cqlSession interface {
+	Query(stmt string, values ...any) queryExecutor
+	NewBatch(typ gocql.BatchType) batchExecutor
+	Close()
+}
+
+// borrowedSession is a session owned by the caller of NewFactoryWithSession,
+// which the exporters must not close.
+type borrowedSession struct {
+	cqlSession
+}
+
+func (borrowedSession) Close() {}
+
+// errSessionNotOpen is returned by the pushes of an exporter whose session
+// failed to open, or was never opened.
+var errSessionNotOpen = errors.New("cassandra session is not open")
+
+// sessionFactory opens a session on the cluster.
+type sessionFactory func(cluster *gocql.ClusterConfig) (cqlSession, error)
+
+// queryExecutor is a single statement sent to Cassandra on its own.
+type queryExecutor interface {
+	WithContext(ctx context.Context) queryExecutor
+	Idempotent(value bool) queryExecutor
+	SpeculativeExecutionPolicy(policy gocql.SpeculativeExecutionPolicy) queryExecutor
+	Consistency(c gocql.Consistency) queryExecutor
+	Exec() error
+	Scan(dest ...any) error
+}
+
+// batchExecutor collects statements that are sent to Cassandra together.
+type batchExecutor interface {
+	Query(stmt string, values ...any)
+	Size() int
+	WithContext(ctx context.Context) batchExecutor
+	SpeculativeExecutionPolicy(policy gocql.SpeculativeExecutionPolicy) batchExecutor
+	Consistency(c gocql.Consistency) batchExecutor
+	Exec() error
+}
+
+type gocqlSession struct {
+	session *gocql.Session
+	// observer is the batch observer of the cluster. Every batch is given
+	// its own observer to learn its coordinator, which replaces the one of
+	// the cluster, so the attempts are handed on to it.
+	observer gocql.BatchObserver
+}
+
+func newGocqlSession(session *gocql.Session, observer gocql.BatchObserver) cqlSession {
+	return gocqlSession{session: session, observer: observer}
+}
+
+func createGocqlSession(cluster *gocql.ClusterConfig) (cqlSession, error) {
+	session, err := cluster.CreateSession()
+	if err != nil {
+		return nil, err
+	}
+	return newGocqlSession(session, cluster.BatchObserver), nil
+}
+
+// coordinatorError is an insert failure along with the address of the host
+// that coordinated the failed attempt.
+type coordinatorError struct {
+	host string
+	err  error
+}
+
+func (e *coordinatorError) Error() string {
+	return e.err.Error()
+}
+
+func (e *coordinatorError) Unwrap() error {
+	return e.err
+}
+
+// withCoordinator attaches the coordinator to a failure, when it is known.
+func withCoordinator(err error, host *gocql.HostInfo) error {
+	if err == nil || host == nil {
+		return err
+	}
+	return &coordinatorError{host: host.ConnectAddressAndPort(), err: err}
+}
+
+// coordinatorObserver remembers the host of the last attempt of a batch. The
+// speculative executions of a batch may report concurrently.
+type coordinatorObserver struct {
+	next gocql.BatchObserver
+
+	mu   sync.Mutex
+	host *gocql.HostInfo
+}
+
+func (o *coordinatorObserver) ObserveBatch(ctx context.Context, b gocql.ObservedBatch) {
+	o.mu.Lock()
+	o.host = b.Host
+	o.mu.Unlock()
+	if o.next != nil {
+		o.next.ObserveBatch(ctx, b)
+	}
+}
+
+func (o *coordinatorObserver) coordinator() *gocql.HostInfo {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.host
+}
+
+// withSessionRetry returns a sessionFactory that retries opening a session
+// with the reconnection policy of the cluster, so that a collector starting
+// before Cassandra does not fail right away. It gives up once the retries of
+// the policy are exhausted or ctx is done.
+func withSessionRetry(ctx context.Context, logger *zap.Logger, newSession sessionFactory) sessionFactory {
+	return func(cluster *gocql.ClusterConfig) (cqlSession, error) {
+		policy := cluster.ReconnectionPolicy
+		for attempt := 1; ; attempt++ {
+			session, err := newSession(cluster)
+			if err == nil || policy == nil || attempt > policy.GetMaxRetries() {
+				return session, err
+			}
+			interval := policy.GetInterval(attempt)
+			logger.Warn("failed to connect to cassandra, retrying",
+				zap.Error(err), zap.Int("attempt", attempt), zap.Duration("interval", interval))
+			timer := time.NewTimer(interval)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return nil, err
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+// probeCluster checks that the cluster answers queries within the connect
+// timeout, telling an unreachable cluster apart from a failing insert or
+// schema statement. The release version and the topology seen by the node
+// answering are logged and, with check_version, the version is checked
+// against the configured features.
+func probeCluster(ctx context.Context, session cqlSession, cfg *Config, logger *zap.Logger) error {
+	version, err := waitReady(ctx, session, cfg, logger)
+	if err != nil {
+		return fmt.Errorf("cassandra health check failed: %w", err)
+	}
+	ctx, cancel := withConnectTimeout(ctx, cfg)
+	defer cancel()
+	logger.Info("connected to cassandra", append([]zap.Field{zap.String("release_version", version)},
+		clusterTopology(ctx, session, logger)...)...)
+	if cfg.CheckVersion {
+		return checkServerVersion(cfg, version, logger)
+	}
+	return nil
+}
+
+// waitReady reads the release version of the cluster. With startup_timeout,
+// a cluster that does not answer yet, such as one starting along with the
+// collector, is polled with the backoff of the reconnection options until the
+// timeout elapses, and the last error is returned then.
+func waitReady(ctx context.Context, session cqlSession, cfg *Config, logger *zap.Logger) (string, error) {
+	deadline := time.Now().Add(cfg.StartupTimeout)
+	interval := cfg.Reconnection.InitialInterval
+	for attempt := 1; ; attempt++ {
+		version, err := readReleaseVersion(ctx, session, cfg)
+		if err == nil {
+			return version, nil
+		}
+		if cfg.StartupTimeout <= 0 || time.Now().Add(interval).After(deadline) {
+			return "", err
+		}
+		logger.Warn("cassandra is not ready, retrying",
+			zap.Error(err), zap.Int("attempt", attempt), zap.Duration("interval", interval))
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return "", err
+		case <-timer.C:
+		}
+		interval = min(2*interval, cfg.Reconnection.MaxInterval)
+	}
+}
+
+func readReleaseVersion(ctx context.Context, session cqlSession, cfg *Config) (string, error) {
+	ctx, cancel := withConnectTimeout(ctx, cfg)
+	defer cancel()
+	var version string
+	err := session.Query(releaseVersionSQL).WithContext(ctx).Scan(&version)
+	return version, err
+}
+
+// withConnectTimeout bounds ctx by connect_timeout, when set.
+func withConnectTimeout(ctx context.Context, cfg *Config) (context.Context, context.CancelFunc) {
+	if cfg.ConnectTimeout > 0 {
+		return context.WithTimeout(ctx, cfg.ConnectTimeout)
+	}
+	return ctx, func() {}
+}
+
+// clusterTopology returns the cluster name, the datacenter of the node
+// answering and the number of nodes of the ring it knows of, confirming the
+// collector reached the intended cluster and datacenter. Databases emulating
+// Cassandra may not provide them, so failures are only logged at debug level.
+func clusterTopology(ctx context.Context, session cqlSession, logger *zap.Logger) []zap.Field {
+	var clusterName, dataCenter string
+	if err := session.Query(localTopologySQL).WithContext(ctx).Scan(&clusterName, &dataCenter); err != nil {
+		logger.Debug("failed to read the cassandra topology", zap.Error(err))
+		return nil
+	}
+	fields := []zap.Field{zap.String("cluster_name", clusterName), zap.String("data_center", dataCenter)}
+	var peers int64
+	if err := session.Query(countPeersSQL).WithContext(ctx).Scan(&peers); err != nil {
+		logger.Debug("failed to count the cassandra peers", zap.Error(err))
+		return fields
+	}
+	return append(fields, zap.Int64("hosts", peers+1))
+}
+
+// startSession returns the writer session of an exporter: borrowed, the
+// session given to NewFactoryWithSession, when set, or else one opened with
+// openSession. A borrowed session is already connected, so only
+// initializeKernel runs on it, with create_schema.
+func startSession(ctx context.Context, cfg *Config, logger *zap.Logger, telemetry *insertTelemetry, newSession sessionFactory, borrowed cqlSession, initializeKernel func(context.Context, cqlSession) error) (cqlSession, error) {
+	if borrowed == nil {
+		return openSession(ctx, cfg, logger, telemetry, newSession, initializeKernel)
+	}
+	if cfg.CreateSchema {
+		if err := initializeKernel(ctx, borrowed); err != nil {
+			return nil, err
+		}
+	}
+	return borrowed, nil
+}
+
+// openSession opens the writer session of an exporter, with the secrets read
+// from their files, retrying with the reconnection policy, probes the cluster
+// and, with create_schema, runs initializeKernel on it. The session is closed
+// when any step fails.
+func openSession(ctx context.Context, cfg *Config, logger *zap.Logger, telemetry *insertTelemetry, newSession sessionFactory, initializeKernel func(context.Context, cqlSession) error) (cqlSession, error) {
+	secretCfg, err := cfg.withSecrets(logger)
+	if err != nil {
+		return nil, err
+	}
+	cluster, err := newSessionCluster(ctx, secretCfg)
+	if err != nil {
+		return nil, err
+	}
+	observeQueries(cluster, cfg, logger, telemetry)
+
+	session, err := withSessionRetry(ctx, logger, newSession)(cluster)
+	if err != nil {
+		return nil, err
+	}
+	if session == nil {
+		return nil, fmt.Errorf("connect to cassandra: %w", errSessionNotOpen)
+	}
+	if err := probeCluster(ctx, session, cfg, logger); err != nil {
+		session.Close()
+		return nil, err
+	}
+	if cfg.CreateSchema {
+		if err := initializeKernel(ctx, session); err != nil {
+			session.Close()
+			return nil, err
+		}
+	}
+	if cfg.HealthCheck.Interval > 0 {
+		return monitorSession(session, cfg, logger, telemetry, newSession), nil
+	}
+	return session, nil
+}
+
+// keyspaceSchema returns the DDL creating the keyspace of cfg, which is empty
+// when create_keyspace is off.
+func keyspaceSchema(cfg *Config) []string {
+	if !cfg.createsKeyspace() {
+		return nil
+	}
+	return []string{parseCreateDatabaseSQL(cfg)}
+}
+
+// execSchema runs the DDL statements in order, stopping at the first failure.
+func execSchema(ctx context.Context, session cqlSession, stmts ...string) error {
+	for _, stmt := range stmts {
+		if err := session.Query(stmt).WithContext(ctx).Exec(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s gocqlSession) Query(stmt string, values ...any) queryExecutor {
+	return gocqlQuery{query: s.session.Query(stmt, values...)}
+}
+
+func (s gocqlSession) NewBatch(typ gocql.BatchType) batchExecutor {
+	return gocqlBatch{session: s.session, batch: s.session.NewBatch(typ), observer: s.observer}
+}
+
+func (s gocqlSession) Close() {
+	s.session.Close()
+}
+
+type gocqlBatch struct {
+	session  *gocql.Session
+	batch    *gocql.Batch
+	observer gocql.BatchObserver
+}
+
+// Query adds an insert to the batch. Inserts write the same row with the same
+// values when repeated, so they are marked idempotent, which lets gocql retry
+// them and execute them speculatively.
+func (b gocqlBatch) Query(stmt string, values ...any) {
+	b.batch.Entries = append(b.batch.Entries, gocql.BatchEntry{Stmt: stmt, Args: values, Idempotent: true})
+}
+
+func (b gocqlBatch) Size() int {
+	return b.batch.Size()
+}
+
+func (b gocqlBatch) WithContext(ctx context.Context) batchExecutor {
+	b.batch = b.batch.WithContext(ctx)
+	return b
+}
+
+func (b gocqlBatch) SpeculativeExecutionPolicy(policy gocql.SpeculativeExecutionPolicy) batchExecutor {
+	b.batch = b.batch.SpeculativeExecutionPolicy(policy)
+	return b
+}
+
+func (b gocqlBatch) Consistency(c gocql.Consistency) batchExecutor {
+	b.batch.SetConsistency(c)
+	return b
+}
+
+func (b gocqlBatch) Exec() error {
+	observer := &coordinatorObserver{next: b.observer}
+	err := b.session.ExecuteBatch(b.batch.Observer(observer))
+	return withCoordinator(err, observer.coordinator())
+}
+
+type gocqlQuery struct {
+	query *gocql.Query
+}
+
+func (q gocqlQuery) WithContext(ctx context.Context) queryExecutor {
+	return gocqlQuery{query: q.query.WithContext(ctx)}
+}
+
+func (q gocqlQuery) Idempotent(value bool) queryExecutor {
+	return gocqlQuery{query: q.query.Idempotent(value)}
+}
+
+func (q gocqlQuery) SpeculativeExecutionPolicy(policy gocql.SpeculativeExecutionPolicy) queryExecutor {
+	return gocqlQuery{query: q.query.SetSpeculativeExecutionPolicy(policy)}
+}
+
+func (q gocqlQuery) Consistency(c gocql.Consistency) queryExecutor {
+	return gocqlQuery{query: q.query.Consistency(c)}
+}
+
+func (q gocqlQuery) Exec() error {
+	iter := q.query.Iter()
+	return withCoordinator(iter.Close(), iter.Host())
+}
+
+func (q gocqlQuery) Scan(dest ...any) error {
+	return q.query.Scan(dest...)
+}
@@ -0,0 +1,458 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/component/componenttest"
+	"go.opentelemetry.io/collector/pdata/pmetric"
+	"go.opentelemetry.io/collector/pdata/ptrace"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fakeStatement is a statement captured by fakeSession.
+type fakeStatement struct {
+	stmt   string
+	values []any
+}
+
+// fakeSession records the executed queries and batches instead of talking to
+// Cassandra.
+type fakeSession struct {
+	mu      sync.Mutex
+	queries []fakeStatement
+	batches [][]fakeStatement
+	// types holds the type of every batch.
+	types []gocql.BatchType
+	// policies holds the speculative execution policy of every batch and
+	// idempotent query.
+	policies []gocql.SpeculativeExecutionPolicy
+	// consistencies holds the consistency every batch and query was
+	// executed at, empty for the one of the session.
+	consistencies []string
+	closed        bool
+	// closes counts the calls to Close.
+	closes int
+
+	// scan answers the single row queries, nil answers every query with an
+	// empty row.
+	scan func(stmt string, dest ...any) error
+	// fail decides the outcome of executing a batch or a query, nil means
+	// success.
+	fail func(stmts []fakeStatement) error
+}
+
+func (s *fakeSession) Query(stmt string, values ...any) queryExecutor {
+	return &fakeQuery{session: s, ctx: context.Background(), stmt: fakeStatement{stmt: stmt, values: values}}
+}
+
+func (s *fakeSession) NewBatch(typ gocql.BatchType) batchExecutor {
+	return &fakeBatch{session: s, ctx: context.Background(), typ: typ}
+}
+
+func (s *fakeSession) Close() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	s.closes++
+}
+
+// statements returns every statement that was executed successfully.
+func (s *fakeSession) statements() []fakeStatement {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var stmts []fakeStatement
+	for _, batch := range s.batches {
+		stmts = append(stmts, batch...)
+	}
+	return stmts
+}
+
+type fakeBatch struct {
+	session *fakeSession
+	ctx     context.Context
+	typ     gocql.BatchType
+	policy  gocql.SpeculativeExecutionPolicy
+	stmts   []fakeStatement
+	// consistency is empty unless set on the batch.
+	consistency string
+}
+
+func (b *fakeBatch) Query(stmt string, values ...any) {
+	b.stmts = append(b.stmts, fakeStatement{stmt: stmt, values: values})
+}
+
+func (b *fakeBatch) Size() int {
+	return len(b.stmts)
+}
+
+func (b *fakeBatch) WithContext(ctx context.Context) batchExecutor {
+	b.ctx = ctx
+	return b
+}
+
+func (b *fakeBatch) SpeculativeExecutionPolicy(policy gocql.SpeculativeExecutionPolicy) batchExecutor {
+	b.policy = policy
+	return b
+}
+
+func (b *fakeBatch) Consistency(c gocql.Consistency) batchExecutor {
+	b.consistency = c.String()
+	return b
+}
+
+func (b *fakeBatch) Exec() error {
+	if err := b.ctx.Err(); err != nil {
+		return err
+	}
+	if b.session.fail != nil {
+		if err := b.session.fail(b.stmts); err != nil {
+			return err
+		}
+	}
+	b.session.mu.Lock()
+	defer b.session.mu.Unlock()
+	b.session.batches = append(b.session.batches, b.stmts)
+	b.session.types = append(b.session.types, b.typ)
+	b.session.policies = append(b.session.policies, b.policy)
+	b.session.consistencies = append(b.session.consistencies, b.consistency)
+	return nil
+}
+
+type fakeQuery struct {
+	session     *fakeSession
+	ctx         context.Context
+	stmt        fakeStatement
+	idempotent  bool
+	policy      gocql.SpeculativeExecutionPolicy
+	consistency string
+}
+
+func (q *fakeQuery) WithContext(ctx context.Context) queryExecutor {
+	q.ctx = ctx
+	return q
+}
+
+func (q *fakeQuery) Idempotent(value bool) queryExecutor {
+	q.idempotent = value
+	return q
+}
+
+func (q *fakeQuery) SpeculativeExecutionPolicy(policy gocql.SpeculativeExecutionPolicy) queryExecutor {
+	q.policy = policy
+	return q
+}
+
+func (q *fakeQuery) Consistency(c gocql.Consistency) queryExecutor {
+	q.consistency = c.String()
+	return q
+}
+
+func (q *fakeQuery) Exec() error {
+	if err := q.ctx.Err(); err != nil {
+		return err
+	}
+	if q.session.fail != nil {
+		if err := q.session.fail([]fakeStatement{q.stmt}); err != nil {
+			return err
+		}
+	}
+	q.session.mu.Lock()
+	defer q.session.mu.Unlock()
+	q.session.queries = append(q.session.queries, q.stmt)
+	q.session.consistencies = append(q.session.consistencies, q.consistency)
+	if q.idempotent {
+		q.session.policies = append(q.session.policies, q.policy)
+	}
+	return nil
+}
+
+func (q *fakeQuery) Scan(dest ...any) error {
+	if err := q.ctx.Err(); err != nil {
+		return err
+	}
+	if q.session.scan == nil {
+		return nil
+	}
+	return q.session.scan(q.stmt.stmt, dest...)
+}
+
+// fakeSessionFactory hands out a new fakeSession for every session opened and
+// keeps track of the cluster each one was opened on.
+type fakeSessionFactory struct {
+	clusters []*gocql.ClusterConfig
+	sessions []*fakeSession
+
+	// failures is the number of attempts failing before sessions are opened.
+	failures int
+	attempts int
+	// scan is handed to every session opened.
+	scan func(stmt string, dest ...any) error
+}
+
+func (f *fakeSessionFactory) newSession(cluster *gocql.ClusterConfig) (cqlSession, error) {
+	f.attempts++
+	if f.attempts <= f.failures {
+		return nil, errors.New("no hosts available in the pool")
+	}
+	session := &fakeSession{scan: f.scan}
+	f.clusters = append(f.clusters, cluster)
+	f.sessions = append(f.sessions, session)
+	return session, nil
+}
+
+func TestGocqlBatchIdempotent(t *testing.T) {
+	batch := gocqlBatch{batch: &gocql.Batch{}}
+	batch.Query("INSERT INTO otel.otel_logs (body) VALUES (?)", "first")
+	batch.Query("INSERT INTO otel.otel_logs (body) VALUES (?)", "second")
+	assert.Equal(t, 2, batch.Size())
+	assert.True(t, batch.batch.IsIdempotent())
+	assert.Equal(t, []any{"second"}, batch.batch.Entries[1].Args)
+}
+
+func TestProbeCluster(t *testing.T) {
+	session := &fakeSession{scan: fakeSystemTables("4.1.5")}
+	core, logs := observer.New(zap.InfoLevel)
+	require.NoError(t, probeCluster(context.Background(), session, withDefaultConfig(), zap.New(core)))
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]any{
+		"release_version": "4.1.5",
+		"cluster_name":    "Test Cluster",
+		"data_center":     "dc1",
+		"hosts":           int64(3),
+	}, logs.All()[0].ContextMap())
+
+	session.scan = func(string, ...any) error {
+		return gocql.ErrTimeoutNoResponse
+	}
+	err := probeCluster(context.Background(), session, withDefaultConfig(), zap.NewNop())
+	require.ErrorIs(t, err, gocql.ErrTimeoutNoResponse)
+	require.ErrorContains(t, err, "cassandra health check failed")
+}
+
+func TestProbeClusterStartupTimeout(t *testing.T) {
+	cfg := withDefaultConfig(func(config *Config) {
+		config.StartupTimeout = time.Minute
+		config.Reconnection.InitialInterval = time.Millisecond
+		config.Reconnection.MaxInterval = 2 * time.Millisecond
+	})
+	ready := fakeSystemTables("4.1.5")
+	var attempts int
+	session := &fakeSession{scan: func(stmt string, dest ...any) error {
+		if stmt == releaseVersionSQL {
+			attempts++
+			if attempts <= 3 {
+				return gocql.ErrNoConnections
+			}
+		}
+		return ready(stmt, dest...)
+	}}
+	core, logs := observer.New(zap.WarnLevel)
+	require.NoError(t, probeCluster(context.Background(), session, cfg, zap.New(core)))
+	assert.Equal(t, 4, attempts)
+	require.Equal(t, 3, logs.FilterMessage("cassandra is not ready, retrying").Len())
+	assert.Equal(t, []any{time.Millisecond, 2 * time.Millisecond, 2 * time.Millisecond}, []any{
+		logs.All()[0].ContextMap()["interval"],
+		logs.All()[1].ContextMap()["interval"],
+		logs.All()[2].ContextMap()["interval"],
+	})
+
+	cfg.StartupTimeout = 20 * time.Millisecond
+	session.scan = func(string, ...any) error {
+		return gocql.ErrNoConnections
+	}
+	start := time.Now()
+	err := probeCluster(context.Background(), session, cfg, zap.NewNop())
+	require.ErrorIs(t, err, gocql.ErrNoConnections)
+	assert.Less(t, time.Since(start), time.Second)
+
+	// Without startup_timeout the first failure fails the start.
+	cfg.StartupTimeout = 0
+	attempts = 0
+	session.scan = func(string, ...any) error {
+		attempts++
+		return gocql.ErrNoConnections
+	}
+	require.ErrorIs(t, probeCluster(context.Background(), session, cfg, zap.NewNop()), gocql.ErrNoConnections)
+	assert.Equal(t, 1, attempts)
+}
+
+func TestProbeClusterTopologyUnavailable(t *testing.T) {
+	scan := fakeSystemTables("4.1.5")
+	session := &fakeSession{scan: func(stmt string, dest ...any) error {
+		if stmt == countPeersSQL {
+			return errors.New("aggregates are not supported")
+		}
+		return scan(stmt, dest...)
+	}}
+	core, logs := observer.New(zap.InfoLevel)
+	require.NoError(t, probeCluster(context.Background(), session, withDefaultConfig(), zap.New(core)))
+	require.Equal(t, 1, logs.Len())
+	assert.Equal(t, map[string]any{
+		"release_version": "4.1.5",
+		"cluster_name":    "Test Cluster",
+		"data_center":     "dc1",
+	}, logs.All()[0].ContextMap())
+
+	session.scan = func(stmt string, dest ...any) error {
+		if stmt != releaseVersionSQL {
+			return errors.New("undefined column name data_center")
+		}
+		return scan(stmt, dest...)
+	}
+	core, logs = observer.New(zap.InfoLevel)
+	require.NoError(t, probeCluster(context.Background(), session, withDefaultConfig(), zap.New(core)))
+	assert.Equal(t, map[string]any{"release_version": "4.1.5"}, logs.All()[0].ContextMap())
+}
+
+// fakeSystemTables answers the queries on the system tables made when a
+// session is opened, for a three nodes cluster of the given release.
+func fakeSystemTables(release string) func(stmt string, dest ...any) error {
+	return func(stmt string, dest ...any) error {
+		switch stmt {
+		case releaseVersionSQL:
+			*dest[0].(*string) = release
+		case localTopologySQL:
+			*dest[0].(*string) = "Test Cluster"
+			*dest[1].(*string) = "dc1"
+		case countPeersSQL:
+			*dest[0].(*int64) = 2
+		}
+		return nil
+	}
+}
+
+func TestProbeClusterCheckVersion(t *testing.T) {
+	session := &fakeSession{scan: fakeSystemTables("3.11.17")}
+	cfg := withDefaultConfig(func(config *Config) {
+		config.Compression.Algorithm = "ZstdCompressor"
+	})
+	require.ErrorIs(t, probeCluster(context.Background(), session, cfg, zap.NewNop()), errUnsupportedServerVersion)
+
+	cfg.CheckVersion = false
+	require.NoError(t, probeCluster(context.Background(), session, cfg, zap.NewNop()))
+}
+
+func TestOpenSession(t *testing.T) {
+	kernel := func(ctx context.Context, session cqlSession) error {
+		return execSchema(ctx, session, "CREATE KEYSPACE", "CREATE TABLE")
+	}
+
+	t.Run("create_schema", func(t *testing.T) {
+		sessions := &fakeSessionFactory{}
+		session, err := openSession(context.Background(), withDefaultConfig(), zap.NewNop(), nil, sessions.newSession, kernel)
+		require.NoError(t, err)
+		require.Len(t, sessions.sessions, 1)
+		assert.Same(t, sessions.sessions[0], session)
+		assert.Equal(t, []fakeStatement{{stmt: "CREATE KEYSPACE"}, {stmt: "CREATE TABLE"}}, sessions.sessions[0].queries)
+	})
+
+	t.Run("skip_schema", func(t *testing.T) {
+		sessions := &fakeSessionFactory{}
+		_, err := openSession(context.Background(), withDefaultConfig(func(config *Config) {
+			config.CreateSchema = false
+		}), zap.NewNop(), nil, sessions.newSession, kernel)
+		require.NoError(t, err)
+		assert.Empty(t, sessions.sessions[0].queries)
+	})
+
+	t.Run("kernel_fails", func(t *testing.T) {
+		sessions := &fakeSessionFactory{}
+		_, err := openSession(context.Background(), withDefaultConfig(), zap.NewNop(), nil, sessions.newSession,
+			func(context.Context, cqlSession) error { return errors.New("unauthorized") })
+		require.EqualError(t, err, "unauthorized")
+		assert.True(t, sessions.sessions[0].closed)
+	})
+
+	t.Run("nil_session", func(t *testing.T) {
+		_, err := openSession(context.Background(), withDefaultConfig(), zap.NewNop(), nil,
+			func(*gocql.ClusterConfig) (cqlSession, error) { return nil, nil }, kernel)
+		require.ErrorIs(t, err, errSessionNotOpen)
+	})
+}
+
+func TestPushWithoutSession(t *testing.T) {
+	set := componenttest.NewNopTelemetrySettings()
+	cfg := withDefaultConfig(func(config *Config) {
+		config.Reconnection.MaxRetries = 0
+	})
+	sessions := &fakeSessionFactory{failures: 3}
+	ctx := context.Background()
+
+	logs, err := newLogsExporter(set, cfg)
+	require.NoError(t, err)
+	logs.newSession = sessions.newSession
+	require.EqualError(t, logs.Start(ctx, componenttest.NewNopHost()), "no hosts available in the pool")
+	require.ErrorIs(t, logs.pushLogsData(ctx, simpleLogs("INFO")), errSessionNotOpen)
+
+	traces, err := newTracesExporter(set, cfg)
+	require.NoError(t, err)
+	traces.newSession = sessions.newSession
+	require.Error(t, traces.Start(ctx, componenttest.NewNopHost()))
+	require.ErrorIs(t, traces.pushTraceData(ctx, ptrace.NewTraces()), errSessionNotOpen)
+
+	metrics, err := newMetricsExporter(set, cfg)
+	require.NoError(t, err)
+	metrics.newSession = sessions.newSession
+	require.Error(t, metrics.Start(ctx, componenttest.NewNopHost()))
+	require.ErrorIs(t, metrics.pushMetricsData(ctx, pmetric.NewMetrics()), errSessionNotOpen)
+
+	require.NoError(t, logs.Shutdown(ctx))
+	require.NoError(t, traces.Shutdown(ctx))
+	require.NoError(t, metrics.Shutdown(ctx))
+}
+
+func TestExecSchema(t *testing.T) {
+	session := &fakeSession{fail: func(stmts []fakeStatement) error {
+		if stmts[0].stmt == "CREATE TABLE b" {
+			return errors.New("invalid")
+		}
+		return nil
+	}}
+	require.EqualError(t, execSchema(context.Background(), session, "CREATE TABLE a", "CREATE TABLE b", "CREATE TABLE c"), "invalid")
+	assert.Equal(t, []fakeStatement{{stmt: "CREATE TABLE a"}}, session.queries)
+}
+
+func TestWithSessionRetry(t *testing.T) {
+	cluster := &gocql.ClusterConfig{ReconnectionPolicy: &gocql.ExponentialReconnectionPolicy{
+		MaxRetries:      3,
+		InitialInterval: time.Millisecond,
+		MaxInterval:     time.Millisecond,
+	}}
+
+	t.Run("recovers", func(t *testing.T) {
+		sessions := &fakeSessionFactory{failures: 3}
+		session, err := withSessionRetry(context.Background(), zap.NewNop(), sessions.newSession)(cluster)
+		require.NoError(t, err)
+		assert.Equal(t, 4, sessions.attempts)
+		assert.Same(t, sessions.sessions[0], session)
+	})
+
+	t.Run("gives_up", func(t *testing.T) {
+		sessions := &fakeSessionFactory{failures: 4}
+		_, err := withSessionRetry(context.Background(), zap.NewNop(), sessions.newSession)(cluster)
+		require.ErrorContains(t, err, "no hosts available")
+		assert.Equal(t, 4, sessions.attempts)
+	})
+
+	t.Run("canceled", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		sessions := &fakeSessionFactory{failures: 4}
+		_, err := withSessionRetry(ctx, zap.NewNop(), sessions.newSession)(&gocql.ClusterConfig{
+			ReconnectionPolicy: &gocql.ConstantReconnectionPolicy{MaxRetries: 3, Interval: time.Hour},
+		})
+		require.Error(t, err)
+		assert.Equal(t, 1, sessions.attempts)
+	})
+}
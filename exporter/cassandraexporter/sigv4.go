@@ -0,0 +1,114 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/gocql/gocql"
+)
+
+// The SigV4 handshake of Amazon Keyspaces: the client announces SigV4, the
+// server answers with a nonce and the client proves its identity by signing a
+// canonical request built around that nonce.
+const (
+	sigV4InitialResponse = "SigV4\x00\x00"
+	sigV4Algorithm       = "AWS4-HMAC-SHA256"
+	sigV4Service         = "cassandra"
+	sigV4TimestampFormat = "2006-01-02T15:04:05.000Z"
+)
+
+var errSigV4NoNonce = errors.New("sigv4: no nonce in the server challenge")
+
+// sigV4Authenticator is a gocql.Authenticator signing the connection handshake
+// with credentials of the default AWS credential chain.
+type sigV4Authenticator struct {
+	region      string
+	credentials aws.CredentialsProvider
+	now         func() time.Time
+}
+
+func newSigV4Authenticator(ctx context.Context, region string) (gocql.Authenticator, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(region))
+	if err != nil {
+		return nil, fmt.Errorf("sigv4: load aws config: %w", err)
+	}
+	return sigV4Authenticator{region: region, credentials: awsCfg.Credentials, now: time.Now}, nil
+}
+
+func (a sigV4Authenticator) Challenge(req []byte) ([]byte, gocql.Authenticator, error) {
+	if !bytes.Contains(req, []byte("nonce=")) {
+		return []byte(sigV4InitialResponse), a, nil
+	}
+	nonce, err := extractNonce(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	creds, err := a.credentials.Retrieve(context.Background())
+	if err != nil {
+		return nil, nil, fmt.Errorf("sigv4: retrieve aws credentials: %w", err)
+	}
+	return []byte(signSigV4Nonce(a.region, creds, nonce, a.now().UTC())), nil, nil
+}
+
+func (a sigV4Authenticator) Success([]byte) error {
+	return nil
+}
+
+// extractNonce reads the nonce out of a challenge such as "nonce=abc,...".
+func extractNonce(challenge []byte) (string, error) {
+	_, after, found := bytes.Cut(challenge, []byte("nonce="))
+	if !found {
+		return "", errSigV4NoNonce
+	}
+	nonce, _, _ := bytes.Cut(after, []byte(","))
+	if len(nonce) == 0 {
+		return "", errSigV4NoNonce
+	}
+	return string(nonce), nil
+}
+
+// signSigV4Nonce returns the authentication response for the given nonce.
+func signSigV4Nonce(region string, creds aws.Credentials, nonce string, t time.Time) string {
+	timestamp := t.Format(sigV4TimestampFormat)
+	scope := fmt.Sprintf("%s/%s/%s/aws4_request", t.Format("20060102"), region, sigV4Service)
+
+	query := fmt.Sprintf("X-Amz-Algorithm=%s&X-Amz-Credential=%s&X-Amz-Date=%s&X-Amz-Expires=900",
+		sigV4Algorithm, url.QueryEscape(creds.AccessKeyID+"/"+scope), url.QueryEscape(timestamp))
+	canonicalRequest := fmt.Sprintf("PUT\n/authenticate\n%s\nhost:%s\n\nhost\n%s", query, sigV4Service, sha256Hex(nonce))
+	stringToSign := fmt.Sprintf("%s\n%s\n%s\n%s", sigV4Algorithm, timestamp, scope, sha256Hex(canonicalRequest))
+
+	key := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), t.Format("20060102"))
+	key = hmacSHA256(key, region)
+	key = hmacSHA256(key, sigV4Service)
+	key = hmacSHA256(key, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+
+	resp := fmt.Sprintf("signature=%s,access_key=%s,amzdate=%s", signature, creds.AccessKeyID, timestamp)
+	if creds.SessionToken != "" {
+		resp += ",session_token=" + creds.SessionToken
+	}
+	return resp
+}
+
+func sha256Hex(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
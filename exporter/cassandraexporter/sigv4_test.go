@@ -0,0 +1,68 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSigV4AuthenticatorChallenge(t *testing.T) {
+	signedAt := time.Date(2024, 9, 1, 12, 30, 15, 0, time.UTC)
+	auth := sigV4Authenticator{
+		region: "us-east-1",
+		credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret", SessionToken: "token"}, nil
+		}),
+		now: func() time.Time { return signedAt },
+	}
+
+	resp, next, err := auth.Challenge([]byte("org.apache.cassandra.auth.sigv4.SigV4Authenticator"))
+	require.NoError(t, err)
+	assert.Equal(t, sigV4InitialResponse, string(resp))
+	require.NotNil(t, next)
+
+	resp, next, err = next.Challenge([]byte("nonce=91703fdc2ef562e19fbdab0f58e42fe5"))
+	require.NoError(t, err)
+	assert.Nil(t, next)
+	parts := strings.Split(string(resp), ",")
+	require.Len(t, parts, 4)
+	assert.Regexp(t, "^signature=[0-9a-f]{64}$", parts[0])
+	assert.Equal(t, "access_key=AKID", parts[1])
+	assert.Equal(t, "amzdate=2024-09-01T12:30:15.000Z", parts[2])
+	assert.Equal(t, "session_token=token", parts[3])
+
+	creds := aws.Credentials{AccessKeyID: "AKID", SecretAccessKey: "secret"}
+	assert.Equal(t, signSigV4Nonce("us-east-1", creds, "nonce-a", signedAt), signSigV4Nonce("us-east-1", creds, "nonce-a", signedAt))
+	assert.NotEqual(t, signSigV4Nonce("us-east-1", creds, "nonce-a", signedAt), signSigV4Nonce("us-east-1", creds, "nonce-b", signedAt))
+	assert.NotContains(t, signSigV4Nonce("us-east-1", creds, "nonce-a", signedAt), "session_token")
+}
+
+func TestExtractNonce(t *testing.T) {
+	nonce, err := extractNonce([]byte("nonce=abc,other=1"))
+	require.NoError(t, err)
+	assert.Equal(t, "abc", nonce)
+
+	_, err = extractNonce([]byte("nonce="))
+	require.ErrorIs(t, err, errSigV4NoNonce)
+	_, err = extractNonce([]byte("other=1"))
+	require.ErrorIs(t, err, errSigV4NoNonce)
+}
+
+func TestNewClusterSigV4(t *testing.T) {
+	c, err := newCluster(context.Background(), withDefaultConfig(func(config *Config) {
+		config.Auth.SigV4 = &SigV4{Region: "us-east-1"}
+		config.TLS.Insecure = false
+	}))
+	require.NoError(t, err)
+	require.IsType(t, sigV4Authenticator{}, c.Authenticator)
+	assert.Equal(t, "us-east-1", c.Authenticator.(sigV4Authenticator).region)
+	require.NotNil(t, c.SslOpts)
+}
@@ -0,0 +1,76 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"context"
+	"time"
+
+	"go.opentelemetry.io/collector/component"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/metric"
+
+	"github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter/internal/metadata"
+)
+
+const (
+	signalLogs    = "logs"
+	signalTraces  = "traces"
+	signalMetrics = "metrics"
+)
+
+// insertTelemetry records the outcome of the inserts of a single signal to
+// the internal telemetry of the collector.
+type insertTelemetry struct {
+	builder *metadata.TelemetryBuilder
+	signal  metric.MeasurementOption
+}
+
+func newInsertTelemetry(set component.TelemetrySettings, signal string) (*insertTelemetry, error) {
+	builder, err := metadata.NewTelemetryBuilder(set)
+	if err != nil {
+		return nil, err
+	}
+	return &insertTelemetry{
+		builder: builder,
+		signal:  metric.WithAttributeSet(attribute.NewSet(attribute.String("signal", signal))),
+	}, nil
+}
+
+// recordBatch records a batch of the given number of records sent to Cassandra.
+func (t *insertTelemetry) recordBatch(ctx context.Context, records int, latency time.Duration, err error) {
+	t.builder.CassandraExporterBatchLatency.Record(ctx, latency.Milliseconds(), t.signal)
+	if err != nil {
+		t.recordFailed(ctx, records)
+		return
+	}
+	t.builder.CassandraExporterSentRecords.Add(ctx, int64(records), t.signal)
+}
+
+// recordQuery records the latency of a query or batch attempt answered by the
+// given host.
+func (t *insertTelemetry) recordQuery(ctx context.Context, host string, latency time.Duration) {
+	t.builder.CassandraExporterQueryLatency.Record(ctx, latency.Milliseconds(), t.signal, metric.WithAttributes(attribute.String("host", host)))
+}
+
+// recordTruncated records the bytes cut off an oversized log body.
+func (t *insertTelemetry) recordTruncated(ctx context.Context, bytes int) {
+	t.builder.CassandraExporterTruncatedBodyBytes.Add(ctx, int64(bytes), t.signal)
+}
+
+// recordTruncatedAttributes records the attributes left out of records with
+// more than max_attributes.
+func (t *insertTelemetry) recordTruncatedAttributes(ctx context.Context, attributes int) {
+	t.builder.CassandraExporterTruncatedAttributes.Add(ctx, int64(attributes), t.signal)
+}
+
+// recordInvalidSpans records spans with an all-zero trace or span id.
+func (t *insertTelemetry) recordInvalidSpans(ctx context.Context, spans int) {
+	t.builder.CassandraExporterInvalidSpans.Add(ctx, int64(spans), t.signal)
+}
+
+// recordFailed records records that were not inserted.
+func (t *insertTelemetry) recordFailed(ctx context.Context, records int) {
+	t.builder.CassandraExporterFailedRecords.Add(ctx, int64(records), t.signal)
+}
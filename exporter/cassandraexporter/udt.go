@@ -0,0 +1,69 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"strings"
+
+	"github.com/gocql/gocql"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// udtAttributes binds attributes to a column of the attributes_udt. Each field
+// of the type takes the attribute of the same name, once flattened like for a
+// map and with the characters an identifier cannot hold replaced by
+// underscores, so k8s.pod.name fills the k8s_pod_name field. Fields without
+// an attribute are null and attributes without a field are dropped.
+type udtAttributes map[string]pcommon.Value
+
+var _ gocql.UDTMarshaler = udtAttributes(nil)
+
+func attributesToUDT(attributes pcommon.Map) udtAttributes {
+	fields := make(udtAttributes, attributes.Len())
+	rangeFlattened("", attributes, func(key string, v pcommon.Value) {
+		fields[udtFieldName(key)] = v
+	})
+	return fields
+}
+
+// udtFieldName returns the name of the field storing the attribute key, which
+// Cassandra lower-cases like every unquoted identifier.
+func udtFieldName(key string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= '0' && r <= '9', r == '_':
+			return r
+		case r >= 'A' && r <= 'Z':
+			return r + 'a' - 'A'
+		default:
+			return '_'
+		}
+	}, key)
+}
+
+// MarshalUDT marshals the attribute of the field into the type of the field.
+// Text fields take the string form of any attribute and double fields accept
+// integer attributes, every other field needs an attribute gocql converts.
+func (a udtAttributes) MarshalUDT(name string, info gocql.TypeInfo) ([]byte, error) {
+	v, ok := a[name]
+	if !ok {
+		return nil, nil
+	}
+	switch info.Type() {
+	case gocql.TypeVarchar, gocql.TypeText, gocql.TypeAscii:
+		return gocql.Marshal(info, v.AsString())
+	case gocql.TypeDouble:
+		if v.Type() == pcommon.ValueTypeInt {
+			return gocql.Marshal(info, float64(v.Int()))
+		}
+	case gocql.TypeFloat:
+		switch v.Type() {
+		case pcommon.ValueTypeInt:
+			return gocql.Marshal(info, float32(v.Int()))
+		case pcommon.ValueTypeDouble:
+			return gocql.Marshal(info, float32(v.Double()))
+		}
+	}
+	return gocql.Marshal(info, v.AsRaw())
+}
@@ -0,0 +1,81 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/gocql/gocql"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/collector/pdata/pcommon"
+)
+
+// testAttributesUDT mirrors
+// CREATE TYPE otel.otel_attributes (service_name text, http_status_code int, ratio double, error boolean, k8s_pod_name text, region text).
+var testAttributesUDT = gocql.UDTTypeInfo{
+	NativeType: gocql.NewNativeType(4, gocql.TypeUDT, ""),
+	KeySpace:   "otel",
+	Name:       "otel_attributes",
+	Elements: []gocql.UDTField{
+		{Name: "service_name", Type: gocql.NewNativeType(4, gocql.TypeVarchar, "")},
+		{Name: "http_status_code", Type: gocql.NewNativeType(4, gocql.TypeInt, "")},
+		{Name: "ratio", Type: gocql.NewNativeType(4, gocql.TypeDouble, "")},
+		{Name: "error", Type: gocql.NewNativeType(4, gocql.TypeBoolean, "")},
+		{Name: "k8s_pod_name", Type: gocql.NewNativeType(4, gocql.TypeVarchar, "")},
+		{Name: "region", Type: gocql.NewNativeType(4, gocql.TypeVarchar, "")},
+	},
+}
+
+func TestAttributesToUDT(t *testing.T) {
+	attributes := pcommon.NewMap()
+	attributes.PutStr("service.name", "checkout")
+	attributes.PutInt("http.status_code", 200)
+	attributes.PutInt("ratio", 1)
+	attributes.PutBool("error", true)
+	attributes.PutEmptyMap("k8s").PutEmptyMap("pod").PutStr("name", "checkout-0")
+	attributes.PutStr("unmapped", "dropped")
+
+	data, err := gocql.Marshal(testAttributesUDT, attributesToUDT(attributes))
+	require.NoError(t, err)
+	fields := map[string]any{}
+	require.NoError(t, gocql.Unmarshal(testAttributesUDT, data, &fields))
+	assert.Equal(t, map[string]any{
+		"service_name":     "checkout",
+		"http_status_code": 200,
+		"ratio":            float64(1),
+		"error":            true,
+		"k8s_pod_name":     "checkout-0",
+		"region":           "",
+	}, fields)
+}
+
+func TestAttributesToUDTMismatch(t *testing.T) {
+	attributes := pcommon.NewMap()
+	attributes.PutBool("http.status_code", true)
+	_, err := gocql.Marshal(testAttributesUDT, attributesToUDT(attributes))
+	require.Error(t, err)
+}
+
+func TestUDTFieldName(t *testing.T) {
+	assert.Equal(t, "k8s_pod_name", udtFieldName("k8s.pod.name"))
+	assert.Equal(t, "http_request_method", udtFieldName("HTTP.Request-Method"))
+}
+
+func TestPushLogsDataAttributesUDT(t *testing.T) {
+	logs := simpleLogs("INFO")
+	logs.ResourceLogs().At(0).Resource().Attributes().PutStr("service.name", "checkout")
+	session := &fakeSession{}
+	exp := newTestLogsExporter(t, session, func(config *Config) {
+		config.AttributesUDT = "otel_attributes"
+	})
+	require.NoError(t, exp.pushLogsData(context.Background(), logs))
+
+	stmts := session.statements()
+	require.Len(t, stmts, 1)
+	require.IsType(t, udtAttributes{}, stmts[0].values[8])
+	assert.Equal(t, "checkout", stmts[0].values[8].(udtAttributes)["service_name"].Str())
+	assert.Contains(t, parseCreateLogTableSQL(exp.cfg), "ResourceAttributes frozen<otel_attributes>, LogAttributes frozen<otel_attributes>,")
+}
@@ -0,0 +1,87 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter // import "github.com/open-telemetry/opentelemetry-collector-contrib/exporter/cassandraexporter"
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"go.uber.org/zap"
+)
+
+var errUnsupportedServerVersion = errors.New("unsupported by the cassandra version")
+
+// serverVersion is the major and minor release of a Cassandra node.
+type serverVersion struct {
+	major, minor int
+}
+
+func (v serverVersion) atLeast(major, minor int) bool {
+	return v.major > major || (v.major == major && v.minor >= minor)
+}
+
+// parseServerVersion parses the release_version of system.local, such as
+// 3.11.4, 4.1.5 or 5.0-rc1.
+func parseServerVersion(release string) (serverVersion, error) {
+	fields := strings.SplitN(release, ".", 3)
+	if len(fields) < 2 {
+		return serverVersion{}, fmt.Errorf("invalid cassandra release version %q", release)
+	}
+	major, err := strconv.Atoi(fields[0])
+	if err != nil {
+		return serverVersion{}, fmt.Errorf("invalid cassandra release version %q", release)
+	}
+	minor, err := strconv.Atoi(strings.SplitN(fields[1], "-", 2)[0])
+	if err != nil {
+		return serverVersion{}, fmt.Errorf("invalid cassandra release version %q", release)
+	}
+	return serverVersion{major: major, minor: minor}, nil
+}
+
+// versionRequirement is a configured feature that older Cassandra releases
+// reject.
+type versionRequirement struct {
+	feature      string
+	major, minor int
+}
+
+// versionRequirements returns the features of cfg with a minimum Cassandra
+// version. The table options only matter when the exporter creates the
+// schema.
+func versionRequirements(cfg *Config) []versionRequirement {
+	var requirements []versionRequirement
+	if cfg.ProtoVersion == 5 {
+		requirements = append(requirements, versionRequirement{feature: "proto_version 5", major: 4})
+	}
+	if !cfg.CreateSchema {
+		return requirements
+	}
+	if cfg.Compression.Algorithm == "ZstdCompressor" {
+		requirements = append(requirements, versionRequirement{feature: "compression.algorithm ZstdCompressor", major: 4})
+	}
+	if cfg.Compaction.Strategy == "UnifiedCompactionStrategy" {
+		requirements = append(requirements, versionRequirement{feature: "compaction.strategy UnifiedCompactionStrategy", major: 5})
+	}
+	return requirements
+}
+
+// checkServerVersion fails when the cluster, running the given release, does
+// not support a feature of cfg. A release that cannot be parsed, as reported
+// by some managed services, is only logged.
+func checkServerVersion(cfg *Config, release string, logger *zap.Logger) error {
+	version, err := parseServerVersion(release)
+	if err != nil {
+		logger.Warn("skipping the cassandra version check", zap.Error(err))
+		return nil
+	}
+	for _, r := range versionRequirements(cfg) {
+		if !version.atLeast(r.major, r.minor) {
+			err = errors.Join(err, fmt.Errorf("%s requires cassandra %d.%d or later, the cluster runs %s: %w",
+				r.feature, r.major, r.minor, release, errUnsupportedServerVersion))
+		}
+	}
+	return err
+}
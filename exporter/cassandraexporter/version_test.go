@@ -0,0 +1,115 @@
+// Copyright The OpenTelemetry Authors
+// SPDX-License-Identifier: Apache-2.0
+
+package cassandraexporter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+func TestParseServerVersion(t *testing.T) {
+	tests := map[string]serverVersion{
+		"3.11.17":   {major: 3, minor: 11},
+		"4.0.13":    {major: 4, minor: 0},
+		"4.1.5":     {major: 4, minor: 1},
+		"5.0-rc1":   {major: 5, minor: 0},
+		"6.8.0.512": {major: 6, minor: 8},
+	}
+	for release, expected := range tests {
+		t.Run(release, func(t *testing.T) {
+			version, err := parseServerVersion(release)
+			require.NoError(t, err)
+			assert.Equal(t, expected, version)
+		})
+	}
+
+	for _, release := range []string{"", "4", "four.0", "4.x"} {
+		_, err := parseServerVersion(release)
+		assert.Error(t, err, release)
+	}
+}
+
+func TestCheckServerVersion(t *testing.T) {
+	tests := map[string]struct {
+		release  string
+		cfg      func(*Config)
+		features []string
+	}{
+		"defaults_on_3.11": {
+			release: "3.11.17",
+		},
+		"zstd_on_3.11": {
+			release:  "3.11.17",
+			cfg:      func(config *Config) { config.Compression.Algorithm = "ZstdCompressor" },
+			features: []string{"compression.algorithm ZstdCompressor"},
+		},
+		"zstd_on_4.0": {
+			release: "4.0.13",
+			cfg:     func(config *Config) { config.Compression.Algorithm = "ZstdCompressor" },
+		},
+		"zstd_without_create_schema": {
+			release: "3.11.17",
+			cfg: func(config *Config) {
+				config.Compression.Algorithm = "ZstdCompressor"
+				config.CreateSchema = false
+			},
+		},
+		"ucs_on_4.1": {
+			release:  "4.1.5",
+			cfg:      func(config *Config) { config.Compaction.Strategy = "UnifiedCompactionStrategy" },
+			features: []string{"compaction.strategy UnifiedCompactionStrategy"},
+		},
+		"ucs_on_5.0": {
+			release: "5.0-rc1",
+			cfg:     func(config *Config) { config.Compaction.Strategy = "UnifiedCompactionStrategy" },
+		},
+		"proto_5_on_3.11": {
+			release: "3.11.17",
+			cfg: func(config *Config) {
+				config.ProtoVersion = 5
+				config.CreateSchema = false
+			},
+			features: []string{"proto_version 5"},
+		},
+		"several_on_3.0": {
+			release: "3.0.29",
+			cfg: func(config *Config) {
+				config.ProtoVersion = 5
+				config.Compression.Algorithm = "ZstdCompressor"
+			},
+			features: []string{"proto_version 5", "compression.algorithm ZstdCompressor"},
+		},
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			var fns []func(*Config)
+			if test.cfg != nil {
+				fns = append(fns, test.cfg)
+			}
+			err := checkServerVersion(withDefaultConfig(fns...), test.release, zap.NewNop())
+			if len(test.features) == 0 {
+				require.NoError(t, err)
+				return
+			}
+			require.ErrorIs(t, err, errUnsupportedServerVersion)
+			for _, feature := range test.features {
+				assert.ErrorContains(t, err, feature)
+			}
+			assert.ErrorContains(t, err, "the cluster runs "+test.release)
+		})
+	}
+}
+
+func TestCheckServerVersionUnparsable(t *testing.T) {
+	core, logs := observer.New(zap.WarnLevel)
+	cfg := withDefaultConfig(func(config *Config) {
+		config.Compression.Algorithm = "ZstdCompressor"
+	})
+	require.NoError(t, checkServerVersion(cfg, "keyspaces", zap.New(core)))
+	assert.Equal(t, 1, logs.FilterMessage("skipping the cassandra version check").Len())
+}